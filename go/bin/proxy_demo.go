@@ -9,6 +9,7 @@ import (
 	"os"
 
 	x402gin "github.com/coinbase/x402/go/pkg/gin"
+	"github.com/coinbase/x402/go/pkg/middleware"
 	"github.com/coinbase/x402/go/pkg/types"
 	"github.com/gin-gonic/gin"
 )
@@ -37,13 +38,20 @@ func main() {
 		CreateAuthHeaders: config.CreateAuthHeaders,
 	}
 
+	price := types.USD(config.Price)
+	if config.Price == "" {
+		price = middleware.USDAmount(big.NewFloat(config.Amount))
+	}
+
 	r.Any("/*path",
 		x402gin.PaymentMiddleware(
 			big.NewFloat(config.Amount),
 			config.PayTo,
+			x402gin.WithPrice(price),
 			x402gin.WithFacilitatorConfig(facilitatorConfig),
 			x402gin.WithResource(config.TargetURL),
 			x402gin.WithTestnet(config.Testnet),
+			x402gin.WithNetwork(config.Network),
 			x402gin.WithDescription(config.Description),
 			x402gin.WithMimeType(config.MimeType),
 			x402gin.WithMaxTimeoutSeconds(config.MaxTimeoutSeconds),
@@ -60,12 +68,14 @@ func main() {
 type ProxyConfig struct {
 	TargetURL         string                                       `json:"targetURL"`
 	Amount            float64                                      `json:"amount"`
+	Price             string                                       `json:"price"`
 	PayTo             string                                       `json:"payTo"`
 	Description       string                                       `json:"description"`
 	FacilitatorURL    string                                       `json:"facilitatorURL"`
 	MimeType          string                                       `json:"mimeType"`
 	MaxTimeoutSeconds int                                          `json:"maxTimeoutSeconds"`
 	Testnet           bool                                         `json:"testnet"`
+	Network           string                                       `json:"network"`
 	Headers           map[string]string                            `json:"headers"`
 	CreateAuthHeaders func() (map[string]map[string]string, error) `json:"-"`
 }
@@ -128,9 +138,14 @@ func loadConfig(configPath string) (*ProxyConfig, error) {
 		return nil, fmt.Errorf("error parsing config file: %w", err)
 	}
 
-	if config.TargetURL == "" || config.Amount == 0 || config.PayTo == "" {
+	if config.TargetURL == "" || (config.Amount == 0 && config.Price == "") || config.PayTo == "" {
 		return nil, fmt.Errorf("config is missing required fields")
 	}
+	if config.Price != "" {
+		if err := types.USD(config.Price).Validate(); err != nil {
+			return nil, fmt.Errorf("invalid price: %w", err)
+		}
+	}
 
 	return config, nil
 }
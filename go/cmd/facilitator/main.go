@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/joho/godotenv"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+	"github.com/coinbase/x402/go/pkg/facilitator"
+)
+
+// defaultRPCURLs are the public RPC endpoints used when no RPC_URL_<NETWORK> variable is set
+var defaultRPCURLs = map[string]string{
+	"base-sepolia":   "https://sepolia.base.org",
+	"base":           "https://mainnet.base.org",
+	"avalanche-fuji": "https://api.avax-test.network/ext/bc/C/rpc",
+	"avalanche":      "https://api.avax.network/ext/bc/C/rpc",
+}
+
+func main() {
+	addr := flag.String("addr", ":3000", "address to listen on")
+	networks := flag.String("networks", "base-sepolia", "comma separated list of networks to facilitate")
+	flag.Parse()
+
+	// Load .env file if it exists
+	if err := godotenv.Load(); err != nil {
+		fmt.Println("Warning: .env file not found. Using environment variables.")
+	}
+
+	privateKey := os.Getenv("PRIVATE_KEY")
+	if privateKey == "" {
+		fmt.Println("Error: PRIVATE_KEY environment variable must be set")
+		os.Exit(1)
+	}
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil {
+		fmt.Printf("Error parsing PRIVATE_KEY: %v\n", err)
+		os.Exit(1)
+	}
+
+	chains := make(map[string]facilitator.ChainReader)
+	for _, network := range strings.Split(*networks, ",") {
+		network = strings.TrimSpace(network)
+
+		chain, err := dialChain(network, key)
+		if err != nil {
+			fmt.Printf("Error connecting to %s: %v\n", network, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Facilitating %s as %s\n", network, chain.Address().Hex())
+		chains[network] = chain
+	}
+
+	handler := facilitator.NewHandler(facilitator.NewFacilitator(chains))
+
+	fmt.Printf("Facilitator listening at %s\n", *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		fmt.Printf("Error running server: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// dialChain connects to the RPC endpoint of the network, read from RPC_URL_<NETWORK> (e.g. RPC_URL_BASE_SEPOLIA)
+func dialChain(network string, key *ecdsa.PrivateKey) (*facilitator.RPCChain, error) {
+	envName := "RPC_URL_" + strings.ToUpper(strings.ReplaceAll(network, "-", "_"))
+	rpcURL := os.Getenv(envName)
+	if rpcURL == "" {
+		rpcURL = defaultRPCURLs[network]
+	}
+	if rpcURL == "" {
+		return nil, fmt.Errorf("%s must be set", envName)
+	}
+
+	ctx := context.Background()
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", rpcURL, err)
+	}
+
+	chain, err := facilitator.NewRPCChain(ctx, client, key)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedChainID, err := evm.ChainID(network)
+	if err != nil {
+		return nil, err
+	}
+	if chain.ChainID().Cmp(expectedChainID) != 0 {
+		return nil, fmt.Errorf("%s is chain %s, expected %s", rpcURL, chain.ChainID(), expectedChainID)
+	}
+
+	return chain, nil
+}
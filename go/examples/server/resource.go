@@ -11,6 +11,7 @@ import (
 func main() {
 	r := gin.Default()
 
+	// A local facilitator, e.g. started with `go run ./cmd/facilitator`
 	facilitatorConfig := &types.FacilitatorConfig{
 		URL: "http://localhost:3000",
 	}
@@ -0,0 +1,142 @@
+// Package echo provides the x402 payment middleware for Echo servers.
+// It is configured with the options of the middleware package.
+package echo
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/coinbase/x402/go/pkg/middleware"
+)
+
+// ReportUsage reports the amount, in atomic units of the asset, used by the request of an upto
+// payment, see WithUpto. It is charged instead of the price once the handler returns.
+func ReportUsage(c echo.Context, amount *big.Int) error {
+	return middleware.ReportUsage(c.Request().Context(), amount)
+}
+
+// PaymentMiddleware is the Echo middleware for the resource server using the x402payment protocol.
+// Amount: the decimal denominated amount to charge (ex: 0.01 for 1 cent)
+func PaymentMiddleware(amount *big.Float, address string, opts ...middleware.Options) echo.MiddlewareFunc {
+	engine := middleware.NewEngine(middleware.USDAmount(amount), address, middleware.NewOptions(opts...))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return handlePayment(c, next, engine)
+		}
+	}
+}
+
+// PaymentMiddlewareContext is PaymentMiddleware returning the error of the supported payment kinds
+// check instead of panicking, see middleware.NewEngineContext
+func PaymentMiddlewareContext(ctx context.Context, amount *big.Float, address string, opts ...middleware.Options) (echo.MiddlewareFunc, error) {
+	engine, err := middleware.NewEngineContext(ctx, middleware.USDAmount(amount), address, middleware.NewOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return handlePayment(c, next, engine)
+		}
+	}, nil
+}
+
+// handlePayment gates the request behind the engine's payment
+func handlePayment(c echo.Context, next echo.HandlerFunc, engine *middleware.Engine) error {
+	payment, response := engine.Verify(c.Request())
+	if response != nil {
+		if response.HTML != "" {
+			return c.Blob(response.StatusCode, "text/html", []byte(response.HTML))
+		}
+		return c.JSON(response.StatusCode, response.Body)
+	}
+	c.SetRequest(c.Request().WithContext(middleware.ContextWithPayment(c.Request().Context(), payment)))
+
+	switch engine.SettlementMode() {
+	case middleware.SettleBeforeHandler:
+		settleResponseHeader, response := engine.Settle(c.Request().Context(), payment)
+		if response != nil {
+			return c.JSON(response.StatusCode, response.Body)
+		}
+		if settleResponseHeader != "" {
+			c.Response().Header().Set(middleware.PaymentResponseHeader, settleResponseHeader)
+		}
+		return next(c)
+	case middleware.SettleAsync:
+		settleResponseHeader, response := engine.HoldSettlement(c.Request().Context(), payment)
+		if response != nil {
+			return c.JSON(response.StatusCode, response.Body)
+		}
+		if settleResponseHeader != "" {
+			c.Response().Header().Set(middleware.PaymentResponseHeader, settleResponseHeader)
+		}
+		if err := next(c); err != nil {
+			engine.CancelSettlement(c.Request().Context(), payment)
+			return err
+		}
+		engine.SettleAsync(c.Request().Context(), payment)
+		return nil
+	}
+
+	// Create a custom response writer to intercept the response
+	original := c.Response().Writer
+	writer := &responseWriter{
+		ResponseWriter: original,
+		buffer:         middleware.NewResponseBuffer(),
+	}
+	c.Response().Writer = writer
+
+	// Execute the handler
+	err := next(c)
+
+	// Reset the response writer to the original
+	c.Response().Writer = original
+
+	// The echo response is already committed by the handler, so the buffered response
+	// is written directly to the original writer
+	flush := func() {
+		original.WriteHeader(writer.buffer.StatusCode)
+		original.Write(writer.buffer.Body.Bytes())
+	}
+
+	// Check if the handler failed, in which case the payment is not settled
+	if err != nil {
+		if c.Response().Committed {
+			flush()
+		}
+		return err
+	}
+
+	// Settle payment
+	settleResponseHeader, response := engine.Settle(c.Request().Context(), payment)
+	if response != nil {
+		middleware.WriteResponse(original, response)
+		return nil
+	}
+
+	// Write the original response with the settlement header
+	if settleResponseHeader != "" {
+		original.Header().Set(middleware.PaymentResponseHeader, settleResponseHeader)
+	}
+	flush()
+
+	return nil
+}
+
+// responseWriter is a custom response writer that captures the response
+type responseWriter struct {
+	http.ResponseWriter
+	buffer *middleware.ResponseBuffer
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.buffer.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.buffer.Write(b)
+}
@@ -0,0 +1,52 @@
+package echo_test
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	x402echo "github.com/coinbase/x402/go/pkg/echo"
+	"github.com/coinbase/x402/go/pkg/middleware"
+	"github.com/coinbase/x402/go/pkg/middleware/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestPaymentMiddleware(t *testing.T) {
+	middlewaretest.Run(t, func(t *testing.T, amount *big.Float, payTo string, opts ...middleware.Options) middlewaretest.Server {
+		e := echo.New()
+		e.GET("/protected", func(c echo.Context) error {
+			return c.String(http.StatusCreated, "success")
+		}, x402echo.PaymentMiddleware(amount, payTo, opts...))
+
+		return middlewaretest.ServeHandler(e)
+	})
+}
+
+func TestPaymentMiddleware_HandlerError(t *testing.T) {
+	facilitatorServer := middlewaretest.NewFacilitatorServer(t, http.StatusOK, true, http.StatusOK, true)
+
+	e := echo.New()
+	e.GET("/protected", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "bad input")
+	}, x402echo.PaymentMiddleware(
+		big.NewFloat(1.0),
+		middlewaretest.PayTo,
+		middleware.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+	))
+
+	payment, err := middlewaretest.NewPaymentPayload().EncodeToBase64String()
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("X-PAYMENT", payment)
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "bad input")
+	assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+}
@@ -0,0 +1,109 @@
+package evm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// PermitPrimaryType is the EIP-712 primary type of an EIP-2612 permit
+const PermitPrimaryType = "Permit"
+
+// permitType is the EIP-2612 permit message type
+var permitType = []apitypes.Type{
+	{Name: "owner", Type: "address"},
+	{Name: "spender", Type: "address"},
+	{Name: "value", Type: "uint256"},
+	{Name: "nonce", Type: "uint256"},
+	{Name: "deadline", Type: "uint256"},
+}
+
+// Permit represents a parsed EIP-2612 permit
+type Permit struct {
+	Owner    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+// ParsePermit parses the string encoded permit of an upto EVM payload
+func ParsePermit(permit *types.UptoEvmPayloadPermit) (*Permit, error) {
+	if permit == nil {
+		return nil, fmt.Errorf("missing permit")
+	}
+	if !common.IsHexAddress(permit.Owner) {
+		return nil, fmt.Errorf("invalid owner address: %q", permit.Owner)
+	}
+	if !common.IsHexAddress(permit.Spender) {
+		return nil, fmt.Errorf("invalid spender address: %q", permit.Spender)
+	}
+
+	value, ok := new(big.Int).SetString(permit.Value, 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid value: %q", permit.Value)
+	}
+	nonce, ok := new(big.Int).SetString(permit.Nonce, 10)
+	if !ok || nonce.Sign() < 0 {
+		return nil, fmt.Errorf("invalid nonce: %q", permit.Nonce)
+	}
+	deadline, ok := new(big.Int).SetString(permit.Deadline, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid deadline: %q", permit.Deadline)
+	}
+
+	return &Permit{
+		Owner:    common.HexToAddress(permit.Owner),
+		Spender:  common.HexToAddress(permit.Spender),
+		Value:    value,
+		Nonce:    nonce,
+		Deadline: deadline,
+	}, nil
+}
+
+// TypedData returns the EIP-712 typed data that is signed for the permit
+func (p *Permit) TypedData(domain Domain) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain":    eip712DomainType,
+			PermitPrimaryType: permitType,
+		},
+		PrimaryType: PermitPrimaryType,
+		Domain: apitypes.TypedDataDomain{
+			Name:              domain.Name,
+			Version:           domain.Version,
+			ChainId:           (*math.HexOrDecimal256)(domain.ChainID),
+			VerifyingContract: domain.VerifyingContract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"owner":    p.Owner.Hex(),
+			"spender":  p.Spender.Hex(),
+			"value":    p.Value.String(),
+			"nonce":    p.Nonce.String(),
+			"deadline": p.Deadline.String(),
+		},
+	}
+}
+
+// UptoSpender returns the permit spender of upto payment requirements, read from their extra field
+func UptoSpender(requirements *types.PaymentRequirements) (common.Address, error) {
+	var extra struct {
+		Spender string `json:"spender"`
+	}
+	if requirements.Extra != nil {
+		if err := json.Unmarshal(*requirements.Extra, &extra); err != nil {
+			return common.Address{}, fmt.Errorf("failed to unmarshal extra: %w", err)
+		}
+	}
+	if !common.IsHexAddress(extra.Spender) {
+		return common.Address{}, fmt.Errorf("invalid spender address: %q", extra.Spender)
+	}
+
+	return common.HexToAddress(extra.Spender), nil
+}
@@ -0,0 +1,157 @@
+package evm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// TransferWithAuthorizationPrimaryType is the EIP-712 primary type of an ERC-3009 authorization
+const TransferWithAuthorizationPrimaryType = "TransferWithAuthorization"
+
+// eip712DomainType is the EIP-712 domain used by EIP-3009 tokens such as USDC
+var eip712DomainType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+// transferWithAuthorizationType is the ERC-3009 transferWithAuthorization message type
+var transferWithAuthorizationType = []apitypes.Type{
+	{Name: "from", Type: "address"},
+	{Name: "to", Type: "address"},
+	{Name: "value", Type: "uint256"},
+	{Name: "validAfter", Type: "uint256"},
+	{Name: "validBefore", Type: "uint256"},
+	{Name: "nonce", Type: "bytes32"},
+}
+
+// Domain represents the EIP-712 domain of a token contract
+type Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract common.Address
+}
+
+// TransferWithAuthorization represents a parsed ERC-3009 authorization
+type TransferWithAuthorization struct {
+	From        common.Address
+	To          common.Address
+	Value       *big.Int
+	ValidAfter  *big.Int
+	ValidBefore *big.Int
+	Nonce       [32]byte
+}
+
+// ParseAuthorization parses the string encoded authorization of an exact EVM payload
+func ParseAuthorization(auth *types.ExactEvmPayloadAuthorization) (*TransferWithAuthorization, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("missing authorization")
+	}
+	if !common.IsHexAddress(auth.From) {
+		return nil, fmt.Errorf("invalid from address: %q", auth.From)
+	}
+	if !common.IsHexAddress(auth.To) {
+		return nil, fmt.Errorf("invalid to address: %q", auth.To)
+	}
+
+	value, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid value: %q", auth.Value)
+	}
+	validAfter, ok := new(big.Int).SetString(auth.ValidAfter, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid validAfter: %q", auth.ValidAfter)
+	}
+	validBefore, ok := new(big.Int).SetString(auth.ValidBefore, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid validBefore: %q", auth.ValidBefore)
+	}
+
+	nonceBytes, err := hexutil.Decode(auth.Nonce)
+	if err != nil || len(nonceBytes) != 32 {
+		return nil, fmt.Errorf("invalid nonce: %q", auth.Nonce)
+	}
+
+	var nonce [32]byte
+	copy(nonce[:], nonceBytes)
+
+	return &TransferWithAuthorization{
+		From:        common.HexToAddress(auth.From),
+		To:          common.HexToAddress(auth.To),
+		Value:       value,
+		ValidAfter:  validAfter,
+		ValidBefore: validBefore,
+		Nonce:       nonce,
+	}, nil
+}
+
+// TypedData returns the EIP-712 typed data that is signed for the authorization
+func (a *TransferWithAuthorization) TypedData(domain Domain) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain":                       eip712DomainType,
+			TransferWithAuthorizationPrimaryType: transferWithAuthorizationType,
+		},
+		PrimaryType: TransferWithAuthorizationPrimaryType,
+		Domain: apitypes.TypedDataDomain{
+			Name:              domain.Name,
+			Version:           domain.Version,
+			ChainId:           (*math.HexOrDecimal256)(domain.ChainID),
+			VerifyingContract: domain.VerifyingContract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"from":        a.From.Hex(),
+			"to":          a.To.Hex(),
+			"value":       a.Value.String(),
+			"validAfter":  a.ValidAfter.String(),
+			"validBefore": a.ValidBefore.String(),
+			"nonce":       hexutil.Encode(a.Nonce[:]),
+		},
+	}
+}
+
+// HashTypedData returns the EIP-712 digest of the typed data
+func HashTypedData(typedData apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+
+	return hash, nil
+}
+
+// RecoverTypedDataSigner recovers the address that produced the signature over the typed data.
+// Both the 0/1 and 27/28 recovery id conventions are accepted.
+func RecoverTypedDataSigner(typedData apitypes.TypedData, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length: %d", len(signature))
+	}
+
+	hash, err := HashTypedData(typedData)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover public key: %w", err)
+	}
+
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
@@ -0,0 +1,64 @@
+package evm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ChainID returns the chain ID of the given EVM network
+func ChainID(network string) (*big.Int, error) {
+	config, err := types.LookupNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+
+	return big.NewInt(config.ChainID), nil
+}
+
+// DomainForRequirements returns the EIP-712 domain of the asset in the payment requirements.
+// The name and version are read from the requirements' extra field, falling back
+// to the USDC defaults of the network when the asset is the network's USDC.
+func DomainForRequirements(requirements *types.PaymentRequirements) (Domain, error) {
+	config, err := types.LookupNetwork(requirements.Network)
+	if err != nil {
+		return Domain{}, err
+	}
+	if !common.IsHexAddress(requirements.Asset) {
+		return Domain{}, fmt.Errorf("invalid asset address: %q", requirements.Asset)
+	}
+
+	var extra struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if requirements.Extra != nil {
+		if err := json.Unmarshal(*requirements.Extra, &extra); err != nil {
+			return Domain{}, fmt.Errorf("failed to unmarshal extra: %w", err)
+		}
+	}
+
+	asset := common.HexToAddress(requirements.Asset)
+	if extra.Name == "" || extra.Version == "" {
+		if asset != common.HexToAddress(config.USDCAddress) {
+			return Domain{}, fmt.Errorf("missing EIP-712 name and version for asset %s", asset.Hex())
+		}
+		if extra.Name == "" {
+			extra.Name = config.USDCName
+		}
+		if extra.Version == "" {
+			extra.Version = config.USDCVersion
+		}
+	}
+
+	return Domain{
+		Name:              extra.Name,
+		Version:           extra.Version,
+		ChainID:           big.NewInt(config.ChainID),
+		VerifyingContract: asset,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+)
+
+// ChainReader is the on-chain state the facilitator reads to verify a payment
+type ChainReader interface {
+	// BalanceOf returns the ERC20 token balance of the account
+	BalanceOf(ctx context.Context, token, account common.Address) (*big.Int, error)
+
+	// AuthorizationState returns whether the ERC-3009 nonce of the authorizer has already been used
+	AuthorizationState(ctx context.Context, token, authorizer common.Address, nonce [32]byte) (bool, error)
+
+	// SimulateTransferWithAuthorization dry-runs transferWithAuthorization and returns an error if it would revert
+	SimulateTransferWithAuthorization(ctx context.Context, token common.Address, auth *evm.TransferWithAuthorization, signature []byte) error
+}
+
+// ChainWriter submits settlement transactions to the chain
+type ChainWriter interface {
+	// TransferWithAuthorization submits transferWithAuthorization and waits for it to be mined.
+	// A transaction that was mined but reverted is reported as ErrTransactionFailed along with its hash.
+	TransferWithAuthorization(ctx context.Context, token common.Address, auth *evm.TransferWithAuthorization, signature []byte) (common.Hash, error)
+}
+
+// Chain is the chain state a facilitator reads to verify payments and writes to settle them
+type Chain interface {
+	ChainReader
+	ChainWriter
+}
+
+// ErrTransactionFailed is returned by a ChainWriter when the settlement transaction reverted
+var ErrTransactionFailed = errors.New("transaction failed")
+
+// PermitReader is the on-chain state the facilitator reads to verify an upto payment
+type PermitReader interface {
+	// PermitNonce returns the current EIP-2612 permit nonce of the owner
+	PermitNonce(ctx context.Context, token, owner common.Address) (*big.Int, error)
+}
+
+// PermitWriter submits the settlement transactions of upto payments
+type PermitWriter interface {
+	// Spender returns the account submitting the settlement transactions, which upto payments must permit
+	Spender() common.Address
+
+	// PermitTransferFrom submits the EIP-2612 permit followed by a transferFrom of amount from the permit owner
+	// to the recipient, and waits for them to be mined. A reverted transaction is reported as ErrTransactionFailed
+	// along with its hash.
+	PermitTransferFrom(ctx context.Context, token common.Address, permit *evm.Permit, signature []byte, to common.Address, amount *big.Int) (common.Hash, error)
+}
@@ -0,0 +1,282 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+const (
+	x402Version = 1
+	schemeExact = types.SchemeExact
+
+	// validBeforeBuffer pads the authorization deadline by ~3 blocks to account for round tripping
+	validBeforeBuffer = 6 * time.Second
+)
+
+// Facilitator verifies and settles x402 payments locally against chain state instead of calling a remote facilitator
+type Facilitator struct {
+	// Chains maps network names (e.g. "base-sepolia") to the chain state of that network.
+	// Settlement requires the chain to also implement ChainWriter.
+	Chains map[string]ChainReader
+	// Now returns the current time, used to check the authorization validity window
+	Now func() time.Time
+}
+
+// NewFacilitator creates a new local facilitator for the given networks
+func NewFacilitator(chains map[string]ChainReader) *Facilitator {
+	return &Facilitator{
+		Chains: chains,
+		Now:    time.Now,
+	}
+}
+
+// Verify verifies an exact or upto EVM payment payload against the payment requirements.
+//
+// An invalid payment is reported through the returned VerifyResponse; an error is
+// only returned when the chain state could not be read.
+func (f *Facilitator) Verify(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	return f.verifyPayment(context.Background(), payload, requirements)
+}
+
+// Settle settles an exact EVM payment by submitting transferWithAuthorization on the payment's network,
+// or an upto EVM payment by submitting its permit and transferring the maxAmountRequired of the requirements.
+// The payment is re-verified before it is submitted.
+func (f *Facilitator) Settle(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	return f.settlePayment(context.Background(), payload, requirements)
+}
+
+// VerifyContext verifies a payment like Verify, reading the chain state with ctx.
+// It makes the Facilitator usable as a facilitatorclient.Facilitator, for example by the middlewares.
+func (f *Facilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	return f.verifyPayment(ctx, payload, requirements)
+}
+
+// SettleContext settles a payment like Settle, submitting the transaction with ctx
+func (f *Facilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	return f.settlePayment(ctx, payload, requirements)
+}
+
+// SupportedContext returns the supported payment kinds like Supported
+func (f *Facilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	return f.Supported()
+}
+
+// verifyPayment verifies a payment according to its scheme
+func (f *Facilitator) verifyPayment(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	if payload != nil && payload.Scheme == types.SchemeUpto {
+		response, _, err := f.verifyUpto(ctx, payload, requirements)
+		return response, err
+	}
+
+	response, _, err := f.verify(ctx, payload, requirements)
+	return response, err
+}
+
+// settlePayment settles a payment according to its scheme
+func (f *Facilitator) settlePayment(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	if requirements == nil {
+		return &types.SettleResponse{
+			Success:     false,
+			ErrorReason: types.ReasonPtr(types.ErrorReasonInvalidPaymentRequirements),
+		}, nil
+	}
+	if payload != nil && payload.Scheme == types.SchemeUpto {
+		return f.settleUpto(ctx, payload, requirements)
+	}
+
+	return f.settle(ctx, payload, requirements)
+}
+
+// Supported returns the scheme and network pairs the facilitator can verify and settle
+func (f *Facilitator) Supported() (*types.SupportedPaymentKindsResponse, error) {
+	networks := make([]string, 0, len(f.Chains))
+	for network, chain := range f.Chains {
+		if _, ok := chain.(ChainWriter); ok {
+			networks = append(networks, network)
+		}
+	}
+	sort.Strings(networks)
+
+	kinds := make([]types.SupportedPaymentKind, 0, len(networks))
+	for _, network := range networks {
+		kinds = append(kinds, types.SupportedPaymentKind{
+			X402Version: x402Version,
+			Scheme:      schemeExact,
+			Network:     network,
+		})
+		if writer, ok := f.Chains[network].(PermitWriter); ok && writer.Spender() != (common.Address{}) {
+			kinds = append(kinds, types.SupportedPaymentKind{
+				X402Version: x402Version,
+				Scheme:      types.SchemeUpto,
+				Network:     network,
+				Extra:       uptoExtra(writer),
+			})
+		}
+	}
+
+	return &types.SupportedPaymentKindsResponse{Kinds: kinds}, nil
+}
+
+func (f *Facilitator) settle(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	verifyResponse, payment, err := f.verify(ctx, payload, requirements)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyResponse.IsValid {
+		return &types.SettleResponse{
+			Success:     false,
+			ErrorReason: verifyResponse.InvalidReason,
+			Network:     requirements.Network,
+			Payer:       verifyResponse.Payer,
+		}, nil
+	}
+
+	writer, ok := f.Chains[requirements.Network].(ChainWriter)
+	if !ok {
+		return nil, fmt.Errorf("settlement is not supported on network %s", requirements.Network)
+	}
+
+	txHash, err := writer.TransferWithAuthorization(ctx, payment.token, payment.auth, payment.signature)
+	if errors.Is(err, ErrTransactionFailed) {
+		return &types.SettleResponse{
+			Success:     false,
+			ErrorReason: types.ReasonPtr(types.ErrorReasonInvalidTransactionState),
+			Transaction: txHash.Hex(),
+			Network:     requirements.Network,
+			Payer:       verifyResponse.Payer,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit settlement transaction: %w", err)
+	}
+
+	return &types.SettleResponse{
+		Success:     true,
+		Transaction: txHash.Hex(),
+		Network:     requirements.Network,
+		Payer:       verifyResponse.Payer,
+	}, nil
+}
+
+// exactPayment is a verified exact EVM payment ready to be settled
+type exactPayment struct {
+	token     common.Address
+	auth      *evm.TransferWithAuthorization
+	signature []byte
+}
+
+func (f *Facilitator) verify(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, *exactPayment, error) {
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return invalid(types.ErrorReasonInvalidPayload, ""), nil, nil
+	}
+	payer := payload.Payload.Authorization.From
+	if requirements == nil {
+		return invalid(types.ErrorReasonInvalidPaymentRequirements, payer), nil, nil
+	}
+
+	if payload.Scheme != schemeExact || requirements.Scheme != schemeExact {
+		return invalid(types.ErrorReasonInvalidScheme, payer), nil, nil
+	}
+
+	// Verify the authorization is for the agreed upon chain and ERC20 contract
+	if payload.Network != requirements.Network {
+		return invalid(types.ErrorReasonInvalidNetwork, payer), nil, nil
+	}
+	chain, ok := f.Chains[requirements.Network]
+	if !ok {
+		return invalid(types.ErrorReasonInvalidNetwork, payer), nil, nil
+	}
+	domain, err := evm.DomainForRequirements(requirements)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidNetwork, payer), nil, nil
+	}
+
+	auth, err := evm.ParseAuthorization(payload.Payload.Authorization)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidPayload, payer), nil, nil
+	}
+	signature, err := hexutil.Decode(payload.Payload.Signature)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadSignature, payer), nil, nil
+	}
+
+	// Verify the signature was produced by the payer
+	signer, err := evm.RecoverTypedDataSigner(auth.TypedData(domain), signature)
+	if err != nil || signer != auth.From {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadSignature, payer), nil, nil
+	}
+
+	// Verify the payment is made to the resource server
+	if !common.IsHexAddress(requirements.PayTo) || auth.To != common.HexToAddress(requirements.PayTo) {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadRecipientMismatch, payer), nil, nil
+	}
+
+	maxAmountRequired, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+	if !ok {
+		return invalid(types.ErrorReasonInvalidPaymentRequirements, payer), nil, nil
+	}
+
+	// Verify the authorization value covers the required amount
+	if auth.Value.Cmp(maxAmountRequired) < 0 {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadAuthorizationValue, payer), nil, nil
+	}
+
+	// Verify the authorization is within its valid time range
+	now := f.Now()
+	if auth.ValidBefore.Cmp(big.NewInt(now.Add(validBeforeBuffer).Unix())) < 0 {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadAuthorizationValidBefore, payer), nil, nil
+	}
+	if auth.ValidAfter.Cmp(big.NewInt(now.Unix())) > 0 {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadAuthorizationValidAfter, payer), nil, nil
+	}
+
+	// Verify the payer has enough of the asset to cover the required amount
+	balance, err := chain.BalanceOf(ctx, domain.VerifyingContract, auth.From)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read balance: %w", err)
+	}
+	if balance.Cmp(maxAmountRequired) < 0 {
+		return invalid(types.ErrorReasonInsufficientFunds, payer), nil, nil
+	}
+
+	// Verify the nonce has not been used
+	used, err := chain.AuthorizationState(ctx, domain.VerifyingContract, auth.From, auth.Nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read authorization state: %w", err)
+	}
+	if used {
+		return invalid(types.ErrorReasonInvalidTransactionState, payer), nil, nil
+	}
+
+	// Simulate the transfer to ensure the transaction would succeed
+	if err := chain.SimulateTransferWithAuthorization(ctx, domain.VerifyingContract, auth, signature); err != nil {
+		return invalid(types.ErrorReasonInvalidTransactionState, payer), nil, nil
+	}
+
+	return &types.VerifyResponse{
+		IsValid: true,
+		Payer:   &payer,
+	}, &exactPayment{token: domain.VerifyingContract, auth: auth, signature: signature}, nil
+}
+
+// invalid creates a VerifyResponse for an invalid payment
+func invalid(reason types.ErrorReason, payer string) *types.VerifyResponse {
+	response := &types.VerifyResponse{
+		IsValid:       false,
+		InvalidReason: types.ReasonPtr(reason),
+	}
+	if payer != "" {
+		response.Payer = &payer
+	}
+	return response
+}
@@ -0,0 +1,287 @@
+package facilitator_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+	"github.com/coinbase/x402/go/pkg/facilitator"
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// The local facilitator can be used by the middlewares in place of a remote one
+var _ facilitatorclient.Facilitator = (*facilitator.Facilitator)(nil)
+
+const (
+	testNetwork = "base-sepolia"
+	testUSDC    = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	testPayTo   = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+)
+
+var testNow = time.Unix(1745323900, 0)
+
+// testEnv holds a payer key, an in-memory chain and a facilitator reading from it.
+type testEnv struct {
+	key         *ecdsa.PrivateKey
+	payer       common.Address
+	chain       *facilitator.MemoryChain
+	facilitator *facilitator.Facilitator
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	payer := crypto.PubkeyToAddress(key.PublicKey)
+
+	chain := facilitator.NewMemoryChain()
+	chain.SetBalance(common.HexToAddress(testUSDC), payer, big.NewInt(10_000_000))
+
+	f := facilitator.NewFacilitator(map[string]facilitator.ChainReader{testNetwork: chain})
+	f.Now = func() time.Time { return testNow }
+
+	return &testEnv{key: key, payer: payer, chain: chain, facilitator: f}
+}
+
+func newTestRequirements() *types.PaymentRequirements {
+	requirements := &types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           testNetwork,
+		MaxAmountRequired: "1000000",
+		Resource:          "https://example.com/resource",
+		Description:       "Test resource",
+		MimeType:          "application/json",
+		PayTo:             testPayTo,
+		MaxTimeoutSeconds: 60,
+		Asset:             testUSDC,
+	}
+	if err := requirements.SetUSDCInfo(true); err != nil {
+		panic(err)
+	}
+	return requirements
+}
+
+// signPayload builds and signs an exact EVM payload with the env's payer key.
+func (e *testEnv) signPayload(t *testing.T, requirements *types.PaymentRequirements, mutate func(*types.ExactEvmPayloadAuthorization)) *types.PaymentPayload {
+	t.Helper()
+
+	authorization := &types.ExactEvmPayloadAuthorization{
+		From:        e.payer.Hex(),
+		To:          requirements.PayTo,
+		Value:       requirements.MaxAmountRequired,
+		ValidAfter:  big.NewInt(testNow.Add(-time.Minute).Unix()).String(),
+		ValidBefore: big.NewInt(testNow.Add(time.Minute).Unix()).String(),
+		Nonce:       hexutil.Encode(common.LeftPadBytes([]byte{1}, 32)),
+	}
+	if mutate != nil {
+		mutate(authorization)
+	}
+
+	auth, err := evm.ParseAuthorization(authorization)
+	require.NoError(t, err)
+	domain, err := evm.DomainForRequirements(requirements)
+	require.NoError(t, err)
+	hash, err := evm.HashTypedData(auth.TypedData(domain))
+	require.NoError(t, err)
+	signature, err := crypto.Sign(hash, e.key)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return &types.PaymentPayload{
+		X402Version: 1,
+		Scheme:      "exact",
+		Network:     requirements.Network,
+		Payload: &types.ExactEvmPayload{
+			Signature:     hexutil.Encode(signature),
+			Authorization: authorization,
+		},
+	}
+}
+
+func TestVerify_ValidPayment(t *testing.T) {
+	env := newTestEnv(t)
+	requirements := newTestRequirements()
+
+	resp, err := env.facilitator.Verify(env.signPayload(t, requirements, nil), requirements)
+	require.NoError(t, err)
+	assert.True(t, resp.IsValid)
+	assert.Nil(t, resp.InvalidReason)
+	require.NotNil(t, resp.Payer)
+	assert.Equal(t, env.payer.Hex(), *resp.Payer)
+}
+
+func TestVerify_InvalidPayments(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mutateAuth    func(*types.ExactEvmPayloadAuthorization)
+		mutatePayload func(*types.PaymentPayload)
+		setup         func(*testEnv)
+		// mutateRequirements changes the requirements once the payload is signed
+		mutateRequirements func(*types.PaymentRequirements)
+		expectedReason     string
+	}{
+		{
+			name: "signature from another account",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.Payload.Authorization.From = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+			},
+			expectedReason: "invalid_exact_evm_payload_signature",
+		},
+		{
+			name: "tampered value",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.Payload.Authorization.Value = "2000000"
+			},
+			expectedReason: "invalid_exact_evm_payload_signature",
+		},
+		{
+			name: "insufficient balance",
+			setup: func(e *testEnv) {
+				e.chain.SetBalance(common.HexToAddress(testUSDC), e.payer, big.NewInt(999_999))
+			},
+			expectedReason: "insufficient_funds",
+		},
+		{
+			name: "value below required amount",
+			mutateAuth: func(a *types.ExactEvmPayloadAuthorization) {
+				a.Value = "999999"
+			},
+			expectedReason: "invalid_exact_evm_payload_authorization_value",
+		},
+		{
+			name: "expired authorization",
+			mutateAuth: func(a *types.ExactEvmPayloadAuthorization) {
+				a.ValidBefore = big.NewInt(testNow.Add(3 * time.Second).Unix()).String()
+			},
+			expectedReason: "invalid_exact_evm_payload_authorization_valid_before",
+		},
+		{
+			name: "authorization not yet valid",
+			mutateAuth: func(a *types.ExactEvmPayloadAuthorization) {
+				a.ValidAfter = big.NewInt(testNow.Add(time.Second).Unix()).String()
+			},
+			expectedReason: "invalid_exact_evm_payload_authorization_valid_after",
+		},
+		{
+			name: "nonce already used",
+			setup: func(e *testEnv) {
+				e.chain.UseNonce(common.HexToAddress(testUSDC), e.payer, [32]byte{31: 1})
+			},
+			expectedReason: "invalid_transaction_state",
+		},
+		{
+			name: "wrong recipient",
+			mutateAuth: func(a *types.ExactEvmPayloadAuthorization) {
+				a.To = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+			},
+			expectedReason: "invalid_exact_evm_payload_recipient_mismatch",
+		},
+		{
+			name: "network mismatch",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.Network = "base"
+			},
+			expectedReason: "invalid_network",
+		},
+		{
+			name: "unsupported scheme",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.Scheme = "upto"
+			},
+			expectedReason: "invalid_scheme",
+		},
+		{
+			name: "unparsable max amount required",
+			mutateRequirements: func(r *types.PaymentRequirements) {
+				r.MaxAmountRequired = "1.5"
+			},
+			expectedReason: "invalid_payment_requirements",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			requirements := newTestRequirements()
+			if tc.setup != nil {
+				tc.setup(env)
+			}
+
+			payload := env.signPayload(t, requirements, tc.mutateAuth)
+			if tc.mutatePayload != nil {
+				tc.mutatePayload(payload)
+			}
+			if tc.mutateRequirements != nil {
+				tc.mutateRequirements(requirements)
+			}
+
+			resp, err := env.facilitator.Verify(payload, requirements)
+			require.NoError(t, err)
+			assert.False(t, resp.IsValid)
+			require.NotNil(t, resp.InvalidReason)
+			assert.Equal(t, tc.expectedReason, *resp.InvalidReason)
+		})
+	}
+}
+
+func TestVerify_UnknownNetwork(t *testing.T) {
+	env := newTestEnv(t)
+	requirements := newTestRequirements()
+	payload := env.signPayload(t, requirements, nil)
+
+	f := facilitator.NewFacilitator(map[string]facilitator.ChainReader{})
+	resp, err := f.Verify(payload, requirements)
+	require.NoError(t, err)
+	assert.False(t, resp.IsValid)
+	assert.Equal(t, "invalid_network", *resp.InvalidReason)
+}
+
+func TestVerify_NilRequirements(t *testing.T) {
+	env := newTestEnv(t)
+	payload := env.signPayload(t, newTestRequirements(), nil)
+
+	resp, err := env.facilitator.Verify(payload, nil)
+	require.NoError(t, err)
+	assert.False(t, resp.IsValid)
+	assert.Equal(t, "invalid_payment_requirements", *resp.InvalidReason)
+
+	settleResp, err := env.facilitator.Settle(payload, nil)
+	require.NoError(t, err)
+	assert.False(t, settleResp.Success)
+	assert.Equal(t, "invalid_payment_requirements", *settleResp.ErrorReason)
+}
+
+// revertingChain is a chain whose settlement transactions are mined but revert
+type revertingChain struct {
+	*facilitator.MemoryChain
+}
+
+func (c revertingChain) TransferWithAuthorization(context.Context, common.Address, *evm.TransferWithAuthorization, []byte) (common.Hash, error) {
+	return common.Hash{1}, facilitator.ErrTransactionFailed
+}
+
+func TestSettle_Reverted(t *testing.T) {
+	env := newTestEnv(t)
+	requirements := newTestRequirements()
+	payload := env.signPayload(t, requirements, nil)
+
+	f := facilitator.NewFacilitator(map[string]facilitator.ChainReader{testNetwork: revertingChain{env.chain}})
+	f.Now = env.facilitator.Now
+	resp, err := f.Settle(payload, requirements)
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, common.Hash{1}.Hex(), resp.Transaction)
+	require.NotNil(t, resp.ErrorReason)
+	assert.Equal(t, "invalid_transaction_state", *resp.ErrorReason)
+}
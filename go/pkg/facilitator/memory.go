@@ -0,0 +1,190 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+)
+
+// MemoryChain is an in-memory fake of the chain state read and written by the facilitator.
+// It is intended for tests and local development.
+type MemoryChain struct {
+	mu         sync.Mutex
+	balances   map[common.Address]map[common.Address]*big.Int
+	usedNonces map[common.Address]map[common.Address]map[[32]byte]bool
+	permits    map[common.Address]map[common.Address]*big.Int
+	spender    common.Address
+	txCount    uint64
+}
+
+// NewMemoryChain creates an empty in-memory chain
+func NewMemoryChain() *MemoryChain {
+	return &MemoryChain{
+		balances:   make(map[common.Address]map[common.Address]*big.Int),
+		usedNonces: make(map[common.Address]map[common.Address]map[[32]byte]bool),
+		permits:    make(map[common.Address]map[common.Address]*big.Int),
+	}
+}
+
+// SetSpender sets the account settling upto payments, which payers must permit
+func (m *MemoryChain) SetSpender(spender common.Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.spender = spender
+}
+
+// SetBalance sets the token balance of the account
+func (m *MemoryChain) SetBalance(token, account common.Address, balance *big.Int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.balances[token] == nil {
+		m.balances[token] = make(map[common.Address]*big.Int)
+	}
+	m.balances[token][account] = new(big.Int).Set(balance)
+}
+
+// UseNonce marks the ERC-3009 nonce of the authorizer as used
+func (m *MemoryChain) UseNonce(token, authorizer common.Address, nonce [32]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.useNonce(token, authorizer, nonce)
+}
+
+// BalanceOf returns the token balance of the account
+func (m *MemoryChain) BalanceOf(_ context.Context, token, account common.Address) (*big.Int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return new(big.Int).Set(m.balanceOf(token, account)), nil
+}
+
+// AuthorizationState returns whether the nonce of the authorizer has been used
+func (m *MemoryChain) AuthorizationState(_ context.Context, token, authorizer common.Address, nonce [32]byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.usedNonces[token][authorizer][nonce], nil
+}
+
+// SimulateTransferWithAuthorization checks the nonce and balance the token contract would check
+func (m *MemoryChain) SimulateTransferWithAuthorization(_ context.Context, token common.Address, auth *evm.TransferWithAuthorization, _ []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.usedNonces[token][auth.From][auth.Nonce] {
+		return fmt.Errorf("authorization is used or canceled")
+	}
+	if m.balanceOf(token, auth.From).Cmp(auth.Value) < 0 {
+		return fmt.Errorf("transfer amount exceeds balance")
+	}
+
+	return nil
+}
+
+// TransferWithAuthorization moves the authorized value between accounts and marks the nonce as used
+func (m *MemoryChain) TransferWithAuthorization(_ context.Context, token common.Address, auth *evm.TransferWithAuthorization, _ []byte) (common.Hash, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.txCount++
+	txHash := crypto.Keccak256Hash(token.Bytes(), auth.From.Bytes(), auth.Nonce[:], new(big.Int).SetUint64(m.txCount).Bytes())
+
+	if m.usedNonces[token][auth.From][auth.Nonce] {
+		return txHash, fmt.Errorf("%w: authorization is used or canceled", ErrTransactionFailed)
+	}
+	from := m.balanceOf(token, auth.From)
+	if from.Cmp(auth.Value) < 0 {
+		return txHash, fmt.Errorf("%w: transfer amount exceeds balance", ErrTransactionFailed)
+	}
+
+	if m.balances[token] == nil {
+		m.balances[token] = make(map[common.Address]*big.Int)
+	}
+	m.balances[token][auth.From] = new(big.Int).Sub(from, auth.Value)
+	m.balances[token][auth.To] = new(big.Int).Add(m.balanceOf(token, auth.To), auth.Value)
+	m.useNonce(token, auth.From, auth.Nonce)
+
+	return txHash, nil
+}
+
+// PermitNonce returns the current permit nonce of the owner
+func (m *MemoryChain) PermitNonce(_ context.Context, token, owner common.Address) (*big.Int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return new(big.Int).Set(m.permitNonce(token, owner)), nil
+}
+
+// Spender returns the account settling upto payments
+func (m *MemoryChain) Spender() common.Address {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.spender
+}
+
+// PermitTransferFrom consumes the permit nonce and moves amount from the permit owner to the recipient
+func (m *MemoryChain) PermitTransferFrom(_ context.Context, token common.Address, permit *evm.Permit, _ []byte, to common.Address, amount *big.Int) (common.Hash, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.txCount++
+	txHash := crypto.Keccak256Hash(token.Bytes(), permit.Owner.Bytes(), permit.Nonce.Bytes(), new(big.Int).SetUint64(m.txCount).Bytes())
+
+	nonce := m.permitNonce(token, permit.Owner)
+	if permit.Nonce.Cmp(nonce) != 0 {
+		return txHash, fmt.Errorf("%w: invalid permit nonce", ErrTransactionFailed)
+	}
+	if amount.Cmp(permit.Value) > 0 {
+		return txHash, fmt.Errorf("%w: insufficient allowance", ErrTransactionFailed)
+	}
+	from := m.balanceOf(token, permit.Owner)
+	if from.Cmp(amount) < 0 {
+		return txHash, fmt.Errorf("%w: transfer amount exceeds balance", ErrTransactionFailed)
+	}
+
+	if m.balances[token] == nil {
+		m.balances[token] = make(map[common.Address]*big.Int)
+	}
+	if m.permits[token] == nil {
+		m.permits[token] = make(map[common.Address]*big.Int)
+	}
+	m.permits[token][permit.Owner] = new(big.Int).Add(nonce, big.NewInt(1))
+	m.balances[token][permit.Owner] = new(big.Int).Sub(from, amount)
+	m.balances[token][to] = new(big.Int).Add(m.balanceOf(token, to), amount)
+
+	return txHash, nil
+}
+
+func (m *MemoryChain) permitNonce(token, owner common.Address) *big.Int {
+	if nonce, ok := m.permits[token][owner]; ok {
+		return nonce
+	}
+	return new(big.Int)
+}
+
+func (m *MemoryChain) balanceOf(token, account common.Address) *big.Int {
+	if balance, ok := m.balances[token][account]; ok {
+		return balance
+	}
+	return new(big.Int)
+}
+
+func (m *MemoryChain) useNonce(token, authorizer common.Address, nonce [32]byte) {
+	if m.usedNonces[token] == nil {
+		m.usedNonces[token] = make(map[common.Address]map[[32]byte]bool)
+	}
+	if m.usedNonces[token][authorizer] == nil {
+		m.usedNonces[token][authorizer] = make(map[[32]byte]bool)
+	}
+	m.usedNonces[token][authorizer][nonce] = true
+}
@@ -0,0 +1,232 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/v2"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+)
+
+// eip3009ABI is the subset of the EIP-3009 and EIP-2612 token ABI used by the facilitator
+const eip3009ABI = `[
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"authorizationState","stateMutability":"view","inputs":[{"name":"authorizer","type":"address"},{"name":"nonce","type":"bytes32"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"nonces","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"permit","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[]},
+	{"type":"function","name":"transferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"transferWithAuthorization","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"validAfter","type":"uint256"},{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[]}
+]`
+
+var parsedEIP3009ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(eip3009ABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// RPCChain reads and writes chain state through an Ethereum JSON-RPC endpoint.
+// Settlement transactions are signed with the facilitator's private key, which pays the gas.
+type RPCChain struct {
+	client  *ethclient.Client
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+}
+
+// NewRPCChain creates a chain backed by the JSON-RPC client, submitting transactions signed by key
+func NewRPCChain(ctx context.Context, client *ethclient.Client, key *ecdsa.PrivateKey) (*RPCChain, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	return &RPCChain{
+		client:  client,
+		key:     key,
+		chainID: chainID,
+	}, nil
+}
+
+// Address returns the address of the facilitator account submitting settlement transactions
+func (c *RPCChain) Address() common.Address {
+	return crypto.PubkeyToAddress(c.key.PublicKey)
+}
+
+// ChainID returns the chain ID reported by the RPC endpoint
+func (c *RPCChain) ChainID() *big.Int {
+	return new(big.Int).Set(c.chainID)
+}
+
+// BalanceOf returns the token balance of the account
+func (c *RPCChain) BalanceOf(ctx context.Context, token, account common.Address) (*big.Int, error) {
+	var balance *big.Int
+	if err := c.call(ctx, token, &balance, "balanceOf", account); err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// AuthorizationState returns whether the nonce of the authorizer has been used
+func (c *RPCChain) AuthorizationState(ctx context.Context, token, authorizer common.Address, nonce [32]byte) (bool, error) {
+	var used bool
+	if err := c.call(ctx, token, &used, "authorizationState", authorizer, nonce); err != nil {
+		return false, err
+	}
+	return used, nil
+}
+
+// SimulateTransferWithAuthorization executes transferWithAuthorization with eth_call
+func (c *RPCChain) SimulateTransferWithAuthorization(ctx context.Context, token common.Address, auth *evm.TransferWithAuthorization, signature []byte) error {
+	data, err := packTransferWithAuthorization(auth, signature)
+	if err != nil {
+		return err
+	}
+
+	from := c.Address()
+	if _, err := c.client.CallContract(ctx, ethereum.CallMsg{From: from, To: &token, Data: data}, nil); err != nil {
+		return fmt.Errorf("transferWithAuthorization simulation failed: %w", err)
+	}
+
+	return nil
+}
+
+// TransferWithAuthorization submits transferWithAuthorization and waits for the receipt
+func (c *RPCChain) TransferWithAuthorization(ctx context.Context, token common.Address, auth *evm.TransferWithAuthorization, signature []byte) (common.Hash, error) {
+	data, err := packTransferWithAuthorization(auth, signature)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return c.transact(ctx, token, data)
+}
+
+// PermitNonce returns the EIP-2612 nonce of the owner
+func (c *RPCChain) PermitNonce(ctx context.Context, token, owner common.Address) (*big.Int, error) {
+	var nonce *big.Int
+	if err := c.call(ctx, token, &nonce, "nonces", owner); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// Spender returns the facilitator account, which submits the permit and transferFrom transactions
+func (c *RPCChain) Spender() common.Address {
+	return c.Address()
+}
+
+// PermitTransferFrom submits permit then transferFrom, waiting for each receipt.
+// It returns the hash of the transferFrom transaction.
+func (c *RPCChain) PermitTransferFrom(ctx context.Context, token common.Address, permit *evm.Permit, signature []byte, to common.Address, amount *big.Int) (common.Hash, error) {
+	if permit.Spender != c.Address() {
+		return common.Hash{}, fmt.Errorf("permit spender %s is not the facilitator account %s", permit.Spender.Hex(), c.Address().Hex())
+	}
+
+	v, r, s, err := splitSignature(signature)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	data, err := parsedEIP3009ABI.Pack("permit", permit.Owner, permit.Spender, permit.Value, permit.Deadline, v, r, s)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to pack permit call: %w", err)
+	}
+	if txHash, err := c.transact(ctx, token, data); err != nil {
+		return txHash, err
+	}
+
+	data, err = parsedEIP3009ABI.Pack("transferFrom", permit.Owner, to, amount)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to pack transferFrom call: %w", err)
+	}
+
+	return c.transact(ctx, token, data)
+}
+
+// transact submits a transaction calling the token contract and waits for the receipt
+func (c *RPCChain) transact(ctx context.Context, token common.Address, data []byte) (common.Hash, error) {
+	contract := bind.NewBoundContract(token, parsedEIP3009ABI, c.client, c.client, c.client)
+	opts := bind.NewKeyedTransactor(c.key, c.chainID)
+	opts.Context = ctx
+
+	tx, err := contract.RawTransact(opts, data)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	receipt, err := bind.WaitMined(ctx, c.client, tx.Hash())
+	if err != nil {
+		return tx.Hash(), fmt.Errorf("failed to wait for transaction receipt: %w", err)
+	}
+	if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		return tx.Hash(), ErrTransactionFailed
+	}
+
+	return tx.Hash(), nil
+}
+
+// call executes a view function of the token contract and unpacks its return value into out
+func (c *RPCChain) call(ctx context.Context, token common.Address, out any, method string, args ...any) error {
+	data, err := parsedEIP3009ABI.Pack(method, args...)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s call: %w", method, err)
+	}
+
+	result, err := c.client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+
+	if err := parsedEIP3009ABI.UnpackIntoInterface(out, method, result); err != nil {
+		return fmt.Errorf("failed to unpack %s result: %w", method, err)
+	}
+
+	return nil
+}
+
+// packTransferWithAuthorization packs the transferWithAuthorization call with the signature split into v, r and s
+func packTransferWithAuthorization(auth *evm.TransferWithAuthorization, signature []byte) ([]byte, error) {
+	v, r, s, err := splitSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsedEIP3009ABI.Pack(
+		"transferWithAuthorization",
+		auth.From,
+		auth.To,
+		auth.Value,
+		auth.ValidAfter,
+		auth.ValidBefore,
+		auth.Nonce,
+		v,
+		r,
+		s,
+	)
+}
+
+// splitSignature splits a 65 byte signature into its v, r and s components, with v being 27 or 28
+func splitSignature(signature []byte) (uint8, [32]byte, [32]byte, error) {
+	var r, s [32]byte
+	if len(signature) != crypto.SignatureLength {
+		return 0, r, s, fmt.Errorf("invalid signature length: %d", len(signature))
+	}
+
+	copy(r[:], signature[:32])
+	copy(s[:], signature[32:64])
+	v := signature[crypto.RecoveryIDOffset]
+	if v < 27 {
+		v += 27
+	}
+
+	return v, r, s, nil
+}
@@ -0,0 +1,88 @@
+package facilitator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// facilitatorRequest is the body of a verify or settle request, as sent by facilitatorclient
+type facilitatorRequest struct {
+	X402Version         int                        `json:"x402Version"`
+	PaymentPayload      *types.PaymentPayload      `json:"paymentPayload"`
+	PaymentRequirements *types.PaymentRequirements `json:"paymentRequirements"`
+}
+
+// NewHandler returns an http.Handler serving the facilitator's /verify, /settle and /supported endpoints
+func NewHandler(f *Facilitator) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /verify", func(w http.ResponseWriter, r *http.Request) {
+		req, err := decodeFacilitatorRequest(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+
+		response, err := f.verifyPayment(r.Context(), req.PaymentPayload, req.PaymentRequirements)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	mux.HandleFunc("POST /settle", func(w http.ResponseWriter, r *http.Request) {
+		req, err := decodeFacilitatorRequest(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+
+		response, err := f.settlePayment(r.Context(), req.PaymentPayload, req.PaymentRequirements)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	mux.HandleFunc("GET /supported", func(w http.ResponseWriter, r *http.Request) {
+		response, err := f.Supported()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	return mux
+}
+
+// decodeFacilitatorRequest decodes and checks the body of a verify or settle request
+func decodeFacilitatorRequest(r *http.Request) (*facilitatorRequest, error) {
+	var req facilitatorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	if req.PaymentPayload == nil || req.PaymentRequirements == nil {
+		return nil, fmt.Errorf("paymentPayload and paymentRequirements are required")
+	}
+	if req.X402Version != x402Version {
+		return nil, fmt.Errorf("unsupported x402Version: %d", req.X402Version)
+	}
+
+	return &req, nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
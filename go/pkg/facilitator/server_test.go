@@ -0,0 +1,100 @@
+package facilitator_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/facilitator"
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// newTestServer serves the env's facilitator over HTTP and returns a client for it.
+func newTestServer(t *testing.T, env *testEnv) (*httptest.Server, *facilitatorclient.FacilitatorClient) {
+	t.Helper()
+
+	server := httptest.NewServer(facilitator.NewHandler(env.facilitator))
+	t.Cleanup(server.Close)
+
+	return server, facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+}
+
+func TestServer_VerifyAndSettle(t *testing.T) {
+	env := newTestEnv(t)
+	_, client := newTestServer(t, env)
+	requirements := newTestRequirements()
+	payload := env.signPayload(t, requirements, nil)
+
+	verifyResp, err := client.Verify(payload, requirements)
+	require.NoError(t, err)
+	assert.True(t, verifyResp.IsValid)
+
+	settleResp, err := client.Settle(payload, requirements)
+	require.NoError(t, err)
+	assert.True(t, settleResp.Success)
+	assert.Equal(t, testNetwork, settleResp.Network)
+	assert.NotEmpty(t, settleResp.Transaction)
+
+	usdc := common.HexToAddress(testUSDC)
+	payerBalance, _ := env.chain.BalanceOf(context.Background(), usdc, env.payer)
+	payToBalance, _ := env.chain.BalanceOf(context.Background(), usdc, common.HexToAddress(testPayTo))
+	assert.Equal(t, big.NewInt(9_000_000), payerBalance)
+	assert.Equal(t, big.NewInt(1_000_000), payToBalance)
+
+	// The authorization nonce has been used, so the payment can't be settled twice
+	settleResp, err = client.Settle(payload, requirements)
+	require.NoError(t, err)
+	assert.False(t, settleResp.Success)
+	require.NotNil(t, settleResp.ErrorReason)
+	assert.Equal(t, "invalid_transaction_state", *settleResp.ErrorReason)
+}
+
+func TestServer_Supported(t *testing.T) {
+	env := newTestEnv(t)
+	server, _ := newTestServer(t, env)
+
+	resp, err := http.Get(server.URL + "/supported")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var supported types.SupportedPaymentKindsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&supported))
+	assert.Equal(t, []types.SupportedPaymentKind{
+		{X402Version: 1, Scheme: "exact", Network: testNetwork},
+	}, supported.Kinds)
+}
+
+func TestServer_InvalidRequest(t *testing.T) {
+	env := newTestEnv(t)
+	server, _ := newTestServer(t, env)
+
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed JSON", body: "{"},
+		{name: "missing payment payload", body: `{"x402Version":1,"paymentRequirements":{}}`},
+		{name: "unsupported version", body: `{"x402Version":2,"paymentPayload":{},"paymentRequirements":{}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL+"/verify", "application/json", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
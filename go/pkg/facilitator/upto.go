@@ -0,0 +1,174 @@
+package facilitator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// uptoPayment is a verified upto EVM payment ready to be settled
+type uptoPayment struct {
+	token     common.Address
+	permit    *evm.Permit
+	signature []byte
+	amount    *big.Int
+}
+
+// uptoExtra returns the extra information advertised for upto payments settled by the writer
+func uptoExtra(writer PermitWriter) *json.RawMessage {
+	extra, _ := json.Marshal(map[string]string{"spender": writer.Spender().Hex()})
+	rawMessage := json.RawMessage(extra)
+	return &rawMessage
+}
+
+// verifyUpto verifies an upto EVM payment, an EIP-2612 permit of at least the maximum amount
+// required to the spender of the payment requirements
+func (f *Facilitator) verifyUpto(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, *uptoPayment, error) {
+	if requirements == nil {
+		return invalid(types.ErrorReasonInvalidPaymentRequirements, ""), nil, nil
+	}
+	if requirements.Scheme != types.SchemeUpto {
+		return invalid(types.ErrorReasonInvalidScheme, ""), nil, nil
+	}
+	if payload.UptoPayload == nil || payload.UptoPayload.Permit == nil {
+		return invalid(types.ErrorReasonInvalidPayload, ""), nil, nil
+	}
+	payer := payload.UptoPayload.Permit.Owner
+
+	// Verify the permit is for the agreed upon chain and ERC20 contract
+	if payload.Network != requirements.Network {
+		return invalid(types.ErrorReasonInvalidNetwork, payer), nil, nil
+	}
+	chain, ok := f.Chains[requirements.Network].(PermitReader)
+	if !ok {
+		return invalid(types.ErrorReasonInvalidNetwork, payer), nil, nil
+	}
+	domain, err := evm.DomainForRequirements(requirements)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidNetwork, payer), nil, nil
+	}
+
+	permit, err := evm.ParsePermit(payload.UptoPayload.Permit)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidPayload, payer), nil, nil
+	}
+	signature, err := hexutil.Decode(payload.UptoPayload.Signature)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadSignature, payer), nil, nil
+	}
+
+	// Verify the signature was produced by the owner
+	signer, err := evm.RecoverTypedDataSigner(permit.TypedData(domain), signature)
+	if err != nil || signer != permit.Owner {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadSignature, payer), nil, nil
+	}
+
+	// Verify the permit is given to the spender settling the payment
+	spender, err := evm.UptoSpender(requirements)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidPaymentRequirements, payer), nil, nil
+	}
+	if permit.Spender != spender {
+		return invalid(types.ErrorReasonInvalidPayload, payer), nil, nil
+	}
+	if !common.IsHexAddress(requirements.PayTo) {
+		return invalid(types.ErrorReasonInvalidPaymentRequirements, payer), nil, nil
+	}
+
+	maxAmountRequired, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+	if !ok {
+		return invalid(types.ErrorReasonInvalidPaymentRequirements, payer), nil, nil
+	}
+
+	// Verify the permit value covers the maximum amount
+	if permit.Value.Cmp(maxAmountRequired) < 0 {
+		return invalid(types.ErrorReasonInvalidExactEvmPayloadAuthorizationValue, payer), nil, nil
+	}
+
+	// Verify the permit has not expired
+	if permit.Deadline.Cmp(big.NewInt(f.Now().Add(validBeforeBuffer).Unix())) < 0 {
+		return invalid(types.ErrorReasonPaymentExpired, payer), nil, nil
+	}
+
+	// Verify the owner has enough of the asset to cover the maximum amount
+	balance, err := f.Chains[requirements.Network].BalanceOf(ctx, domain.VerifyingContract, permit.Owner)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read balance: %w", err)
+	}
+	if balance.Cmp(maxAmountRequired) < 0 {
+		return invalid(types.ErrorReasonInsufficientFunds, payer), nil, nil
+	}
+
+	// Verify the permit nonce is the next nonce of the owner
+	nonce, err := chain.PermitNonce(ctx, domain.VerifyingContract, permit.Owner)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read permit nonce: %w", err)
+	}
+	if permit.Nonce.Cmp(nonce) != 0 {
+		return invalid(types.ErrorReasonInvalidTransactionState, payer), nil, nil
+	}
+
+	return &types.VerifyResponse{
+		IsValid: true,
+		Payer:   &payer,
+	}, &uptoPayment{token: domain.VerifyingContract, permit: permit, signature: signature, amount: maxAmountRequired}, nil
+}
+
+// settleUpto settles an upto EVM payment by submitting the permit and transferring the
+// maxAmountRequired of the requirements, which is the amount actually used
+func (f *Facilitator) settleUpto(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	verifyResponse, payment, err := f.verifyUpto(ctx, payload, requirements)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyResponse.IsValid {
+		return &types.SettleResponse{
+			Success:     false,
+			ErrorReason: verifyResponse.InvalidReason,
+			Network:     requirements.Network,
+			Payer:       verifyResponse.Payer,
+		}, nil
+	}
+
+	writer, ok := f.Chains[requirements.Network].(PermitWriter)
+	if !ok {
+		return nil, fmt.Errorf("upto settlement is not supported on network %s", requirements.Network)
+	}
+	if writer.Spender() != payment.permit.Spender {
+		return &types.SettleResponse{
+			Success:     false,
+			ErrorReason: types.ReasonPtr(types.ErrorReasonInvalidPaymentRequirements),
+			Network:     requirements.Network,
+			Payer:       verifyResponse.Payer,
+		}, nil
+	}
+
+	txHash, err := writer.PermitTransferFrom(ctx, payment.token, payment.permit, payment.signature, common.HexToAddress(requirements.PayTo), payment.amount)
+	if errors.Is(err, ErrTransactionFailed) {
+		return &types.SettleResponse{
+			Success:     false,
+			ErrorReason: types.ReasonPtr(types.ErrorReasonInvalidTransactionState),
+			Transaction: txHash.Hex(),
+			Network:     requirements.Network,
+			Payer:       verifyResponse.Payer,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit settlement transaction: %w", err)
+	}
+
+	return &types.SettleResponse{
+		Success:     true,
+		Transaction: txHash.Hex(),
+		Network:     requirements.Network,
+		Payer:       verifyResponse.Payer,
+	}, nil
+}
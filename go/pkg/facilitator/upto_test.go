@@ -0,0 +1,223 @@
+package facilitator_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+const testSpender = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+
+func newTestUptoRequirements(t *testing.T) *types.PaymentRequirements {
+	t.Helper()
+
+	requirements := newTestRequirements()
+	requirements.Scheme = types.SchemeUpto
+
+	var extra map[string]any
+	require.NoError(t, json.Unmarshal(*requirements.Extra, &extra))
+	extra["spender"] = testSpender
+	extraBytes, err := json.Marshal(extra)
+	require.NoError(t, err)
+	rawMessage := json.RawMessage(extraBytes)
+	requirements.Extra = &rawMessage
+
+	return requirements
+}
+
+// signUptoPayload builds and signs an upto EVM payload with the env's payer key.
+func (e *testEnv) signUptoPayload(t *testing.T, requirements *types.PaymentRequirements, mutate func(*types.UptoEvmPayloadPermit)) *types.PaymentPayload {
+	t.Helper()
+
+	permit := &types.UptoEvmPayloadPermit{
+		Owner:    e.payer.Hex(),
+		Spender:  testSpender,
+		Value:    requirements.MaxAmountRequired,
+		Nonce:    "0",
+		Deadline: big.NewInt(testNow.Add(time.Minute).Unix()).String(),
+	}
+	if mutate != nil {
+		mutate(permit)
+	}
+
+	parsed, err := evm.ParsePermit(permit)
+	require.NoError(t, err)
+	domain, err := evm.DomainForRequirements(requirements)
+	require.NoError(t, err)
+	hash, err := evm.HashTypedData(parsed.TypedData(domain))
+	require.NoError(t, err)
+	signature, err := crypto.Sign(hash, e.key)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return &types.PaymentPayload{
+		X402Version: 1,
+		Scheme:      types.SchemeUpto,
+		Network:     requirements.Network,
+		UptoPayload: &types.UptoEvmPayload{
+			Signature: hexutil.Encode(signature),
+			Permit:    permit,
+		},
+	}
+}
+
+func TestVerifyUpto_ValidPayment(t *testing.T) {
+	env := newTestEnv(t)
+	requirements := newTestUptoRequirements(t)
+
+	resp, err := env.facilitator.Verify(env.signUptoPayload(t, requirements, nil), requirements)
+	require.NoError(t, err)
+	assert.True(t, resp.IsValid)
+	require.NotNil(t, resp.Payer)
+	assert.Equal(t, env.payer.Hex(), *resp.Payer)
+}
+
+func TestVerifyUpto_InvalidPayments(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mutatePermit  func(*types.UptoEvmPayloadPermit)
+		mutatePayload func(*types.PaymentPayload)
+		setup         func(*testEnv)
+		// mutateRequirements changes the requirements once the payload is signed
+		mutateRequirements func(*types.PaymentRequirements)
+		expectedReason     string
+	}{
+		{
+			name: "tampered value",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.UptoPayload.Permit.Value = "2000000"
+			},
+			expectedReason: "invalid_exact_evm_payload_signature",
+		},
+		{
+			name: "value below maximum amount",
+			mutatePermit: func(p *types.UptoEvmPayloadPermit) {
+				p.Value = "999999"
+			},
+			expectedReason: "invalid_exact_evm_payload_authorization_value",
+		},
+		{
+			name: "wrong spender",
+			mutatePermit: func(p *types.UptoEvmPayloadPermit) {
+				p.Spender = testPayTo
+			},
+			expectedReason: "invalid_payload",
+		},
+		{
+			name: "expired permit",
+			mutatePermit: func(p *types.UptoEvmPayloadPermit) {
+				p.Deadline = big.NewInt(testNow.Add(3 * time.Second).Unix()).String()
+			},
+			expectedReason: "payment_expired",
+		},
+		{
+			name: "stale nonce",
+			setup: func(e *testEnv) {
+				e.chain.SetSpender(common.HexToAddress(testSpender))
+				permit := &evm.Permit{Owner: e.payer, Value: big.NewInt(1), Nonce: big.NewInt(0)}
+				_, err := e.chain.PermitTransferFrom(context.Background(), common.HexToAddress(testUSDC), permit, nil, common.HexToAddress(testPayTo), big.NewInt(1))
+				if err != nil {
+					panic(err)
+				}
+			},
+			expectedReason: "invalid_transaction_state",
+		},
+		{
+			name: "insufficient balance",
+			setup: func(e *testEnv) {
+				e.chain.SetBalance(common.HexToAddress(testUSDC), e.payer, big.NewInt(999_999))
+			},
+			expectedReason: "insufficient_funds",
+		},
+		{
+			name: "network mismatch",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.Network = "base"
+			},
+			expectedReason: "invalid_network",
+		},
+		{
+			name: "unparsable max amount required",
+			mutateRequirements: func(r *types.PaymentRequirements) {
+				r.MaxAmountRequired = "1.5"
+			},
+			expectedReason: "invalid_payment_requirements",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			requirements := newTestUptoRequirements(t)
+			if tc.setup != nil {
+				tc.setup(env)
+			}
+
+			payload := env.signUptoPayload(t, requirements, tc.mutatePermit)
+			if tc.mutatePayload != nil {
+				tc.mutatePayload(payload)
+			}
+			if tc.mutateRequirements != nil {
+				tc.mutateRequirements(requirements)
+			}
+
+			resp, err := env.facilitator.Verify(payload, requirements)
+			require.NoError(t, err)
+			assert.False(t, resp.IsValid)
+			require.NotNil(t, resp.InvalidReason)
+			assert.Equal(t, tc.expectedReason, *resp.InvalidReason)
+		})
+	}
+}
+
+func TestSettleUpto_ChargesUsage(t *testing.T) {
+	env := newTestEnv(t)
+	env.chain.SetSpender(common.HexToAddress(testSpender))
+	requirements := newTestUptoRequirements(t)
+	payload := env.signUptoPayload(t, requirements, nil)
+
+	// The resource server settles the amount used, below the permitted maximum
+	usage := *requirements
+	usage.MaxAmountRequired = "250000"
+
+	resp, err := env.facilitator.Settle(payload, &usage)
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.NotEmpty(t, resp.Transaction)
+
+	token := common.HexToAddress(testUSDC)
+	payTo, err := env.chain.BalanceOf(context.Background(), token, common.HexToAddress(testPayTo))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(250_000), payTo)
+	payer, err := env.chain.BalanceOf(context.Background(), token, env.payer)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(9_750_000), payer)
+
+	// The permit cannot be replayed
+	resp, err = env.facilitator.Settle(payload, &usage)
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+}
+
+func TestSettleUpto_WrongSpender(t *testing.T) {
+	env := newTestEnv(t)
+	env.chain.SetSpender(common.HexToAddress(testPayTo))
+	requirements := newTestUptoRequirements(t)
+
+	resp, err := env.facilitator.Settle(env.signUptoPayload(t, requirements, nil), requirements)
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	require.NotNil(t, resp.ErrorReason)
+	assert.Equal(t, "invalid_payment_requirements", *resp.ErrorReason)
+}
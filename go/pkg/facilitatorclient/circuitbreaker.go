@@ -0,0 +1,110 @@
+package facilitatorclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by the errors of facilitator calls failed fast by an open circuit breaker
+var ErrCircuitOpen = errors.New("facilitator circuit breaker is open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker fails facilitator calls fast while the facilitator is down. It opens after
+// FailureThreshold consecutive timeouts, network failures or 5xx responses, then lets a single
+// trial call through once OpenTimeout has elapsed, closing again if it succeeds.
+type CircuitBreaker struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		OpenTimeout:      openTimeout,
+	}
+}
+
+// WithCircuitBreaker sets the circuit breaker of the client's calls. A circuit breaker may be
+// shared by the clients of the same facilitator.
+func WithCircuitBreaker(breaker *CircuitBreaker) ClientOption {
+	return func(c *FacilitatorClient) {
+		c.CircuitBreaker = breaker
+	}
+}
+
+// Allow returns ErrCircuitOpen if a call must not be made
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Now().Sub(b.openedAt) < b.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		// A trial call is in flight
+		return ErrCircuitOpen
+	default:
+		return nil
+	}
+}
+
+// Record records the outcome of an allowed call
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A canceled call tells nothing of the facilitator, the next call is a trial again
+	if errors.Is(err, ErrCanceled) {
+		if b.state == circuitHalfOpen {
+			b.state = circuitOpen
+		}
+		return
+	}
+
+	if !isFacilitatorFailure(err) {
+		b.state = circuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.FailureThreshold {
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// isFacilitatorFailure reports whether err shows the facilitator is unavailable
+func isFacilitatorFailure(err error) bool {
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) {
+		return false
+	}
+
+	switch requestErr.Kind {
+	case ErrTimeout, ErrNetwork:
+		return true
+	case ErrRejected:
+		return requestErr.StatusCode >= 500
+	default:
+		return false
+	}
+}
@@ -0,0 +1,212 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// loggingFacilitator logs the calls to a facilitator
+type loggingFacilitator struct {
+	next   Facilitator
+	logger *slog.Logger
+}
+
+// NewLoggingFacilitator logs the calls to the facilitator f with their duration and outcome.
+// A nil logger logs to slog.Default().
+func NewLoggingFacilitator(f Facilitator, logger *slog.Logger) Facilitator {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &loggingFacilitator{next: f, logger: logger}
+}
+
+func (l *loggingFacilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	start := time.Now()
+	response, err := l.next.VerifyContext(ctx, payload, requirements)
+	l.log(ctx, "verify", start, outcome(response, err), paymentAttrs(payload)...)
+	return response, err
+}
+
+func (l *loggingFacilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	start := time.Now()
+	response, err := l.next.SettleContext(ctx, payload, requirements)
+	attrs := paymentAttrs(payload)
+	if err == nil {
+		attrs = append(attrs, slog.String("transaction", response.Transaction))
+	}
+	l.log(ctx, "settle", start, outcome(response, err), attrs...)
+	return response, err
+}
+
+func (l *loggingFacilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	start := time.Now()
+	response, err := l.next.SupportedContext(ctx)
+	l.log(ctx, "supported", start, err)
+	return response, err
+}
+
+// log logs a call, at the error level when the facilitator could not answer
+func (l *loggingFacilitator) log(ctx context.Context, op string, start time.Time, err error, attrs ...slog.Attr) {
+	attrs = append(attrs, slog.String("op", op), slog.Duration("duration", time.Since(start)))
+
+	var paymentErr *types.PaymentError
+	switch {
+	case err == nil:
+		l.logger.LogAttrs(ctx, slog.LevelInfo, "facilitator call", attrs...)
+	case errors.As(err, &paymentErr):
+		attrs = append(attrs, slog.String("reason", string(paymentErr.Reason)))
+		l.logger.LogAttrs(ctx, slog.LevelWarn, "facilitator rejected payment", attrs...)
+	default:
+		attrs = append(attrs, slog.String("error", err.Error()))
+		l.logger.LogAttrs(ctx, slog.LevelError, "facilitator call failed", attrs...)
+	}
+}
+
+func paymentAttrs(payload *types.PaymentPayload) []slog.Attr {
+	if payload == nil {
+		return nil
+	}
+	return []slog.Attr{slog.String("scheme", payload.Scheme), slog.String("network", payload.Network)}
+}
+
+// MetricsRecorder records the calls to a facilitator, for example as Prometheus histograms
+type MetricsRecorder interface {
+	// ObserveCall records a call of op, "verify", "settle" or "supported". err is the error of the
+	// call, or the *types.PaymentError of an invalid payment or failed settlement.
+	ObserveCall(op string, duration time.Duration, err error)
+}
+
+// metricsFacilitator records the calls to a facilitator
+type metricsFacilitator struct {
+	next     Facilitator
+	recorder MetricsRecorder
+}
+
+// NewMetricsFacilitator records the calls to the facilitator f with recorder
+func NewMetricsFacilitator(f Facilitator, recorder MetricsRecorder) Facilitator {
+	return &metricsFacilitator{next: f, recorder: recorder}
+}
+
+func (m *metricsFacilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	start := time.Now()
+	response, err := m.next.VerifyContext(ctx, payload, requirements)
+	m.recorder.ObserveCall("verify", time.Since(start), outcome(response, err))
+	return response, err
+}
+
+func (m *metricsFacilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	start := time.Now()
+	response, err := m.next.SettleContext(ctx, payload, requirements)
+	m.recorder.ObserveCall("settle", time.Since(start), outcome(response, err))
+	return response, err
+}
+
+func (m *metricsFacilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	start := time.Now()
+	response, err := m.next.SupportedContext(ctx)
+	m.recorder.ObserveCall("supported", time.Since(start), err)
+	return response, err
+}
+
+// outcome returns the error of a call, or the error of its response
+func outcome(response interface{ Err() error }, err error) error {
+	if err != nil {
+		return err
+	}
+	return response.Err()
+}
+
+// cachingFacilitator caches the supported payment kinds of a facilitator
+type cachingFacilitator struct {
+	next  Facilitator
+	cache *supportedCache
+}
+
+// NewCachingFacilitator caches the supported payment kinds of the facilitator f for ttl. Errors
+// are not cached. Verifications and settlements are never cached, as each payment must be checked
+// against the current chain state. f is returned as is when it already caches its supported
+// payment kinds for ttl.
+func NewCachingFacilitator(f Facilitator, ttl time.Duration) Facilitator {
+	if c, ok := f.(*cachingFacilitator); ok && c.cache != nil && c.cache.ttl == ttl {
+		return f
+	}
+	return &cachingFacilitator{next: f, cache: newSupportedCache(ttl)}
+}
+
+func (c *cachingFacilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	return c.next.VerifyContext(ctx, payload, requirements)
+}
+
+func (c *cachingFacilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	return c.next.SettleContext(ctx, payload, requirements)
+}
+
+func (c *cachingFacilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	if c.cache == nil {
+		return c.next.SupportedContext(ctx)
+	}
+	return c.cache.get(ctx, c.next.SupportedContext)
+}
+
+// failoverFacilitator calls the first facilitator able to answer
+type failoverFacilitator struct {
+	facilitators []Facilitator
+}
+
+// NewFailoverFacilitator calls the facilitators in order until one answers. Verification fails
+// over on any error, an invalid payment being an answer. Settlement only fails over when the
+// facilitator did not accept the request, so that a payment is never settled twice.
+func NewFailoverFacilitator(facilitators ...Facilitator) Facilitator {
+	return &failoverFacilitator{facilitators: facilitators}
+}
+
+func (f *failoverFacilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	return failover(ctx, f.facilitators, canFailover, func(facilitator Facilitator) (*types.VerifyResponse, error) {
+		return facilitator.VerifyContext(ctx, payload, requirements)
+	})
+}
+
+func (f *failoverFacilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	return failover(ctx, f.facilitators, canFailoverSettle, func(facilitator Facilitator) (*types.SettleResponse, error) {
+		return facilitator.SettleContext(ctx, payload, requirements)
+	})
+}
+
+func (f *failoverFacilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	return failover(ctx, f.facilitators, canFailover, func(facilitator Facilitator) (*types.SupportedPaymentKindsResponse, error) {
+		return facilitator.SupportedContext(ctx)
+	})
+}
+
+// failover makes the call with each facilitator in turn, until it succeeds or fails with an error
+// that can't fail over
+func failover[F, R any](ctx context.Context, facilitators []F, canFailover func(err error) bool, call func(F) (*R, error)) (*R, error) {
+	err := errors.New("no facilitator configured")
+	for _, facilitator := range facilitators {
+		var response *R
+		response, err = call(facilitator)
+		if err == nil {
+			return response, nil
+		}
+		if ctx.Err() != nil || !canFailover(err) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+// canFailover reports whether an idempotent call can be made with another facilitator
+func canFailover(err error) bool {
+	return !errors.Is(err, ErrCanceled)
+}
+
+// canFailoverSettle reports whether a settlement can be made with another facilitator, which is
+// only the case when the failed facilitator did not accept it
+func canFailoverSettle(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || retryable("settle", err)
+}
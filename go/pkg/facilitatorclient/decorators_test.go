@@ -0,0 +1,270 @@
+package facilitatorclient_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// fakeFacilitator answers with the configured responses and errors, counting the calls
+type fakeFacilitator struct {
+	verifyResponse    *types.VerifyResponse
+	verifyErr         error
+	settleResponse    *types.SettleResponse
+	settleErr         error
+	supportedResponse *types.SupportedPaymentKindsResponse
+	supportedErr      error
+
+	verifyCalls    int
+	settleCalls    int
+	supportedCalls int
+}
+
+func (f *fakeFacilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	f.verifyCalls++
+	return f.verifyResponse, f.verifyErr
+}
+
+func (f *fakeFacilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	f.settleCalls++
+	return f.settleResponse, f.settleErr
+}
+
+func (f *fakeFacilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	f.supportedCalls++
+	return f.supportedResponse, f.supportedErr
+}
+
+// recordedCall is a call observed by a recordingMetrics
+type recordedCall struct {
+	op  string
+	err error
+}
+
+type recordingMetrics struct {
+	calls []recordedCall
+}
+
+func (m *recordingMetrics) ObserveCall(op string, duration time.Duration, err error) {
+	m.calls = append(m.calls, recordedCall{op: op, err: err})
+}
+
+var (
+	testPayload      = &types.PaymentPayload{X402Version: 1, Scheme: "exact", Network: "base-sepolia"}
+	testRequirements = &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+)
+
+func TestSupported(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/supported" {
+			t.Errorf("Expected to request '/supported', got: %s", r.URL.Path)
+		}
+		if r.Method != http.MethodGet {
+			t.Errorf("Expected GET request, got: %s", r.Method)
+		}
+
+		json.NewEncoder(w).Encode(types.SupportedPaymentKindsResponse{
+			Kinds: []types.SupportedPaymentKind{{X402Version: 1, Scheme: "exact", Network: "base-sepolia"}},
+		})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.Supported()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !resp.Supports("exact", "base-sepolia") || resp.Supports("exact", "base") {
+		t.Errorf("Expected only the base-sepolia kind, got: %+v", resp.Kinds)
+	}
+
+	// The response is cached
+	client.Supported()
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got: %d", requests)
+	}
+
+	uncached := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL}, facilitatorclient.WithSupportedCacheTTL(0))
+	uncached.Supported()
+	uncached.Supported()
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got: %d", requests)
+	}
+}
+
+func TestLoggingFacilitator(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	invalidReason := types.ReasonPtr(types.ErrorReasonInsufficientFunds)
+
+	fake := &fakeFacilitator{
+		verifyResponse: &types.VerifyResponse{InvalidReason: invalidReason},
+		settleErr:      &facilitatorclient.RequestError{Op: "settle", Kind: facilitatorclient.ErrRejected, StatusCode: 500, Status: "500 Internal Server Error"},
+	}
+	f := facilitatorclient.NewLoggingFacilitator(fake, logger)
+
+	resp, err := f.VerifyContext(context.Background(), testPayload, testRequirements)
+	if err != nil || resp.IsValid {
+		t.Fatalf("Expected the invalid response, got: %v, %v", resp, err)
+	}
+	if _, err := f.SettleContext(context.Background(), testPayload, testRequirements); !errors.Is(err, facilitatorclient.ErrRejected) {
+		t.Fatalf("Expected the settle error, got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got: %q", logs.String())
+	}
+	for _, expected := range []string{"level=WARN", "op=verify", "reason=insufficient_funds", "network=base-sepolia"} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("Expected verify log to contain %q, got: %s", expected, lines[0])
+		}
+	}
+	for _, expected := range []string{"level=ERROR", "op=settle", "500 Internal Server Error"} {
+		if !strings.Contains(lines[1], expected) {
+			t.Errorf("Expected settle log to contain %q, got: %s", expected, lines[1])
+		}
+	}
+}
+
+func TestMetricsFacilitator(t *testing.T) {
+	fake := &fakeFacilitator{
+		verifyResponse:    &types.VerifyResponse{IsValid: true},
+		settleResponse:    &types.SettleResponse{ErrorReason: types.ReasonPtr(types.ErrorReasonInvalidTransactionState)},
+		supportedResponse: &types.SupportedPaymentKindsResponse{},
+	}
+	metrics := &recordingMetrics{}
+	f := facilitatorclient.NewMetricsFacilitator(fake, metrics)
+
+	f.VerifyContext(context.Background(), testPayload, testRequirements)
+	settleResp, err := f.SettleContext(context.Background(), testPayload, testRequirements)
+	if err != nil || settleResp.Success {
+		t.Fatalf("Expected the failed settle response, got: %v, %v", settleResp, err)
+	}
+	f.SupportedContext(context.Background())
+
+	if len(metrics.calls) != 3 {
+		t.Fatalf("Expected 3 recorded calls, got: %d", len(metrics.calls))
+	}
+	if metrics.calls[0].op != "verify" || metrics.calls[0].err != nil {
+		t.Errorf("Expected successful verify, got: %+v", metrics.calls[0])
+	}
+	if metrics.calls[1].op != "settle" || !errors.Is(metrics.calls[1].err, types.ErrorReasonInvalidTransactionState) {
+		t.Errorf("Expected failed settle, got: %+v", metrics.calls[1])
+	}
+	if metrics.calls[2].op != "supported" || metrics.calls[2].err != nil {
+		t.Errorf("Expected successful supported, got: %+v", metrics.calls[2])
+	}
+}
+
+func TestCachingFacilitator(t *testing.T) {
+	fake := &fakeFacilitator{supportedErr: errors.New("unavailable")}
+	f := facilitatorclient.NewCachingFacilitator(fake, 50*time.Millisecond)
+
+	// Errors are not cached
+	if _, err := f.SupportedContext(context.Background()); err == nil {
+		t.Fatal("Expected an error")
+	}
+	fake.supportedErr = nil
+	fake.supportedResponse = &types.SupportedPaymentKindsResponse{}
+	for range 3 {
+		if _, err := f.SupportedContext(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if fake.supportedCalls != 2 {
+		t.Errorf("Expected 2 supported calls, got: %d", fake.supportedCalls)
+	}
+
+	// The response is fetched again once expired
+	time.Sleep(60 * time.Millisecond)
+	f.SupportedContext(context.Background())
+	if fake.supportedCalls != 3 {
+		t.Errorf("Expected 3 supported calls, got: %d", fake.supportedCalls)
+	}
+
+	// Payments are never cached
+	for range 2 {
+		f.VerifyContext(context.Background(), testPayload, testRequirements)
+	}
+	if fake.verifyCalls != 2 {
+		t.Errorf("Expected 2 verify calls, got: %d", fake.verifyCalls)
+	}
+}
+
+func TestFailoverFacilitator(t *testing.T) {
+	unreachable := &facilitatorclient.RequestError{Op: "verify", Kind: facilitatorclient.ErrNetwork, Err: errors.New("connection reset")}
+	serverError := &facilitatorclient.RequestError{Op: "settle", Kind: facilitatorclient.ErrRejected, StatusCode: 500, Status: "500 Internal Server Error"}
+	circuitOpen := &facilitatorclient.RequestError{Op: "settle", Kind: facilitatorclient.ErrCircuitOpen}
+
+	testCases := []struct {
+		name            string
+		primary         *fakeFacilitator
+		op              string
+		expectSecondary bool
+		expectedErr     error
+	}{
+		{
+			name:            "verify fails over on network errors",
+			primary:         &fakeFacilitator{verifyErr: unreachable},
+			op:              "verify",
+			expectSecondary: true,
+		},
+		{
+			name:    "verify does not fail over on invalid payments",
+			primary: &fakeFacilitator{verifyResponse: &types.VerifyResponse{InvalidReason: types.ReasonPtr(types.ErrorReasonInsufficientFunds)}},
+			op:      "verify",
+		},
+		{
+			name:        "settle does not fail over once the request may have been accepted",
+			primary:     &fakeFacilitator{settleErr: serverError},
+			op:          "settle",
+			expectedErr: facilitatorclient.ErrRejected,
+		},
+		{
+			name:            "settle fails over while the circuit is open",
+			primary:         &fakeFacilitator{settleErr: circuitOpen},
+			op:              "settle",
+			expectSecondary: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secondary := &fakeFacilitator{
+				verifyResponse: &types.VerifyResponse{IsValid: true},
+				settleResponse: &types.SettleResponse{Success: true},
+			}
+			f := facilitatorclient.NewFailoverFacilitator(tc.primary, secondary)
+
+			var err error
+			if tc.op == "verify" {
+				_, err = f.VerifyContext(context.Background(), testPayload, testRequirements)
+			} else {
+				_, err = f.SettleContext(context.Background(), testPayload, testRequirements)
+			}
+
+			if tc.expectedErr == nil && err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error matching '%v', got: %v", tc.expectedErr, err)
+			}
+			if calls := secondary.verifyCalls + secondary.settleCalls; (calls == 1) != tc.expectSecondary {
+				t.Errorf("Expected secondary called: %v, got %d calls", tc.expectSecondary, calls)
+			}
+		})
+	}
+}
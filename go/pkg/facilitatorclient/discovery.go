@@ -0,0 +1,97 @@
+package facilitatorclient
+
+import (
+	"context"
+	"math/big"
+	"net/url"
+	"strconv"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ResourceFilter selects the resources returned by FindResources
+type ResourceFilter func(resource types.DiscoveredResource) bool
+
+// AcceptsNetwork selects the resources accepting payments on network
+func AcceptsNetwork(network string) ResourceFilter {
+	return func(resource types.DiscoveredResource) bool {
+		for _, requirements := range resource.Accepts {
+			if requirements.Network == network {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MaxAmountRequired selects the resources that can be paid for at most amount, in atomic units
+func MaxAmountRequired(amount *big.Int) ResourceFilter {
+	return func(resource types.DiscoveredResource) bool {
+		for _, requirements := range resource.Accepts {
+			required, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+			if ok && required.Cmp(amount) <= 0 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ListResources fetches a page of the resources listed by the discovery endpoint of the facilitator
+func (c *FacilitatorClient) ListResources(ctx context.Context, req types.ListDiscoveryResourcesRequest) (*types.ListDiscoveryResourcesResponse, error) {
+	query := url.Values{}
+	if req.Type != "" {
+		query.Set("type", req.Type)
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	if req.Offset > 0 {
+		query.Set("offset", strconv.Itoa(req.Offset))
+	}
+
+	path := "discovery/resources"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var listResp types.ListDiscoveryResourcesResponse
+	if err := c.get(ctx, "list", path, c.VerifyTimeout, &listResp); err != nil {
+		return nil, err
+	}
+
+	return &listResp, nil
+}
+
+// FindResources fetches the pages of the resources listed by the discovery endpoint of the
+// facilitator, starting at the offset of req, and returns the resources selected by all filters
+func (c *FacilitatorClient) FindResources(ctx context.Context, req types.ListDiscoveryResourcesRequest, filters ...ResourceFilter) ([]types.DiscoveredResource, error) {
+	var resources []types.DiscoveredResource
+	for {
+		page, err := c.ListResources(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resource := range page.Items {
+			if matches(resource, filters) {
+				resources = append(resources, resource)
+			}
+		}
+
+		req.Offset += len(page.Items)
+		if len(page.Items) == 0 || req.Offset >= page.Pagination.Total {
+			return resources, nil
+		}
+	}
+}
+
+// matches reports whether the resource is selected by all filters
+func matches(resource types.DiscoveredResource, filters []ResourceFilter) bool {
+	for _, filter := range filters {
+		if !filter(resource) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// newDiscoveryServer creates a facilitator listing a resource per payment requirements, in pages of
+// at most 2 resources
+func newDiscoveryServer(t *testing.T, resources []types.PaymentRequirements) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/discovery/resources" {
+			t.Errorf("Expected to request '/discovery/resources', got: %s", r.URL.Path)
+		}
+		if resourceType := r.URL.Query().Get("type"); resourceType != types.ResourceTypeHTTP {
+			t.Errorf("Expected type 'http', got: %s", resourceType)
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		end := min(offset+2, len(resources))
+
+		response := types.ListDiscoveryResourcesResponse{
+			X402Version: 1,
+			Pagination:  types.DiscoveryPagination{Limit: 2, Offset: offset, Total: len(resources)},
+		}
+		for _, requirements := range resources[offset:end] {
+			response.Items = append(response.Items, types.DiscoveredResource{
+				Resource:    requirements.Resource,
+				Type:        types.ResourceTypeHTTP,
+				X402Version: 1,
+				Accepts:     []types.PaymentRequirements{requirements},
+			})
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestFindResources(t *testing.T) {
+	var resources []types.PaymentRequirements
+	for i, network := range []string{"base", "base-sepolia", "base", "base", "avalanche"} {
+		resources = append(resources, types.PaymentRequirements{
+			Resource:          fmt.Sprintf("https://api.example.com/%d", i),
+			Network:           network,
+			MaxAmountRequired: strconv.Itoa((i + 1) * 1000),
+		})
+	}
+	server := newDiscoveryServer(t, resources)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	page, err := client.ListResources(context.Background(), types.ListDiscoveryResourcesRequest{Type: "http", Offset: 4})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(page.Items) != 1 || page.Pagination.Total != 5 {
+		t.Errorf("Expected the last page, got: %+v", page)
+	}
+
+	found, err := client.FindResources(context.Background(), types.ListDiscoveryResourcesRequest{Type: "http"},
+		facilitatorclient.AcceptsNetwork("base"),
+		facilitatorclient.MaxAmountRequired(big.NewInt(3000)),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(found) != 2 || found[0].Resource != "https://api.example.com/0" || found[1].Resource != "https://api.example.com/2" {
+		t.Errorf("Expected resources 0 and 2, got: %+v", found)
+	}
+}
+
+func TestFindResources_Error(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	_, err := client.FindResources(context.Background(), types.ListDiscoveryResourcesRequest{})
+	if !errors.Is(err, facilitatorclient.ErrRejected) {
+		t.Errorf("Expected rejection, got: %v", err)
+	}
+	if err.Error() != "failed to list resources: 404 Not Found" {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
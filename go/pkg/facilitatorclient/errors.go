@@ -0,0 +1,79 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+var (
+	// ErrTimeout is matched by the errors of facilitator calls exceeding their deadline
+	ErrTimeout = errors.New("facilitator timeout")
+	// ErrCanceled is matched by the errors of facilitator calls canceled by their context
+	ErrCanceled = errors.New("facilitator call canceled")
+	// ErrNetwork is matched by the errors of facilitator calls that failed to reach the facilitator
+	ErrNetwork = errors.New("facilitator unreachable")
+	// ErrRejected is matched by the errors of facilitator calls answered with a non 200 status
+	ErrRejected = errors.New("facilitator rejected the request")
+)
+
+// RequestError is the error of a facilitator call that did not get a response. It matches with
+// errors.Is its kind, ErrTimeout, ErrCanceled, ErrNetwork, ErrRejected or ErrCircuitOpen, and its
+// cause.
+type RequestError struct {
+	// Op is the facilitator operation, "verify", "settle", "supported" or "list"
+	Op string
+	// Kind is ErrTimeout, ErrCanceled, ErrNetwork, ErrRejected or ErrCircuitOpen
+	Kind error
+	// StatusCode and Status are the response status of rejected calls
+	StatusCode int
+	Status     string
+	// Err is the cause of timeouts, cancellations and network failures
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	var action string
+	switch e.Op {
+	case "verify", "settle":
+		action = fmt.Sprintf("%s payment", e.Op)
+	case "list":
+		action = "list resources"
+	default:
+		action = fmt.Sprintf("fetch %s", e.Op)
+	}
+
+	switch e.Kind {
+	case ErrRejected:
+		return fmt.Sprintf("failed to %s: %s", action, e.Status)
+	case ErrCircuitOpen:
+		return fmt.Sprintf("failed to %s: %s", action, e.Kind)
+	}
+	return fmt.Sprintf("failed to send %s request: %v", e.Op, e.Err)
+}
+
+// Is reports whether target is the kind of the error
+func (e *RequestError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// requestError classifies the error of sending a request
+func requestError(op string, err error) *RequestError {
+	kind := ErrNetwork
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = ErrTimeout
+	case errors.Is(err, context.Canceled):
+		kind = ErrCanceled
+	case errors.As(err, &netErr) && netErr.Timeout():
+		kind = ErrTimeout
+	}
+
+	return &RequestError{Op: op, Kind: kind, Err: err}
+}
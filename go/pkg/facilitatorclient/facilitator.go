@@ -0,0 +1,84 @@
+package facilitatorclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// Facilitator verifies and settles payments. It is implemented by FacilitatorClient for remote
+// facilitators and by facilitator.Facilitator for local verification, and decorated by
+// NewLoggingFacilitator, NewMetricsFacilitator, NewCachingFacilitator and NewFailoverFacilitator.
+type Facilitator interface {
+	// VerifyContext verifies the payment of the payload against the requirements
+	VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error)
+	// SettleContext settles the payment of the payload
+	SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error)
+	// SupportedContext returns the scheme and network pairs the facilitator can verify and settle
+	SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error)
+}
+
+var _ Facilitator = (*FacilitatorClient)(nil)
+
+// Supported fetches the scheme and network pairs the facilitator can verify and settle
+func (c *FacilitatorClient) Supported() (*types.SupportedPaymentKindsResponse, error) {
+	return c.SupportedContext(context.Background())
+}
+
+// SupportedContext fetches the scheme and network pairs the facilitator can verify and settle,
+// canceled with ctx. The response is cached, see WithSupportedCacheTTL.
+// Calls that get no response return a *RequestError.
+func (c *FacilitatorClient) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	if c.supported == nil {
+		return c.fetchSupported(ctx)
+	}
+	return c.supported.get(ctx, c.fetchSupported)
+}
+
+// fetchSupported fetches the supported payment kinds from the facilitator
+func (c *FacilitatorClient) fetchSupported(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	var supportedResp types.SupportedPaymentKindsResponse
+	if err := c.get(ctx, "supported", "supported", c.VerifyTimeout, &supportedResp); err != nil {
+		return nil, err
+	}
+
+	return &supportedResp, nil
+}
+
+// supportedCache caches supported payment kinds for a ttl. Errors are not cached.
+type supportedCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	response  *types.SupportedPaymentKindsResponse
+	expiresAt time.Time
+}
+
+// newSupportedCache returns a cache of supported payment kinds, or nil when ttl is not positive
+func newSupportedCache(ttl time.Duration) *supportedCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &supportedCache{ttl: ttl}
+}
+
+// get returns the cached response, or the response of fetch once expired
+func (c *supportedCache) get(ctx context.Context, fetch func(ctx context.Context) (*types.SupportedPaymentKindsResponse, error)) (*types.SupportedPaymentKindsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.response != nil && time.Now().Before(c.expiresAt) {
+		return c.response, nil
+	}
+
+	response, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.response = response
+	c.expiresAt = time.Now().Add(c.ttl)
+
+	return response, nil
+}
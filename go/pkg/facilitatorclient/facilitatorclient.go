@@ -2,9 +2,12 @@ package facilitatorclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/coinbase/x402/go/pkg/types"
 )
@@ -12,80 +15,137 @@ import (
 // DefaultFacilitatorURL is the default URL for the x402 facilitator service
 const DefaultFacilitatorURL = "https://x402.org/facilitator"
 
+// Default deadlines of facilitator calls. Settlement waits for the transaction to be mined.
+const (
+	DefaultVerifyTimeout = 10 * time.Second
+	DefaultSettleTimeout = 60 * time.Second
+)
+
+// DefaultSupportedCacheTTL is how long the supported payment kinds of a facilitator are cached by default
+const DefaultSupportedCacheTTL = 5 * time.Minute
+
 // FacilitatorClient represents a facilitator client for verifying and settling payments
 type FacilitatorClient struct {
 	URL               string
 	HTTPClient        *http.Client
 	CreateAuthHeaders func() (map[string]map[string]string, error)
+	// VerifyTimeout and SettleTimeout bound each attempt of a call, on top of the deadline of its context.
+	// Zero means no timeout.
+	VerifyTimeout time.Duration
+	SettleTimeout time.Duration
+	// RetryPolicy retries the calls that failed transiently, calls are not retried when nil
+	RetryPolicy *RetryPolicy
+	// CircuitBreaker fails calls fast while the facilitator is down, it may be nil
+	CircuitBreaker *CircuitBreaker
+
+	// supported caches the supported payment kinds, they are fetched on each call when nil
+	supported *supportedCache
+}
+
+// ClientOption configures a FacilitatorClient
+type ClientOption func(*FacilitatorClient)
+
+// WithHTTPClient sets the HTTP client sending the requests to the facilitator
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *FacilitatorClient) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithTimeout sets the deadline of both verify and settle calls
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *FacilitatorClient) {
+		c.VerifyTimeout = timeout
+		c.SettleTimeout = timeout
+	}
+}
+
+// WithVerifyTimeout sets the deadline of verify calls
+func WithVerifyTimeout(timeout time.Duration) ClientOption {
+	return func(c *FacilitatorClient) {
+		c.VerifyTimeout = timeout
+	}
+}
+
+// WithSettleTimeout sets the deadline of settle calls
+func WithSettleTimeout(timeout time.Duration) ClientOption {
+	return func(c *FacilitatorClient) {
+		c.SettleTimeout = timeout
+	}
+}
+
+// WithSupportedCacheTTL sets how long the supported payment kinds are cached, a zero ttl disabling
+// the cache
+func WithSupportedCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *FacilitatorClient) {
+		c.supported = newSupportedCache(ttl)
+	}
 }
 
 // NewFacilitatorClient creates a new facilitator client
-func NewFacilitatorClient(config *types.FacilitatorConfig) *FacilitatorClient {
+func NewFacilitatorClient(config *types.FacilitatorConfig, opts ...ClientOption) *FacilitatorClient {
 	if config == nil {
 		config = &types.FacilitatorConfig{
 			URL: DefaultFacilitatorURL,
 		}
 	}
 
-	return &FacilitatorClient{
+	client := &FacilitatorClient{
 		URL:               config.URL,
 		HTTPClient:        http.DefaultClient,
 		CreateAuthHeaders: config.CreateAuthHeaders,
+		VerifyTimeout:     DefaultVerifyTimeout,
+		SettleTimeout:     DefaultSettleTimeout,
+		supported:         newSupportedCache(DefaultSupportedCacheTTL),
+	}
+	for _, opt := range opts {
+		opt(client)
 	}
+
+	return client
 }
 
 // Verify sends a payment verification request to the facilitator
 func (c *FacilitatorClient) Verify(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
-	reqBody := map[string]any{
-		"x402Version":         1,
-		"paymentPayload":      payload,
-		"paymentRequirements": requirements,
-	}
+	return c.VerifyContext(context.Background(), payload, requirements)
+}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+// VerifyContext sends a payment verification request to the facilitator, canceled with ctx.
+// Calls that get no response return a *RequestError.
+func (c *FacilitatorClient) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	var verifyResp types.VerifyResponse
+	if err := c.post(ctx, "verify", c.VerifyTimeout, payload, requirements, &verifyResp); err != nil {
+		return nil, err
 	}
-
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/verify", c.URL), bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if err := verifyResp.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid verify response: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	// Add auth headers if available
-	if c.CreateAuthHeaders != nil {
-		headers, err := c.CreateAuthHeaders()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create auth headers: %w", err)
-		}
-		if verifyHeaders, ok := headers["verify"]; ok {
-			for key, value := range verifyHeaders {
-				req.Header.Set(key, value)
-			}
-		}
-	}
+	return &verifyResp, nil
+}
 
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send verify request: %w", err)
-	}
-	defer resp.Body.Close()
+// Settle sends a payment settlement request to the facilitator
+func (c *FacilitatorClient) Settle(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	return c.SettleContext(context.Background(), payload, requirements)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to verify payment: %s", resp.Status)
+// SettleContext sends a payment settlement request to the facilitator, canceled with ctx.
+// Calls that get no response return a *RequestError.
+func (c *FacilitatorClient) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	var settleResp types.SettleResponse
+	if err := c.post(ctx, "settle", c.SettleTimeout, payload, requirements, &settleResp); err != nil {
+		return nil, err
 	}
-
-	var verifyResp types.VerifyResponse
-	if err := json.NewDecoder(resp.Body).Decode(&verifyResp); err != nil {
-		return nil, fmt.Errorf("failed to decode verify response: %w", err)
+	if err := settleResp.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settle response: %w", err)
 	}
 
-	return &verifyResp, nil
+	return &settleResp, nil
 }
 
-// Settle sends a payment settlement request to the facilitator
-func (c *FacilitatorClient) Settle(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+// post sends the payment to the op endpoint of the facilitator and decodes its response into out,
+// retrying according to the retry policy
+func (c *FacilitatorClient) post(ctx context.Context, op string, timeout time.Duration, payload *types.PaymentPayload, requirements *types.PaymentRequirements, out any) error {
 	reqBody := map[string]any{
 		"x402Version":         1,
 		"paymentPayload":      payload,
@@ -94,42 +154,98 @@ func (c *FacilitatorClient) Settle(payload *types.PaymentPayload, requirements *
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/settle", c.URL), bytes.NewBuffer(jsonBody))
+	return c.do(ctx, op, func(ctx context.Context) error {
+		return c.send(ctx, http.MethodPost, op, op, timeout, jsonBody, out)
+	})
+}
+
+// get fetches path, relative to the facilitator URL, for the op call and decodes its response
+// into out, retrying according to the retry policy
+func (c *FacilitatorClient) get(ctx context.Context, op, path string, timeout time.Duration, out any) error {
+	return c.do(ctx, op, func(ctx context.Context) error {
+		return c.send(ctx, http.MethodGet, op, path, timeout, nil, out)
+	})
+}
+
+// do makes the attempts of the op call through the circuit breaker, as allowed by the retry policy
+func (c *FacilitatorClient) do(ctx context.Context, op string, attempt func(ctx context.Context) error) error {
+	for attempts := 1; ; attempts++ {
+		if c.CircuitBreaker != nil {
+			if err := c.CircuitBreaker.Allow(); err != nil {
+				return &RequestError{Op: op, Kind: ErrCircuitOpen}
+			}
+		}
+
+		err := attempt(ctx)
+		if c.CircuitBreaker != nil {
+			c.CircuitBreaker.Record(err)
+		}
+		if err == nil || c.RetryPolicy == nil || attempts >= c.RetryPolicy.MaxAttempts || !retryable(op, err) {
+			return err
+		}
+
+		if c.RetryPolicy.wait(ctx, attempts) != nil {
+			return err
+		}
+	}
+}
+
+// send makes a single attempt of the op call to path, with the JSON body if any
+func (c *FacilitatorClient) send(ctx context.Context, method, op, path string, timeout time.Duration, jsonBody []byte, out any) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if jsonBody != nil {
+		body = bytes.NewReader(jsonBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s", c.URL, path), body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if jsonBody != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
-	req.Header.Set("Content-Type", "application/json")
 
 	// Add auth headers if available
 	if c.CreateAuthHeaders != nil {
 		headers, err := c.CreateAuthHeaders()
 		if err != nil {
-			return nil, fmt.Errorf("failed to create auth headers: %w", err)
+			return fmt.Errorf("failed to create auth headers: %w", err)
 		}
-		if settleHeaders, ok := headers["settle"]; ok {
-			for key, value := range settleHeaders {
+		if opHeaders, ok := headers[op]; ok {
+			for key, value := range opHeaders {
 				req.Header.Set(key, value)
 			}
 		}
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send settle request: %w", err)
+		return requestError(op, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to settle payment: %s", resp.Status)
+		return &RequestError{Op: op, Kind: ErrRejected, StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
-	var settleResp types.SettleResponse
-	if err := json.NewDecoder(resp.Body).Decode(&settleResp); err != nil {
-		return nil, fmt.Errorf("failed to decode settle response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if ctx.Err() != nil {
+			return requestError(op, ctx.Err())
+		}
+		return fmt.Errorf("failed to decode %s response: %w", op, err)
 	}
 
-	return &settleResp, nil
+	return nil
 }
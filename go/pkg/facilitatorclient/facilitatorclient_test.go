@@ -1,10 +1,13 @@
 package facilitatorclient_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/coinbase/x402/go/pkg/facilitatorclient"
 	"github.com/coinbase/x402/go/pkg/types"
@@ -281,3 +284,140 @@ func TestSettleWithAuthHeaders(t *testing.T) {
 		t.Errorf("Expected auth header '%s', got: '%s'", expectedAuthHeader, capturedAuthHeader)
 	}
 }
+
+func TestInvalidResponses(t *testing.T) {
+	invalidPayer := "0xvalidPayer"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: &invalidPayer})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Network: "base-sepolia"})
+		}
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	paymentPayload := &types.PaymentPayload{X402Version: 1, Scheme: "exact", Network: "base-sepolia"}
+	paymentRequirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	_, err := client.Verify(paymentPayload, paymentRequirements)
+	expectedErr := `invalid verify response: invalid payer: "0xvalidPayer" is not an address`
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error '%s', got: %v", expectedErr, err)
+	}
+
+	_, err = client.Settle(paymentPayload, paymentRequirements)
+	expectedErr = "invalid settle response: invalid transaction: is required"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error '%s', got: %v", expectedErr, err)
+	}
+}
+
+func TestUnknownReason(t *testing.T) {
+	unknownReason := "nonce_already_used"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(types.VerifyResponse{InvalidReason: &unknownReason})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.Verify(
+		&types.PaymentPayload{X402Version: 1, Scheme: "exact", Network: "base-sepolia"},
+		&types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"},
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Reason() != types.ErrorReason(unknownReason) {
+		t.Errorf("Expected reason '%s', got: %s", unknownReason, resp.Reason())
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	release := make(chan struct{})
+	hangingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer hangingServer.Close()
+	defer close(release)
+
+	rejectingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer rejectingServer.Close()
+
+	closedServer := httptest.NewServer(http.NotFoundHandler())
+	closedServer.Close()
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name         string
+		url          string
+		opts         []facilitatorclient.ClientOption
+		ctx          context.Context
+		expectedKind error
+	}{
+		{
+			name:         "client timeout",
+			url:          hangingServer.URL,
+			opts:         []facilitatorclient.ClientOption{facilitatorclient.WithTimeout(50 * time.Millisecond)},
+			ctx:          context.Background(),
+			expectedKind: facilitatorclient.ErrTimeout,
+		},
+		{
+			name:         "canceled context",
+			url:          hangingServer.URL,
+			ctx:          canceledCtx,
+			expectedKind: facilitatorclient.ErrCanceled,
+		},
+		{
+			name:         "unreachable facilitator",
+			url:          closedServer.URL,
+			ctx:          context.Background(),
+			expectedKind: facilitatorclient.ErrNetwork,
+		},
+		{
+			name:         "rejected request",
+			url:          rejectingServer.URL,
+			ctx:          context.Background(),
+			expectedKind: facilitatorclient.ErrRejected,
+		},
+	}
+
+	paymentPayload := &types.PaymentPayload{X402Version: 1, Scheme: "exact", Network: "base-sepolia"}
+	paymentRequirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: tc.url}, tc.opts...)
+
+			_, verifyErr := client.VerifyContext(tc.ctx, paymentPayload, paymentRequirements)
+			_, settleErr := client.SettleContext(tc.ctx, paymentPayload, paymentRequirements)
+
+			for op, err := range map[string]error{"verify": verifyErr, "settle": settleErr} {
+				if !errors.Is(err, tc.expectedKind) {
+					t.Errorf("Expected %s error matching '%v', got: %v", op, tc.expectedKind, err)
+				}
+
+				var requestErr *facilitatorclient.RequestError
+				if !errors.As(err, &requestErr) {
+					t.Fatalf("Expected *RequestError, got: %T", err)
+				}
+				if requestErr.Op != op {
+					t.Errorf("Expected op '%s', got: %s", op, requestErr.Op)
+				}
+				if tc.expectedKind == facilitatorclient.ErrRejected && requestErr.StatusCode != http.StatusServiceUnavailable {
+					t.Errorf("Expected status code %d, got: %d", http.StatusServiceUnavailable, requestErr.StatusCode)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package facilitatorclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// DefaultHealthCheckInterval is the default interval between the health checks of a Pool
+const DefaultHealthCheckInterval = 30 * time.Second
+
+// pinTTL is how long a Pool remembers the facilitator that verified a payment
+const pinTTL = time.Hour
+
+// PoolMember is a facilitator of a Pool
+type PoolMember struct {
+	// Config is the config of the facilitator's client, whose URL identifies the member, see
+	// WithPoolMember
+	Config *types.FacilitatorConfig
+	// Facilitator is used instead of a client of Config when set, for example a local facilitator
+	Facilitator Facilitator
+	// Priority orders the members, lower priorities being tried first
+	Priority int
+	// Weight is the share of the calls of the member among the members of the same priority,
+	// defaults to 1
+	Weight int
+}
+
+// PoolConfig is the config of a Pool of facilitators
+type PoolConfig struct {
+	Members []PoolMember
+	// HealthCheckInterval is the interval between the checks of the members' /supported endpoint,
+	// defaults to DefaultHealthCheckInterval. A negative interval disables the checks.
+	HealthCheckInterval time.Duration
+}
+
+// Pool verifies payments with the healthiest facilitator of several, failing over to the next one
+// when a facilitator can't answer. A payment is settled by the facilitator that verified it, and
+// never submitted to another one. The pool checks the health of its members in the background
+// until it is closed, see Close.
+type Pool struct {
+	members  []*poolMember
+	interval time.Duration
+
+	// ctx is canceled by Close, stopping the health checks tracked by checks
+	ctx    context.Context
+	cancel context.CancelFunc
+	checks sync.WaitGroup
+
+	mu        sync.Mutex
+	pins      map[string]pin
+	lastSweep time.Time
+	lastCheck time.Time
+	checking  bool
+}
+
+// poolMember is a member of a Pool with its health
+type poolMember struct {
+	PoolMember
+	healthy bool
+}
+
+// pin is the facilitator that verified a payment
+type pin struct {
+	member    *poolMember
+	expiresAt time.Time
+}
+
+var _ Facilitator = (*Pool)(nil)
+
+// poolMemberKey is the context key of the member URL of WithPoolMember
+type poolMemberKey struct{}
+
+// WithPoolMember returns a context tying the Pool calls made with it to the member whose config
+// URL is *url. A payment verified with the context records in url the member that verified it, and
+// a payment settled with the context is settled by the member of url, when set, rather than by the
+// member pinned in memory. Storing url with the payment thus settles it with the member that
+// verified it once the pin has expired or after a restart, as the settlement queue does.
+func WithPoolMember(ctx context.Context, url *string) context.Context {
+	return context.WithValue(ctx, poolMemberKey{}, url)
+}
+
+// PoolMemberURL returns the member URL of a context created by WithPoolMember, or an empty string
+func PoolMemberURL(ctx context.Context) string {
+	if url, ok := ctx.Value(poolMemberKey{}).(*string); ok {
+		return *url
+	}
+	return ""
+}
+
+// NewPool creates a pool of the facilitators of config. The clients of the members' configs are
+// created with opts, without caching their supported payment kinds so that the health checks reach
+// the facilitators.
+func NewPool(config PoolConfig, opts ...ClientOption) *Pool {
+	opts = append(slices.Clone(opts), WithSupportedCacheTTL(0))
+
+	pool := &Pool{
+		interval: config.HealthCheckInterval,
+		pins:     map[string]pin{},
+	}
+	pool.ctx, pool.cancel = context.WithCancel(context.Background())
+	if pool.interval == 0 {
+		pool.interval = DefaultHealthCheckInterval
+	}
+
+	for _, member := range config.Members {
+		if member.Facilitator == nil {
+			member.Facilitator = NewFacilitatorClient(member.Config, opts...)
+		}
+		if member.Weight <= 0 {
+			member.Weight = 1
+		}
+		pool.members = append(pool.members, &poolMember{PoolMember: member, healthy: true})
+	}
+
+	// Members are stored by priority, so that candidates only have to shuffle them by weight
+	slices.SortStableFunc(pool.members, func(a, b *poolMember) int {
+		return a.Priority - b.Priority
+	})
+
+	return pool
+}
+
+// VerifyContext verifies the payment with the members in turn until one answers, and pins valid
+// payments to the member that verified them, which is also recorded in the member URL of ctx, see
+// WithPoolMember
+func (p *Pool) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	var verifier *poolMember
+	response, err := failover(ctx, p.candidates(), canFailover, func(member *poolMember) (*types.VerifyResponse, error) {
+		response, err := member.Facilitator.VerifyContext(ctx, payload, requirements)
+		p.observe(member, err)
+		verifier = member
+		return response, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.IsValid {
+		p.pin(payload, verifier)
+		if url, ok := ctx.Value(poolMemberKey{}).(*string); ok && verifier.Config != nil {
+			*url = verifier.Config.URL
+		}
+	}
+	return response, nil
+}
+
+// SettleContext settles the payment with the member of the member URL of ctx, see WithPoolMember,
+// or else with the member pinned when verifying it. Payments not verified by the pool are settled
+// by the first member that accepts them.
+func (p *Pool) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	key, member := p.pinned(payload)
+	if url := PoolMemberURL(ctx); url != "" {
+		member = p.member(url)
+		if member == nil {
+			return nil, fmt.Errorf("facilitator %s is not a member of the pool", url)
+		}
+	}
+	if member != nil {
+		response, err := member.Facilitator.SettleContext(ctx, payload, requirements)
+		p.observe(member, err)
+		if err == nil {
+			p.unpin(key)
+		}
+		return response, err
+	}
+
+	return failover(ctx, p.candidates(), canFailoverSettle, func(member *poolMember) (*types.SettleResponse, error) {
+		response, err := member.Facilitator.SettleContext(ctx, payload, requirements)
+		p.observe(member, err)
+		return response, err
+	})
+}
+
+// SupportedContext returns the supported payment kinds of the first member that answers
+func (p *Pool) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	return failover(ctx, p.candidates(), canFailover, func(member *poolMember) (*types.SupportedPaymentKindsResponse, error) {
+		response, err := member.Facilitator.SupportedContext(ctx)
+		p.observe(member, err)
+		return response, err
+	})
+}
+
+// CheckHealth checks the /supported endpoint of each member, marking the members that fail to
+// answer as unhealthy until they answer again
+func (p *Pool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, member := range p.members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := member.Facilitator.SupportedContext(ctx)
+			p.observe(member, err)
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	p.lastCheck = time.Now()
+	p.checking = false
+	p.mu.Unlock()
+}
+
+// Close stops the background health checks, waiting for the one in progress, if any. The pool can
+// still be used, without health checks.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.cancel()
+	p.mu.Unlock()
+
+	p.checks.Wait()
+	return nil
+}
+
+// candidates returns the members in the order they must be called: healthy members by priority and
+// in a weighted random order within a priority, then the unhealthy members as a last resort.
+// It starts a health check in the background when the last one is older than the interval, unless
+// the pool is closed.
+func (p *Pool) candidates() []*poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.interval > 0 && !p.checking && p.ctx.Err() == nil && time.Since(p.lastCheck) >= p.interval {
+		p.checking = true
+		p.checks.Add(1)
+		go func() {
+			defer p.checks.Done()
+			p.CheckHealth(p.ctx)
+		}()
+	}
+
+	var healthy, unhealthy []*poolMember
+	for start := 0; start < len(p.members); {
+		end := start
+		for end < len(p.members) && p.members[end].Priority == p.members[start].Priority {
+			end++
+		}
+		for _, member := range weightedShuffle(p.members[start:end]) {
+			if member.healthy {
+				healthy = append(healthy, member)
+			} else {
+				unhealthy = append(unhealthy, member)
+			}
+		}
+		start = end
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+// member returns the member whose config URL is url, if any
+func (p *Pool) member(url string) *poolMember {
+	for _, member := range p.members {
+		if member.Config != nil && member.Config.URL == url {
+			return member
+		}
+	}
+	return nil
+}
+
+// observe updates the health of a member from the outcome of a call. Payment errors and canceled
+// calls are answers or neutral, only the failures of the facilitator itself make it unhealthy.
+func (p *Pool) observe(member *poolMember, err error) {
+	if errors.Is(err, ErrCanceled) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	member.healthy = err == nil || !isFacilitatorFailure(err)
+}
+
+// pin remembers that member verified the payment of payload. Expired pins are swept at most once
+// per pinTTL, and ignored until then.
+func (p *Pool) pin(payload *types.PaymentPayload, member *poolMember) {
+	key, err := paymentKey(payload)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.lastSweep) >= pinTTL {
+		for k, existing := range p.pins {
+			if now.After(existing.expiresAt) {
+				delete(p.pins, k)
+			}
+		}
+		p.lastSweep = now
+	}
+	p.pins[key] = pin{member: member, expiresAt: now.Add(pinTTL)}
+}
+
+// pinned returns the key of the payment of payload and the member that verified it, if any
+func (p *Pool) pinned(payload *types.PaymentPayload) (string, *poolMember) {
+	key, err := paymentKey(payload)
+	if err != nil {
+		return "", nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pin, ok := p.pins[key]
+	if !ok {
+		return key, nil
+	}
+	if time.Now().After(pin.expiresAt) {
+		delete(p.pins, key)
+		return key, nil
+	}
+	return key, pin.member
+}
+
+// unpin forgets the facilitator of a settled payment
+func (p *Pool) unpin(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pins, key)
+}
+
+// paymentKey identifies the payment of a payload by the hash of its JSON encoding
+func paymentKey(payload *types.PaymentPayload) (string, error) {
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payloadJson)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// weightedShuffle returns the members in a random order, members of higher weights being more
+// likely to come first
+func weightedShuffle(members []*poolMember) []*poolMember {
+	remaining := slices.Clone(members)
+	shuffled := make([]*poolMember, 0, len(members))
+	for len(remaining) > 0 {
+		total := 0
+		for _, member := range remaining {
+			total += member.Weight
+		}
+
+		n := rand.N(total)
+		for i, member := range remaining {
+			if n < member.Weight {
+				shuffled = append(shuffled, member)
+				remaining = slices.Delete(remaining, i, i+1)
+				break
+			}
+			n -= member.Weight
+		}
+	}
+	return shuffled
+}
@@ -0,0 +1,225 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// newHealthyFake returns a fake facilitator verifying and settling every payment
+func newHealthyFake() *fakeFacilitator {
+	return &fakeFacilitator{
+		verifyResponse:    &types.VerifyResponse{IsValid: true},
+		settleResponse:    &types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: "base-sepolia"},
+		supportedResponse: &types.SupportedPaymentKindsResponse{},
+	}
+}
+
+var errUnreachable = &facilitatorclient.RequestError{Op: "verify", Kind: facilitatorclient.ErrNetwork, Err: errors.New("connection refused")}
+
+func TestPool_Priority(t *testing.T) {
+	primary, secondary := newHealthyFake(), newHealthyFake()
+	pool := facilitatorclient.NewPool(facilitatorclient.PoolConfig{
+		Members: []facilitatorclient.PoolMember{
+			{Facilitator: secondary, Priority: 1},
+			{Facilitator: primary, Priority: 0},
+		},
+		HealthCheckInterval: -1,
+	})
+
+	for range 5 {
+		if _, err := pool.VerifyContext(context.Background(), testPayload, testRequirements); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if primary.verifyCalls != 5 || secondary.verifyCalls != 0 {
+		t.Errorf("Expected all calls on the primary, got: %d primary, %d secondary", primary.verifyCalls, secondary.verifyCalls)
+	}
+}
+
+func TestPool_FailoverPinsSettlement(t *testing.T) {
+	primary, secondary := newHealthyFake(), newHealthyFake()
+	primary.verifyErr = errUnreachable
+	pool := facilitatorclient.NewPool(facilitatorclient.PoolConfig{
+		Members: []facilitatorclient.PoolMember{
+			{Facilitator: primary, Priority: 0},
+			{Facilitator: secondary, Priority: 1},
+		},
+		HealthCheckInterval: -1,
+	})
+
+	if _, err := pool.VerifyContext(context.Background(), testPayload, testRequirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := pool.SettleContext(context.Background(), testPayload, testRequirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if secondary.verifyCalls != 1 || secondary.settleCalls != 1 {
+		t.Errorf("Expected the secondary to verify and settle, got: %d verify, %d settle", secondary.verifyCalls, secondary.settleCalls)
+	}
+	if primary.settleCalls != 0 {
+		t.Errorf("Expected no settlement on the primary, got: %d", primary.settleCalls)
+	}
+
+	// The unreachable primary is now tried last
+	primary.verifyErr = nil
+	pool.VerifyContext(context.Background(), testPayload, testRequirements)
+	if secondary.verifyCalls != 2 {
+		t.Errorf("Expected the unhealthy primary to be skipped, got: %d secondary calls", secondary.verifyCalls)
+	}
+}
+
+func TestPool_PinnedSettlementDoesNotFailOver(t *testing.T) {
+	primary, secondary := newHealthyFake(), newHealthyFake()
+	primary.settleErr = &facilitatorclient.RequestError{Op: "settle", Kind: facilitatorclient.ErrNetwork, Err: errors.New("connection refused")}
+	pool := facilitatorclient.NewPool(facilitatorclient.PoolConfig{
+		Members: []facilitatorclient.PoolMember{
+			{Facilitator: primary, Priority: 0},
+			{Facilitator: secondary, Priority: 1},
+		},
+		HealthCheckInterval: -1,
+	})
+
+	if _, err := pool.VerifyContext(context.Background(), testPayload, testRequirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := pool.SettleContext(context.Background(), testPayload, testRequirements); !errors.Is(err, facilitatorclient.ErrNetwork) {
+		t.Fatalf("Expected the primary's error, got: %v", err)
+	}
+	if secondary.settleCalls != 0 {
+		t.Errorf("Expected no settlement on the secondary, got: %d", secondary.settleCalls)
+	}
+}
+
+func TestPool_InvalidPaymentNotPinned(t *testing.T) {
+	primary, secondary := newHealthyFake(), newHealthyFake()
+	primary.verifyResponse = &types.VerifyResponse{InvalidReason: types.ReasonPtr(types.ErrorReasonInsufficientFunds)}
+	primary.settleErr = &facilitatorclient.RequestError{Op: "settle", Kind: facilitatorclient.ErrCircuitOpen}
+	pool := facilitatorclient.NewPool(facilitatorclient.PoolConfig{
+		Members: []facilitatorclient.PoolMember{
+			{Facilitator: primary, Priority: 0},
+			{Facilitator: secondary, Priority: 1},
+		},
+		HealthCheckInterval: -1,
+	})
+
+	resp, err := pool.VerifyContext(context.Background(), testPayload, testRequirements)
+	if err != nil || resp.IsValid {
+		t.Fatalf("Expected the invalid response, got: %v, %v", resp, err)
+	}
+
+	// Unpinned payments fail over while the primary's circuit is open
+	if _, err := pool.SettleContext(context.Background(), testPayload, testRequirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if secondary.settleCalls != 1 {
+		t.Errorf("Expected the unpinned payment to fail over, got: %d secondary settle calls", secondary.settleCalls)
+	}
+}
+
+func TestPool_CheckHealth(t *testing.T) {
+	primary, secondary := newHealthyFake(), newHealthyFake()
+	primary.supportedErr = errUnreachable
+	pool := facilitatorclient.NewPool(facilitatorclient.PoolConfig{
+		Members: []facilitatorclient.PoolMember{
+			{Facilitator: primary, Priority: 0},
+			{Facilitator: secondary, Priority: 1},
+		},
+		HealthCheckInterval: -1,
+	})
+
+	pool.CheckHealth(context.Background())
+	pool.VerifyContext(context.Background(), testPayload, testRequirements)
+	if primary.verifyCalls != 0 || secondary.verifyCalls != 1 {
+		t.Errorf("Expected the unhealthy primary to be skipped, got: %d primary, %d secondary", primary.verifyCalls, secondary.verifyCalls)
+	}
+
+	// The primary is used again once it answers
+	primary.supportedErr = nil
+	pool.CheckHealth(context.Background())
+	pool.VerifyContext(context.Background(), testPayload, testRequirements)
+	if primary.verifyCalls != 1 {
+		t.Errorf("Expected the recovered primary to be used, got: %d calls", primary.verifyCalls)
+	}
+}
+
+func TestPool_Weights(t *testing.T) {
+	heavy, light := newHealthyFake(), newHealthyFake()
+	pool := facilitatorclient.NewPool(facilitatorclient.PoolConfig{
+		Members: []facilitatorclient.PoolMember{
+			{Facilitator: heavy, Weight: 3},
+			{Facilitator: light, Weight: 1},
+		},
+		HealthCheckInterval: -1,
+	})
+
+	for range 1000 {
+		pool.SupportedContext(context.Background())
+	}
+	if heavy.supportedCalls < 650 || heavy.supportedCalls > 850 {
+		t.Errorf("Expected about 750 calls on the heavy member, got: %d", heavy.supportedCalls)
+	}
+}
+
+func TestPool_SettlesWithRecordedMember(t *testing.T) {
+	primary, secondary := newHealthyFake(), newHealthyFake()
+	primary.verifyErr = errUnreachable
+	config := facilitatorclient.PoolConfig{
+		Members: []facilitatorclient.PoolMember{
+			{Config: &types.FacilitatorConfig{URL: "https://primary.example.com"}, Facilitator: primary, Priority: 0},
+			{Config: &types.FacilitatorConfig{URL: "https://secondary.example.com"}, Facilitator: secondary, Priority: 1},
+		},
+		HealthCheckInterval: -1,
+	}
+
+	var member string
+	ctx := facilitatorclient.WithPoolMember(context.Background(), &member)
+	if _, err := facilitatorclient.NewPool(config).VerifyContext(ctx, testPayload, testRequirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if member != "https://secondary.example.com" {
+		t.Fatalf("Expected the secondary to be recorded, got: %q", member)
+	}
+
+	// A new pool, as after a restart, has no pin but settles with the recorded member
+	primary.verifyErr = nil
+	if _, err := facilitatorclient.NewPool(config).SettleContext(ctx, testPayload, testRequirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if primary.settleCalls != 0 || secondary.settleCalls != 1 {
+		t.Errorf("Expected the settlement on the secondary, got: %d primary, %d secondary", primary.settleCalls, secondary.settleCalls)
+	}
+
+	// A member that left the pool is not replaced by another one
+	member = "https://removed.example.com"
+	if _, err := facilitatorclient.NewPool(config).SettleContext(ctx, testPayload, testRequirements); err == nil {
+		t.Error("Expected an error for an unknown member")
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	member := newHealthyFake()
+	pool := facilitatorclient.NewPool(facilitatorclient.PoolConfig{
+		Members:             []facilitatorclient.PoolMember{{Facilitator: member}},
+		HealthCheckInterval: time.Nanosecond,
+	})
+
+	// The first call starts a health check, which Close waits for
+	pool.VerifyContext(context.Background(), testPayload, testRequirements)
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if member.supportedCalls != 1 {
+		t.Fatalf("Expected one health check, got: %d", member.supportedCalls)
+	}
+
+	pool.VerifyContext(context.Background(), testPayload, testRequirements)
+	if member.supportedCalls != 1 {
+		t.Errorf("Expected no health check once closed, got: %d", member.supportedCalls)
+	}
+}
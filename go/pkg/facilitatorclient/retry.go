@@ -0,0 +1,108 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy retries the facilitator calls that failed transiently, waiting an exponential
+// backoff with jitter between attempts. Verify calls are idempotent and retried on timeouts,
+// network failures and 5xx or 429 responses. Settle calls are only retried when the facilitator
+// did not accept the request, which would otherwise risk a double settlement: when the
+// connection could not be established, or on 429 and 503 responses.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first one
+	MaxAttempts int
+	// InitialBackoff is the backoff after the first attempt, doubled after each attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy makes up to 3 attempts, backing off from 100ms
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// WithRetryPolicy sets the retry policy of the client's calls
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *FacilitatorClient) {
+		c.RetryPolicy = &policy
+	}
+}
+
+// Backoff returns the time to wait after the given attempt, starting at 1. It is drawn between
+// half and all of the exponential backoff, so that clients failing together retry apart.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || backoff < p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+	return half + rand.N(backoff-half+1)
+}
+
+// Retryable reports whether the failed call op, "verify" or "settle", can be retried by the policy
+func (p RetryPolicy) Retryable(op string, err error) bool {
+	return retryable(op, err)
+}
+
+// wait waits the backoff after the given attempt, or until ctx is done
+func (p RetryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.Backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryable reports whether the failed call op can be retried
+func retryable(op string, err error) bool {
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) {
+		return false
+	}
+
+	if op == "settle" {
+		switch requestErr.Kind {
+		case ErrNetwork:
+			return isDialError(requestErr.Err)
+		case ErrRejected:
+			return requestErr.StatusCode == http.StatusTooManyRequests || requestErr.StatusCode == http.StatusServiceUnavailable
+		default:
+			return false
+		}
+	}
+
+	switch requestErr.Kind {
+	case ErrTimeout, ErrNetwork:
+		return true
+	case ErrRejected:
+		return requestErr.StatusCode >= 500 || requestErr.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
+// isDialError reports whether err is a failure to connect, in which case no request was sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,234 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// fastRetryPolicy retries without slowing the tests down
+var fastRetryPolicy = facilitatorclient.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+// countingTransport counts the requests sent
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newFlakyServer creates a facilitator answering the given statuses in turn, then 200
+func newFlakyServer(t *testing.T, statuses ...int) *httptest.Server {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(calls.Add(1)) - 1
+		if call < len(statuses) {
+			w.WriteHeader(statuses[call])
+			return
+		}
+
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: "base-sepolia"})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRetryPolicy(t *testing.T) {
+	testCases := []struct {
+		name             string
+		op               string
+		statuses         []int
+		expectedRequests int32
+		expectedKind     error
+	}{
+		{
+			name:             "verify retried on 5xx",
+			op:               "verify",
+			statuses:         []int{http.StatusInternalServerError, http.StatusBadGateway},
+			expectedRequests: 3,
+		},
+		{
+			name:             "verify gives up after max attempts",
+			op:               "verify",
+			statuses:         []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			expectedRequests: 3,
+			expectedKind:     facilitatorclient.ErrRejected,
+		},
+		{
+			name:             "verify not retried on 4xx",
+			op:               "verify",
+			statuses:         []int{http.StatusBadRequest},
+			expectedRequests: 1,
+			expectedKind:     facilitatorclient.ErrRejected,
+		},
+		{
+			name:             "settle not retried on 500",
+			op:               "settle",
+			statuses:         []int{http.StatusInternalServerError},
+			expectedRequests: 1,
+			expectedKind:     facilitatorclient.ErrRejected,
+		},
+		{
+			name:             "settle retried on 503 and 429",
+			op:               "settle",
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			expectedRequests: 3,
+		},
+	}
+
+	paymentPayload := &types.PaymentPayload{X402Version: 1, Scheme: "exact", Network: "base-sepolia"}
+	paymentRequirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newFlakyServer(t, tc.statuses...)
+			transport := &countingTransport{}
+			client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+				facilitatorclient.WithHTTPClient(&http.Client{Transport: transport}),
+				facilitatorclient.WithRetryPolicy(fastRetryPolicy),
+			)
+
+			var err error
+			if tc.op == "verify" {
+				_, err = client.VerifyContext(context.Background(), paymentPayload, paymentRequirements)
+			} else {
+				_, err = client.SettleContext(context.Background(), paymentPayload, paymentRequirements)
+			}
+
+			if tc.expectedKind == nil && err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if tc.expectedKind != nil && !errors.Is(err, tc.expectedKind) {
+				t.Errorf("Expected error matching '%v', got: %v", tc.expectedKind, err)
+			}
+			if requests := transport.requests.Load(); requests != tc.expectedRequests {
+				t.Errorf("Expected %d requests, got: %d", tc.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_SettleRetriedOnConnectionFailure(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	transport := &countingTransport{}
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithHTTPClient(&http.Client{Transport: transport}),
+		facilitatorclient.WithRetryPolicy(fastRetryPolicy),
+	)
+
+	_, err := client.SettleContext(context.Background(), &types.PaymentPayload{}, &types.PaymentRequirements{})
+	if !errors.Is(err, facilitatorclient.ErrNetwork) {
+		t.Errorf("Expected network error, got: %v", err)
+	}
+	if requests := transport.requests.Load(); requests != 3 {
+		t.Errorf("Expected 3 requests, got: %d", requests)
+	}
+}
+
+func TestRetryPolicy_StopsWhenContextIsDone(t *testing.T) {
+	server := newFlakyServer(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithRetryPolicy(facilitatorclient.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.VerifyContext(ctx, &types.PaymentPayload{}, &types.PaymentRequirements{})
+	if !errors.Is(err, facilitatorclient.ErrRejected) {
+		t.Errorf("Expected the last attempt's error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the backoff to stop with the context, waited %s", elapsed)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := facilitatorclient.RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, backoff := range expected {
+		attempt := i + 1
+		for range 20 {
+			got := policy.Backoff(attempt)
+			if got < backoff/2 || got > backoff {
+				t.Fatalf("Expected backoff of attempt %d between %s and %s, got: %s", attempt, backoff/2, backoff, got)
+			}
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithHTTPClient(&http.Client{Transport: transport}),
+		facilitatorclient.WithCircuitBreaker(facilitatorclient.NewCircuitBreaker(2, 100*time.Millisecond)),
+	)
+	verify := func() error {
+		_, err := client.VerifyContext(context.Background(), &types.PaymentPayload{}, &types.PaymentRequirements{})
+		return err
+	}
+
+	// The circuit opens after 2 consecutive failures
+	for range 2 {
+		if err := verify(); !errors.Is(err, facilitatorclient.ErrRejected) {
+			t.Fatalf("Expected rejection, got: %v", err)
+		}
+	}
+	if err := verify(); !errors.Is(err, facilitatorclient.ErrCircuitOpen) {
+		t.Fatalf("Expected open circuit, got: %v", err)
+	}
+	if requests := transport.requests.Load(); requests != 2 {
+		t.Errorf("Expected no request while the circuit is open, got: %d requests", requests)
+	}
+
+	// A trial call closes the circuit once the open timeout has elapsed
+	healthy.Store(true)
+	time.Sleep(150 * time.Millisecond)
+	for range 2 {
+		if err := verify(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if requests := transport.requests.Load(); requests != 4 {
+		t.Errorf("Expected 4 requests, got: %d", requests)
+	}
+}
@@ -0,0 +1,112 @@
+// Package fiber provides the x402 payment middleware for Fiber servers.
+// It is configured with the options of the middleware package.
+package fiber
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+
+	"github.com/coinbase/x402/go/pkg/middleware"
+)
+
+// ReportUsage reports the amount, in atomic units of the asset, used by the request of an upto
+// payment, see WithUpto. It is charged instead of the price once the handler returns.
+func ReportUsage(c *fiber.Ctx, amount *big.Int) error {
+	return middleware.ReportUsage(c.UserContext(), amount)
+}
+
+// PaymentMiddleware is the Fiber middleware for the resource server using the x402payment protocol.
+// Amount: the decimal denominated amount to charge (ex: 0.01 for 1 cent)
+func PaymentMiddleware(amount *big.Float, address string, opts ...middleware.Options) fiber.Handler {
+	engine := middleware.NewEngine(middleware.USDAmount(amount), address, middleware.NewOptions(opts...))
+
+	return func(c *fiber.Ctx) error {
+		return handlePayment(c, engine)
+	}
+}
+
+// PaymentMiddlewareContext is PaymentMiddleware returning the error of the supported payment kinds
+// check instead of panicking, see middleware.NewEngineContext
+func PaymentMiddlewareContext(ctx context.Context, amount *big.Float, address string, opts ...middleware.Options) (fiber.Handler, error) {
+	engine, err := middleware.NewEngineContext(ctx, middleware.USDAmount(amount), address, middleware.NewOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *fiber.Ctx) error {
+		return handlePayment(c, engine)
+	}, nil
+}
+
+// handlePayment gates the request behind the engine's payment.
+// Fiber buffers responses until the handler chain returns, so the handler's response
+// can be replaced if the settlement fails.
+func handlePayment(c *fiber.Ctx, engine *middleware.Engine) error {
+	req, err := adaptor.ConvertRequest(c, false)
+	if err != nil {
+		return writeResponse(c, middleware.ErrorResponse(http.StatusInternalServerError, err))
+	}
+
+	payment, response := engine.Verify(req.WithContext(c.UserContext()))
+	if response != nil {
+		return writeResponse(c, response)
+	}
+	c.SetUserContext(middleware.ContextWithPayment(c.UserContext(), payment))
+
+	switch engine.SettlementMode() {
+	case middleware.SettleBeforeHandler:
+		settleResponseHeader, response := engine.Settle(c.UserContext(), payment)
+		if response != nil {
+			return writeResponse(c, response)
+		}
+		if settleResponseHeader != "" {
+			c.Set(middleware.PaymentResponseHeader, settleResponseHeader)
+		}
+		return c.Next()
+	case middleware.SettleAsync:
+		settleResponseHeader, response := engine.HoldSettlement(c.UserContext(), payment)
+		if response != nil {
+			return writeResponse(c, response)
+		}
+		if settleResponseHeader != "" {
+			c.Set(middleware.PaymentResponseHeader, settleResponseHeader)
+		}
+		if err := c.Next(); err != nil {
+			engine.CancelSettlement(c.UserContext(), payment)
+			return err
+		}
+		engine.SettleAsync(c.UserContext(), payment)
+		return nil
+	}
+
+	// Execute the handler, the payment is not settled if it fails
+	if err := c.Next(); err != nil {
+		return err
+	}
+
+	// Settle payment
+	settleResponseHeader, response := engine.Settle(c.UserContext(), payment)
+	if response != nil {
+		return writeResponse(c, response)
+	}
+
+	if settleResponseHeader != "" {
+		c.Set(middleware.PaymentResponseHeader, settleResponseHeader)
+	}
+
+	return nil
+}
+
+// writeResponse writes a response of the engine
+func writeResponse(c *fiber.Ctx, response *middleware.Response) error {
+	if response.HTML != "" {
+		c.Set(fiber.HeaderContentType, "text/html")
+		return c.Status(response.StatusCode).SendString(response.HTML)
+	}
+
+	return c.Status(response.StatusCode).JSON(response.Body)
+}
@@ -0,0 +1,66 @@
+package fiber_test
+
+import (
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	x402fiber "github.com/coinbase/x402/go/pkg/fiber"
+	"github.com/coinbase/x402/go/pkg/middleware"
+	"github.com/coinbase/x402/go/pkg/middleware/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// serve adapts a Fiber app to a middlewaretest.Server
+func serve(t *testing.T, app *fiber.App) middlewaretest.Server {
+	return func(req *http.Request) *http.Response {
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+}
+
+func TestPaymentMiddleware(t *testing.T) {
+	middlewaretest.Run(t, func(t *testing.T, amount *big.Float, payTo string, opts ...middleware.Options) middlewaretest.Server {
+		app := fiber.New()
+		app.Get("/protected", x402fiber.PaymentMiddleware(amount, payTo, opts...), func(c *fiber.Ctx) error {
+			return c.Status(http.StatusCreated).SendString("success")
+		})
+
+		return serve(t, app)
+	})
+}
+
+func TestPaymentMiddleware_HandlerError(t *testing.T) {
+	facilitatorServer := middlewaretest.NewFacilitatorServer(t, http.StatusOK, true, http.StatusOK, true)
+
+	app := fiber.New()
+	app.Get("/protected", x402fiber.PaymentMiddleware(
+		big.NewFloat(1.0),
+		middlewaretest.PayTo,
+		middleware.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+	), func(c *fiber.Ctx) error {
+		return fiber.NewError(http.StatusBadRequest, "bad input")
+	})
+
+	payment, err := middlewaretest.NewPaymentPayload().EncodeToBase64String()
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("X-PAYMENT", payment)
+	resp := serve(t, app)(req)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, string(body), "bad input")
+	assert.Empty(t, resp.Header.Get("X-PAYMENT-RESPONSE"))
+}
@@ -1,288 +1,213 @@
 package gin
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"math/big"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 
-	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/middleware"
 	"github.com/coinbase/x402/go/pkg/types"
 )
 
-const x402Version = 1
-
-// PaymentMiddlewareOptions is the options for the PaymentMiddleware.
-type PaymentMiddlewareOptions struct {
-	Description       string
-	MimeType          string
-	MaxTimeoutSeconds int
-	OutputSchema      *json.RawMessage
-	FacilitatorConfig *types.FacilitatorConfig
-	Testnet           bool
-	CustomPaywallHTML string
-	Resource          string
-	ResourceRootURL   string
-}
-
-// Options is the type for the options for the PaymentMiddleware.
-type Options func(*PaymentMiddlewareOptions)
-
-// WithDescription is an option for the PaymentMiddleware to set the description.
-func WithDescription(description string) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.Description = description
-	}
-}
-
-// WithMimeType is an option for the PaymentMiddleware to set the mime type.
-func WithMimeType(mimeType string) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.MimeType = mimeType
-	}
-}
-
-// WithMaxDeadlineSeconds is an option for the PaymentMiddleware to set the max timeout seconds.
-func WithMaxTimeoutSeconds(maxTimeoutSeconds int) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.MaxTimeoutSeconds = maxTimeoutSeconds
-	}
-}
-
-// WithOutputSchema is an option for the PaymentMiddleware to set the output schema.
-func WithOutputSchema(outputSchema *json.RawMessage) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.OutputSchema = outputSchema
-	}
-}
-
-// WithFacilitatorConfig is an option for the PaymentMiddleware to set the facilitator config.
-func WithFacilitatorConfig(config *types.FacilitatorConfig) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.FacilitatorConfig = config
-	}
-}
+// The options of the Gin middleware are those of the middleware package, which documents them.
+type (
+	PaymentMiddlewareOptions = middleware.PaymentMiddlewareOptions
+	Options                  = middleware.Options
+	PaymentOption            = middleware.PaymentOption
+	SettlementMode           = middleware.SettlementMode
+	SupportedCheck           = middleware.SupportedCheck
+	Catalog                  = middleware.Catalog
+)
 
-// WithTestnet is an option for the PaymentMiddleware to set the testnet flag.
-func WithTestnet(testnet bool) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.Testnet = testnet
-	}
-}
+// See the middleware package.
+const (
+	SettleAfterHandler    = middleware.SettleAfterHandler
+	SettleBeforeHandler   = middleware.SettleBeforeHandler
+	SettleAsync           = middleware.SettleAsync
+	SupportedCheckNone    = middleware.SupportedCheckNone
+	SupportedCheckRequire = middleware.SupportedCheckRequire
+	SupportedCheckFilter  = middleware.SupportedCheckFilter
+)
 
-// WithCustomPaywallHTML is an option for the PaymentMiddleware to set the custom paywall HTML.
-func WithCustomPaywallHTML(customPaywallHTML string) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.CustomPaywallHTML = customPaywallHTML
-	}
-}
+// See the middleware package.
+var (
+	NewCatalog                   = middleware.NewCatalog
+	WithCatalog                  = middleware.WithCatalog
+	WithDescription              = middleware.WithDescription
+	WithDiscoveryMetadata        = middleware.WithDiscoveryMetadata
+	WithMimeType                 = middleware.WithMimeType
+	WithMaxTimeoutSeconds        = middleware.WithMaxTimeoutSeconds
+	WithOutputSchema             = middleware.WithOutputSchema
+	WithFacilitator              = middleware.WithFacilitator
+	WithFacilitatorPool          = middleware.WithFacilitatorPool
+	WithFacilitatorConfig        = middleware.WithFacilitatorConfig
+	WithFacilitatorTimeout       = middleware.WithFacilitatorTimeout
+	WithFacilitatorClientOptions = middleware.WithFacilitatorClientOptions
+	WithNetwork                  = middleware.WithNetwork
+	WithPaymentOptions           = middleware.WithPaymentOptions
+	WithPrice                    = middleware.WithPrice
+	WithTestnet                  = middleware.WithTestnet
+	WithUpto                     = middleware.WithUpto
+	WithCustomPaywallHTML        = middleware.WithCustomPaywallHTML
+	WithResource                 = middleware.WithResource
+	WithResourceRootURL          = middleware.WithResourceRootURL
+	WithSettlementMode           = middleware.WithSettlementMode
+	WithSettlementQueue          = middleware.WithSettlementQueue
+	WithSupportedCheck           = middleware.WithSupportedCheck
+	WithLogger                   = middleware.WithLogger
+)
 
-// WithResource is an option for the PaymentMiddleware to set the resource.
-func WithResource(resource string) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.Resource = resource
-	}
+// Price is the price of a resource, see types.USD and types.TokenAmount.
+type Price = types.Price
+
+// ginContextKey is the request context key of the gin context, for WithPriceFunc
+type ginContextKey struct{}
+
+// WithPriceFunc is an option for the PaymentMiddleware to compute the price of each request,
+// for example from its query or path parameters or the authenticated user. The price is
+// computed once per request and used for both the 402 response and the payment verification
+// and settlement.
+func WithPriceFunc(priceFunc func(c *gin.Context) (Price, error)) Options {
+	return middleware.WithPriceFunc(func(r *http.Request) (types.Price, error) {
+		c, ok := r.Context().Value(ginContextKey{}).(*gin.Context)
+		if !ok {
+			return types.Price{}, fmt.Errorf("gin context not found in request")
+		}
+		return priceFunc(c)
+	})
 }
 
-func WithResourceRootURL(resourceRootURL string) Options {
-	return func(options *PaymentMiddlewareOptions) {
-		options.ResourceRootURL = resourceRootURL
-	}
+// ReportUsage reports the amount, in atomic units of the asset, used by the request of an upto
+// payment, see WithUpto. It is charged instead of the price once the handler returns.
+func ReportUsage(c *gin.Context, amount *big.Int) error {
+	return middleware.ReportUsage(c.Request.Context(), amount)
 }
 
 // PaymentMiddleware is the Gin middleware for the resource server using the x402payment protocol.
 // Amount: the decimal denominated amount to charge (ex: 0.01 for 1 cent)
 func PaymentMiddleware(amount *big.Float, address string, opts ...Options) gin.HandlerFunc {
-	options := &PaymentMiddlewareOptions{
-		FacilitatorConfig: &types.FacilitatorConfig{
-			URL: facilitatorclient.DefaultFacilitatorURL,
-		},
-		MaxTimeoutSeconds: 60,
-		Testnet:           true,
+	engine := middleware.NewEngine(middleware.USDAmount(amount), address, middleware.NewOptions(opts...))
+
+	return func(c *gin.Context) {
+		handlePayment(c, engine)
 	}
+}
 
-	for _, opt := range opts {
-		opt(options)
+// PaymentMiddlewareContext is PaymentMiddleware returning the error of the supported payment kinds
+// check instead of panicking, see middleware.NewEngineContext
+func PaymentMiddlewareContext(ctx context.Context, amount *big.Float, address string, opts ...Options) (gin.HandlerFunc, error) {
+	engine, err := middleware.NewEngineContext(ctx, middleware.USDAmount(amount), address, middleware.NewOptions(opts...))
+	if err != nil {
+		return nil, err
 	}
 
 	return func(c *gin.Context) {
-		var (
-			network              = "base"
-			usdcAddress          = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
-			facilitatorClient    = facilitatorclient.NewFacilitatorClient(options.FacilitatorConfig)
-			maxAmountRequired, _ = new(big.Float).Mul(amount, big.NewFloat(1e6)).Int(nil)
-		)
-
-		if options.Testnet {
-			network = "base-sepolia"
-			usdcAddress = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
-		}
-
-		fmt.Println("Payment middleware checking request:", c.Request.URL)
-
-		userAgent := c.GetHeader("User-Agent")
-		acceptHeader := c.GetHeader("Accept")
-		isWebBrowser := strings.Contains(acceptHeader, "text/html") && strings.Contains(userAgent, "Mozilla")
-		var resource string
-		if options.Resource == "" {
-			resource = options.ResourceRootURL + c.Request.URL.Path
-		} else {
-			resource = options.Resource
-		}
+		handlePayment(c, engine)
+	}, nil
+}
 
-		paymentRequirements := &types.PaymentRequirements{
-			Scheme:            "exact",
-			Network:           network,
-			MaxAmountRequired: maxAmountRequired.String(),
-			Resource:          resource,
-			Description:       options.Description,
-			MimeType:          options.MimeType,
-			PayTo:             address,
-			MaxTimeoutSeconds: options.MaxTimeoutSeconds,
-			Asset:             usdcAddress,
-			OutputSchema:      options.OutputSchema,
-			Extra:             nil,
-		}
+// handlePayment gates the request behind the engine's payment
+func handlePayment(c *gin.Context, engine *middleware.Engine) {
+	payment, response := engine.Verify(c.Request.WithContext(context.WithValue(c.Request.Context(), ginContextKey{}, c)))
+	if response != nil {
+		abortWithResponse(c, response)
+		return
+	}
+	c.Request = c.Request.WithContext(middleware.ContextWithPayment(c.Request.Context(), payment))
 
-		if err := paymentRequirements.SetUSDCInfo(options.Testnet); err != nil {
-			fmt.Println("failed to set USDC info:", err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"error":       err.Error(),
-				"x402Version": x402Version,
-			})
+	switch engine.SettlementMode() {
+	case middleware.SettleBeforeHandler:
+		settleResponseHeader, response := engine.Settle(c.Request.Context(), payment)
+		if response != nil {
+			abortWithResponse(c, response)
 			return
 		}
-
-		payment := c.GetHeader("X-PAYMENT")
-		paymentPayload, err := types.DecodePaymentPayloadFromBase64(payment)
-		if err != nil {
-			if isWebBrowser {
-				html := options.CustomPaywallHTML
-				if html == "" {
-					html = getPaywallHtml(options)
-				}
-				c.Abort()
-				c.Data(http.StatusPaymentRequired, "text/html", []byte(html))
-				return
-			}
-
-			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{
-				"error":       "X-PAYMENT header is required",
-				"accepts":     []*types.PaymentRequirements{paymentRequirements},
-				"x402Version": x402Version,
-			})
-			return
+		if settleResponseHeader != "" {
+			c.Header(middleware.PaymentResponseHeader, settleResponseHeader)
 		}
-		paymentPayload.X402Version = x402Version
-
-		// Verify payment
-		response, err := facilitatorClient.Verify(paymentPayload, paymentRequirements)
-		if err != nil {
-			fmt.Println("failed to verify", err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"error":       err.Error(),
-				"x402Version": x402Version,
-			})
+		c.Next()
+		return
+	case middleware.SettleAsync:
+		settleResponseHeader, response := engine.HoldSettlement(c.Request.Context(), payment)
+		if response != nil {
+			abortWithResponse(c, response)
 			return
 		}
-
-		if !response.IsValid {
-			fmt.Println("Invalid payment: ", response.InvalidReason)
-			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{
-				"error":       response.InvalidReason,
-				"accepts":     []*types.PaymentRequirements{paymentRequirements},
-				"x402Version": x402Version,
-			})
+		if settleResponseHeader != "" {
+			c.Header(middleware.PaymentResponseHeader, settleResponseHeader)
+		}
+		c.Next()
+		if c.IsAborted() {
+			engine.CancelSettlement(c.Request.Context(), payment)
 			return
 		}
+		engine.SettleAsync(c.Request.Context(), payment)
+		return
+	}
 
-		fmt.Println("Payment verified, proceeding")
+	// Create a custom response writer to intercept the response
+	writer := &responseWriter{
+		ResponseWriter: c.Writer,
+		buffer:         middleware.NewResponseBuffer(),
+	}
+	c.Writer = writer
 
-		// Create a custom response writer to intercept the response
-		writer := &responseWriter{
-			ResponseWriter: c.Writer,
-			body:           &strings.Builder{},
-			statusCode:     http.StatusOK,
-		}
-		c.Writer = writer
+	// Execute the handler
+	c.Next()
 
-		// Execute the handler
-		c.Next()
+	// Reset the response writer to the original
+	c.Writer = writer.ResponseWriter
 
-		// Check if the handler was aborted
-		if c.IsAborted() {
-			return
-		}
+	// Check if the handler was aborted, in which case the payment is not settled
+	if c.IsAborted() {
+		c.Writer.WriteHeader(writer.buffer.StatusCode)
+		c.Writer.Write(writer.buffer.Body.Bytes())
+		return
+	}
 
-		// Settle payment
-		settleResponse, err := facilitatorClient.Settle(paymentPayload, paymentRequirements)
-		if err != nil {
-			fmt.Println("Settlement failed:", err)
-			// Reset the response writer
-			c.Writer = writer.ResponseWriter
-			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{
-				"error":       err.Error(),
-				"accepts":     []*types.PaymentRequirements{paymentRequirements},
-				"x402Version": x402Version,
-			})
-			return
-		}
+	// Settle payment
+	settleResponseHeader, response := engine.Settle(c.Request.Context(), payment)
+	if response != nil {
+		abortWithResponse(c, response)
+		return
+	}
 
-		settleResponseHeader, err := settleResponse.EncodeToBase64String()
-		if err != nil {
-			fmt.Println("Settle Header Encoding failed:", err)
-			// Reset the response writer
-			c.Writer = writer.ResponseWriter
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"error":       err.Error(),
-				"x402Version": x402Version,
-			})
-			return
-		}
+	// Write the original response with the settlement header
+	if settleResponseHeader != "" {
+		c.Header(middleware.PaymentResponseHeader, settleResponseHeader)
+	}
+	c.Writer.WriteHeader(writer.buffer.StatusCode)
+	c.Writer.Write(writer.buffer.Body.Bytes())
+}
 
-		// Write the original response with the settlement header
-		c.Header("X-PAYMENT-RESPONSE", settleResponseHeader)
-		// Reset the response writer to the original
-		c.Writer = writer.ResponseWriter
-		c.Writer.WriteHeader(writer.statusCode)
-		c.Writer.Write([]byte(writer.body.String()))
+// abortWithResponse aborts the request with a response of the engine
+func abortWithResponse(c *gin.Context, response *middleware.Response) {
+	if response.HTML != "" {
+		c.Abort()
+		c.Data(response.StatusCode, "text/html", []byte(response.HTML))
+		return
 	}
+
+	c.AbortWithStatusJSON(response.StatusCode, response.Body)
 }
 
 // responseWriter is a custom response writer that captures the response
 type responseWriter struct {
 	gin.ResponseWriter
-	body       *strings.Builder
-	statusCode int
-	written    bool
+	buffer *middleware.ResponseBuffer
 }
 
 func (w *responseWriter) WriteHeader(code int) {
-	if !w.written {
-		w.statusCode = code
-		w.written = true
-	}
+	w.buffer.WriteHeader(code)
 }
 
 func (w *responseWriter) Write(b []byte) (int, error) {
-	if !w.written {
-		w.WriteHeader(http.StatusOK)
-	}
-	w.body.Write(b)
-	return len(b), nil
+	return w.buffer.Write(b)
 }
 
 func (w *responseWriter) WriteString(s string) (int, error) {
-	if !w.written {
-		w.WriteHeader(http.StatusOK)
-	}
-	return w.body.WriteString(s)
-}
-
-// getPaywallHtml is the default paywall HTML for the PaymentMiddleware.
-func getPaywallHtml(_ *PaymentMiddlewareOptions) string {
-	return "<html><body>Payment Required</body></html>"
+	return w.buffer.Write([]byte(s))
 }
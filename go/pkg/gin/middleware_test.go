@@ -1,17 +1,32 @@
 package gin_test
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
 	x402gin "github.com/coinbase/x402/go/pkg/gin"
+	"github.com/coinbase/x402/go/pkg/middleware"
+	"github.com/coinbase/x402/go/pkg/middleware/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/settlement"
 	"github.com/coinbase/x402/go/pkg/types"
 )
 
@@ -37,27 +52,12 @@ type TestServerConfig struct {
 
 // NewTestConfig returns a default test configuration with successful responses.
 func NewTestConfig() TestServerConfig {
-	invalidReason := "Invalid payment"
-	settleErrorReason := "Settlement failed"
-	payer := "0xvalidPayer"
+	invalidReason := string(types.ErrorReasonInsufficientFunds)
+	settleErrorReason := string(types.ErrorReasonInvalidTransactionState)
+	payer := middlewaretest.Payer
 
 	return TestServerConfig{
-		PaymentPayload: &types.PaymentPayload{
-			X402Version: 1,
-			Scheme:      "exact",
-			Network:     "base-sepolia",
-			Payload: &types.ExactEvmPayload{
-				Signature: "0xvalidSignature",
-				Authorization: &types.ExactEvmPayloadAuthorization{
-					From:        "0xvalidFrom",
-					To:          "0xvalidTo",
-					Value:       "1000000",
-					ValidAfter:  "1745323800",
-					ValidBefore: "1745323985",
-					Nonce:       "0xvalidNonce",
-				},
-			},
-		},
+		PaymentPayload:    middlewaretest.NewPaymentPayload(),
 		VerifySuccess:     true,
 		InvalidReason:     &invalidReason,
 		SettleSuccess:     true,
@@ -70,11 +70,10 @@ func NewTestConfig() TestServerConfig {
 	}
 }
 
-// setupTest creates a test environment with configurable facilitator server.
-func setupTest(t *testing.T, amount *big.Float, address string, config TestServerConfig, opts ...x402gin.Options) (*gin.Engine, *httptest.ResponseRecorder, *http.Request) {
+// newTestFacilitatorServer creates a test facilitator server responding as configured.
+func newTestFacilitatorServer(t *testing.T, config TestServerConfig) *httptest.Server {
 	t.Helper()
 
-	// Create a test facilitator server
 	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case "/verify":
@@ -93,10 +92,24 @@ func setupTest(t *testing.T, amount *big.Float, address string, config TestServe
 				Network:     config.Network,
 				Payer:       config.Payer,
 			})
+		case "/supported":
+			json.NewEncoder(w).Encode(types.SupportedPaymentKindsResponse{
+				Kinds: []types.SupportedPaymentKind{{X402Version: 1, Scheme: types.SchemeExact, Network: config.Network}},
+			})
 		}
 	}))
 	t.Cleanup(func() { facilitatorServer.Close() })
 
+	return facilitatorServer
+}
+
+// setupTest creates a test environment with configurable facilitator server.
+func setupTest(t *testing.T, amount *big.Float, address string, config TestServerConfig, opts ...x402gin.Options) (*gin.Engine, *httptest.ResponseRecorder, *http.Request) {
+	t.Helper()
+
+	// Create a test facilitator server
+	facilitatorServer := newTestFacilitatorServer(t, config)
+
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
 
@@ -110,13 +123,13 @@ func setupTest(t *testing.T, amount *big.Float, address string, config TestServe
 	})
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/protected", nil)
+	req := httptest.NewRequest("GET", "/protected", nil)
 
 	return router, w, req
 }
 
 func TestPaymentMiddleware_NoPaymentHeader(t *testing.T) {
-	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", NewTestConfig())
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, NewTestConfig())
 
 	router.ServeHTTP(w, req)
 
@@ -130,7 +143,7 @@ func TestPaymentMiddleware_NoPaymentHeader(t *testing.T) {
 }
 
 func TestPaymentMiddleware_WebBrowserRequest(t *testing.T) {
-	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", NewTestConfig())
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, NewTestConfig())
 
 	req.Header.Set("Accept", "text/html")
 	req.Header.Set("User-Agent", "Mozilla/5.0")
@@ -144,7 +157,7 @@ func TestPaymentMiddleware_WebBrowserRequest(t *testing.T) {
 func TestPaymentMiddleware_ValidPayment(t *testing.T) {
 	config := NewTestConfig()
 
-	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config)
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config)
 
 	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
 	assert.NoError(t, err, "marshaling payment payload should not fail")
@@ -171,9 +184,9 @@ func TestPaymentMiddleware_ValidPayment(t *testing.T) {
 func TestPaymentMiddleware_VerificationFails(t *testing.T) {
 	config := NewTestConfig()
 	config.VerifySuccess = false
-	config.PaymentPayload.Payload.Signature = "0xInvalidSignature"
+	config.PaymentPayload.Payload.Signature = "0x" + strings.Repeat("de", 65)
 
-	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config)
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config)
 
 	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
 	assert.NoError(t, err, "marshaling payment payload should not fail")
@@ -196,7 +209,7 @@ func TestPaymentMiddleware_VerificationServerError(t *testing.T) {
 	config := NewTestConfig()
 	config.VerifyStatusCode = http.StatusInternalServerError
 
-	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config)
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config)
 
 	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
 	assert.NoError(t, err, "marshaling payment payload should not fail")
@@ -217,7 +230,7 @@ func TestPaymentMiddleware_SettlementFails(t *testing.T) {
 	config := NewTestConfig()
 	config.SettleSuccess = false
 
-	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config)
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config)
 
 	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
 	assert.NoError(t, err, "marshaling payment payload should not fail")
@@ -241,11 +254,82 @@ func TestPaymentMiddleware_SettlementFails(t *testing.T) {
 	assert.False(t, settleResponse.Success)
 }
 
+func TestPaymentMiddleware_Logger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	config := NewTestConfig()
+	config.SettleStatusCode = http.StatusInternalServerError
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config, x402gin.WithLogger(logger))
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	require.NoError(t, err)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusPaymentRequired, w.Code)
+
+	assert.Contains(t, logs.String(), `level=DEBUG msg="payment verified" network=base-sepolia`)
+	assert.Contains(t, logs.String(), `level=ERROR msg="failed to settle payment"`)
+}
+
+// lockedBuffer is a buffer written by the logs of background settlements
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestPaymentMiddleware_SettlementFailureLogged(t *testing.T) {
+	testCases := []struct {
+		mode           x402gin.SettlementMode
+		expectedStatus int
+	}{
+		{mode: x402gin.SettleAfterHandler, expectedStatus: http.StatusOK},
+		{mode: x402gin.SettleBeforeHandler, expectedStatus: http.StatusPaymentRequired},
+		{mode: x402gin.SettleAsync, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			var logs lockedBuffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+			config := NewTestConfig()
+			config.SettleSuccess = false
+			router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config,
+				x402gin.WithLogger(logger),
+				x402gin.WithSettlementMode(tc.mode),
+			)
+
+			paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+			require.NoError(t, err)
+			req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+
+			assert.Eventually(t, func() bool {
+				return strings.Contains(logs.String(), `level=WARN msg="settlement failed" reason=invalid_transaction_state`)
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
 func TestPaymentMiddleware_SettlementServerError(t *testing.T) {
 	config := NewTestConfig()
 	config.SettleStatusCode = http.StatusInternalServerError
 
-	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config)
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config)
 
 	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
 	assert.NoError(t, err, "marshaling payment payload should not fail")
@@ -260,6 +344,236 @@ func TestPaymentMiddleware_SettlementServerError(t *testing.T) {
 	assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
 }
 
+// newHangingFacilitatorServer creates a facilitator server that never answers /verify
+func newHangingFacilitatorServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	release := make(chan struct{})
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(facilitatorServer.Close)
+	t.Cleanup(func() { close(release) })
+
+	return facilitatorServer
+}
+
+func TestPaymentMiddleware_FacilitatorTimeout(t *testing.T) {
+	facilitatorServer := newHangingFacilitatorServer(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithFacilitatorTimeout(50*time.Millisecond),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	paymentPayloadJson, err := json.Marshal(middlewaretest.NewPaymentPayload())
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), "context deadline exceeded")
+}
+
+func TestPaymentMiddleware_RequestContextCancelsVerify(t *testing.T) {
+	facilitatorServer := newHangingFacilitatorServer(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	paymentPayloadJson, err := json.Marshal(middlewaretest.NewPaymentPayload())
+	require.NoError(t, err)
+
+	// The request deadline is much shorter than the default verify timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil).WithContext(ctx)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+
+	start := time.Now()
+	router.ServeHTTP(w, req)
+
+	assert.Less(t, time.Since(start), facilitatorclient.DefaultVerifyTimeout)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestPaymentMiddleware_RetriesTransientVerifyFailure(t *testing.T) {
+	config := NewTestConfig()
+	var verifyCalls atomic.Int32
+	testFacilitatorServer := newTestFacilitatorServer(t, config)
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/verify" && verifyCalls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		testFacilitatorServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(facilitatorServer.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithFacilitatorClientOptions(facilitatorclient.WithRetryPolicy(facilitatorclient.RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+		})),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 2, verifyCalls.Load())
+	assert.NotEmpty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+}
+
+func TestPaymentMiddleware_FacilitatorPoolFailsOver(t *testing.T) {
+	config := NewTestConfig()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	var settleCalls atomic.Int32
+	testFacilitatorServer := newTestFacilitatorServer(t, config)
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/settle" {
+			settleCalls.Add(1)
+		}
+		testFacilitatorServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(facilitatorServer.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), middlewaretest.PayTo,
+		x402gin.WithFacilitatorPool(facilitatorclient.PoolConfig{
+			Members: []facilitatorclient.PoolMember{
+				{Config: &types.FacilitatorConfig{URL: down.URL}, Priority: 0},
+				{Config: &types.FacilitatorConfig{URL: facilitatorServer.URL}, Priority: 1},
+			},
+			HealthCheckInterval: -1,
+		}),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 1, settleCalls.Load())
+	assert.NotEmpty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+}
+
+func TestPaymentMiddleware_SupportedCheckRequire(t *testing.T) {
+	config := NewTestConfig()
+
+	assert.NotPanics(t, func() {
+		setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config, x402gin.WithSupportedCheck(x402gin.SupportedCheckRequire))
+	})
+
+	assert.PanicsWithError(t, "payment kind not supported by the facilitator: exact on base", func() {
+		setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config,
+			x402gin.WithSupportedCheck(x402gin.SupportedCheckRequire),
+			x402gin.WithNetwork("base"),
+		)
+	})
+
+	facilitatorServer := newTestFacilitatorServer(t, config)
+	_, err := x402gin.PaymentMiddlewareContext(context.Background(), big.NewFloat(1.0), middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithSupportedCheck(x402gin.SupportedCheckRequire),
+		x402gin.WithNetwork("base"),
+	)
+	assert.ErrorIs(t, err, middleware.ErrUnsupportedPaymentKind)
+
+	// The check is skipped when the facilitator can't be reached
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	assert.NotPanics(t, func() {
+		x402gin.PaymentMiddleware(big.NewFloat(1.0), middlewaretest.PayTo,
+			x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: down.URL}),
+			x402gin.WithNetwork("base"),
+			x402gin.WithSupportedCheck(x402gin.SupportedCheckRequire),
+		)
+	})
+
+	// or does not answer in time
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hung.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = x402gin.PaymentMiddlewareContext(ctx, big.NewFloat(1.0), middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: hung.URL}),
+		x402gin.WithNetwork("base"),
+		x402gin.WithSupportedCheck(x402gin.SupportedCheckRequire),
+	)
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestPaymentMiddleware_SupportedCheckFilter(t *testing.T) {
+	config := NewTestConfig()
+
+	router, w, req := setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config,
+		x402gin.WithSupportedCheck(x402gin.SupportedCheckFilter),
+		x402gin.WithPaymentOptions(
+			x402gin.PaymentOption{Network: "base"},
+			x402gin.PaymentOption{Network: "base-sepolia"},
+		),
+	)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	var response struct {
+		Accepts []types.PaymentRequirements `json:"accepts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Accepts, 1)
+	assert.Equal(t, "base-sepolia", response.Accepts[0].Network)
+
+	// None of the options is supported
+	router, w, req = setupTest(t, big.NewFloat(1.0), middlewaretest.PayTo, config,
+		x402gin.WithSupportedCheck(x402gin.SupportedCheckFilter),
+		x402gin.WithNetwork("base"),
+	)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
 func TestPaymentMiddleware_Options(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -276,16 +590,16 @@ func TestPaymentMiddleware_Options(t *testing.T) {
 				x402gin.WithTestnet(true),
 			},
 			amount:  big.NewFloat(1.0),
-			address: "0xTestAddress",
+			address: middlewaretest.PayTo,
 		},
 		{
 			name: "with custom paywall HTML",
 			opts: []x402gin.Options{
 				x402gin.WithCustomPaywallHTML("<html><body>Custom Paywall</body></html>"),
-				x402gin.WithResource("test-resource"),
+				x402gin.WithResource("https://example.com/test-resource"),
 			},
 			amount:  big.NewFloat(2.0),
-			address: "0xTestAddress2",
+			address: "0x857b06519E91e3A54538791bDbb0E22373e36b66",
 		},
 	}
 
@@ -329,7 +643,7 @@ func TestPaymentMiddleware_NetworkSelection(t *testing.T) {
 			router, w, req := setupTest(
 				t,
 				big.NewFloat(1.0),
-				"0xTestAddress",
+				middlewaretest.PayTo,
 				NewTestConfig(),
 				x402gin.WithTestnet(tc.testnet),
 			)
@@ -357,3 +671,396 @@ func TestPaymentMiddleware_NetworkSelection(t *testing.T) {
 		})
 	}
 }
+
+func TestPaymentMiddleware_HandlerAborted(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitatorServer(t, config)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", x402gin.PaymentMiddleware(
+		big.NewFloat(1.0),
+		middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+	), func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "bad input"})
+	})
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "bad input")
+	assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+}
+
+func TestPaymentMiddleware_Suite(t *testing.T) {
+	middlewaretest.Run(t, func(t *testing.T, amount *big.Float, payTo string, opts ...x402gin.Options) middlewaretest.Server {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/protected", x402gin.PaymentMiddleware(amount, payTo, opts...), func(c *gin.Context) {
+			c.String(http.StatusCreated, "success")
+		})
+
+		return middlewaretest.ServeHandler(router)
+	})
+}
+
+func TestPaymentMiddleware_PriceFunc(t *testing.T) {
+	config := NewTestConfig()
+
+	// Record the requirements sent to the facilitator
+	var mu sync.Mutex
+	var verified, settled []string
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			PaymentRequirements types.PaymentRequirements `json:"paymentRequirements"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/verify":
+			verified = append(verified, req.PaymentRequirements.MaxAmountRequired)
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: config.Payer})
+		case "/settle":
+			settled = append(settled, req.PaymentRequirements.MaxAmountRequired)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: "base-sepolia"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/weather/:city", x402gin.PaymentMiddleware(
+		big.NewFloat(1.0),
+		middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithPriceFunc(func(c *gin.Context) (x402gin.Price, error) {
+			if c.Param("city") == "atlantis" {
+				return x402gin.Price{}, errors.New("unknown city")
+			}
+			if c.Query("tier") == "premium" {
+				return types.USD("$0.10"), nil
+			}
+			return types.USD("$0.01"), nil
+		}),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "sunny in "+c.Param("city"))
+	})
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	require.NoError(t, err)
+	payment := base64.StdEncoding.EncodeToString(paymentPayloadJson)
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedAmount string
+	}{
+		{name: "default tier", path: "/weather/paris", expectedAmount: "10000"},
+		{name: "premium tier", path: "/weather/paris?tier=premium", expectedAmount: "100000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, requirements := requestRequirements(t, router, "GET", tc.path)
+			require.NotNil(t, requirements)
+			assert.Equal(t, tc.expectedAmount, requirements.MaxAmountRequired)
+
+			mu.Lock()
+			verified, settled = nil, nil
+			mu.Unlock()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.path, nil)
+			req.Header.Set("X-PAYMENT", payment)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "sunny in paris", w.Body.String())
+			assert.Equal(t, []string{tc.expectedAmount}, verified)
+			assert.Equal(t, []string{tc.expectedAmount}, settled)
+		})
+	}
+
+	t.Run("price func error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/weather/atlantis", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to compute price: unknown city")
+	})
+}
+
+func TestPaymentMiddleware_Catalog(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitatorServer(t, config)
+	catalog := x402gin.NewCatalog()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET(middleware.DiscoveryPath, gin.WrapH(catalog))
+	router.GET("/joke", x402gin.PaymentMiddleware(big.NewFloat(0.01), middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithCatalog(catalog),
+		x402gin.WithResource("https://api.example.com/joke"),
+		x402gin.WithDescription("A joke"),
+		x402gin.WithDiscoveryMetadata(map[string]any{"category": "fun"}),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+	router.GET("/weather/:city", x402gin.PaymentMiddleware(big.NewFloat(0.001), middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithCatalog(catalog),
+		x402gin.WithResourceRootURL("https://api.example.com"),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	list := func(query string) types.ListDiscoveryResourcesResponse {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, middleware.DiscoveryPath+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response types.ListDiscoveryResourcesResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// Static resources are listed when the middleware is created
+	response := list("")
+	require.Len(t, response.Items, 1)
+	joke := response.Items[0]
+	assert.Equal(t, "https://api.example.com/joke", joke.Resource)
+	assert.Equal(t, types.ResourceTypeHTTP, joke.Type)
+	assert.Equal(t, "fun", joke.Metadata["category"])
+	require.Len(t, joke.Accepts, 1)
+	assert.Equal(t, "10000", joke.Accepts[0].MaxAmountRequired)
+	assert.Equal(t, "A joke", joke.Accepts[0].Description)
+
+	// Other resources are listed once paid for
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/weather/london", nil))
+	require.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Len(t, list("").Items, 1)
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/weather/paris", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	response = list("?limit=1&offset=1")
+	assert.Equal(t, types.DiscoveryPagination{Limit: 1, Offset: 1, Total: 2}, response.Pagination)
+	require.Len(t, response.Items, 1)
+	assert.Equal(t, "https://api.example.com/weather/paris", response.Items[0].Resource)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, middleware.DiscoveryPath+"?limit=ten", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The catalog is bounded
+	for i := range middleware.MaxCatalogResources {
+		catalog.Register(types.DiscoveredResource{Resource: fmt.Sprintf("https://api.example.com/%d", i)})
+	}
+	assert.Equal(t, middleware.MaxCatalogResources, list("").Pagination.Total)
+	assert.True(t, catalog.Register(types.DiscoveredResource{Resource: "https://api.example.com/joke"}))
+	assert.False(t, catalog.Register(types.DiscoveredResource{Resource: "https://api.example.com/new"}))
+}
+
+// recordingFacilitator verifies every payment and records the settlements among the events of a
+// request, along with the handler's
+type recordingFacilitator struct {
+	settleErr error
+
+	mu      sync.Mutex
+	events  []string
+	settled chan struct{}
+}
+
+func (f *recordingFacilitator) record(event string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+}
+
+func (f *recordingFacilitator) recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.events)
+}
+
+func (f *recordingFacilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	return &types.VerifyResponse{IsValid: true}, nil
+}
+
+func (f *recordingFacilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	defer close(f.settled)
+	f.record("settle")
+	if f.settleErr != nil {
+		return nil, f.settleErr
+	}
+	return &types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: requirements.Network}, nil
+}
+
+func (f *recordingFacilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	return &types.SupportedPaymentKindsResponse{}, nil
+}
+
+func TestPaymentMiddleware_SettlementModes(t *testing.T) {
+	settleErr := &facilitatorclient.RequestError{Op: "settle", Kind: facilitatorclient.ErrRejected, StatusCode: 500, Status: "500 Internal Server Error"}
+
+	testCases := []struct {
+		name           string
+		mode           x402gin.SettlementMode
+		settleErr      error
+		expectedStatus int
+		expectedEvents []string
+		expectHeader   bool
+	}{
+		{
+			name:           "after handler",
+			mode:           x402gin.SettleAfterHandler,
+			expectedStatus: http.StatusOK,
+			expectedEvents: []string{"handler", "settle"},
+			expectHeader:   true,
+		},
+		{
+			name:           "after handler, settlement fails after the handler ran",
+			mode:           x402gin.SettleAfterHandler,
+			settleErr:      settleErr,
+			expectedStatus: http.StatusPaymentRequired,
+			expectedEvents: []string{"handler", "settle"},
+		},
+		{
+			name:           "before handler",
+			mode:           x402gin.SettleBeforeHandler,
+			expectedStatus: http.StatusOK,
+			expectedEvents: []string{"settle", "handler"},
+			expectHeader:   true,
+		},
+		{
+			name:           "before handler, handler not run when settlement fails",
+			mode:           x402gin.SettleBeforeHandler,
+			settleErr:      settleErr,
+			expectedStatus: http.StatusPaymentRequired,
+			expectedEvents: []string{"settle"},
+		},
+		{
+			name:           "async",
+			mode:           x402gin.SettleAsync,
+			expectedStatus: http.StatusOK,
+			expectedEvents: []string{"handler", "settle"},
+		},
+		{
+			name:           "async, response served when settlement fails",
+			mode:           x402gin.SettleAsync,
+			settleErr:      settleErr,
+			expectedStatus: http.StatusOK,
+			expectedEvents: []string{"handler", "settle"},
+		},
+	}
+
+	paymentPayloadJson, err := json.Marshal(middlewaretest.NewPaymentPayload())
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			facilitator := &recordingFacilitator{settleErr: tc.settleErr, settled: make(chan struct{})}
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), middlewaretest.PayTo,
+				x402gin.WithFacilitator(facilitator),
+				x402gin.WithSettlementMode(tc.mode),
+			), func(c *gin.Context) {
+				facilitator.record("handler")
+				c.String(http.StatusOK, "success")
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+			router.ServeHTTP(w, req)
+
+			select {
+			case <-facilitator.settled:
+			case <-time.After(time.Second):
+				t.Fatal("payment not settled")
+			}
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedEvents, facilitator.recorded())
+			assert.Equal(t, tc.expectHeader, w.Header().Get("X-PAYMENT-RESPONSE") != "")
+		})
+	}
+}
+
+func TestPaymentMiddleware_SettlementQueue(t *testing.T) {
+	facilitator := &recordingFacilitator{settled: make(chan struct{})}
+	queue := settlement.NewQueue(settlement.NewMemoryOutbox(), facilitator, settlement.WithPollInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queue.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), middlewaretest.PayTo,
+		x402gin.WithFacilitator(facilitator),
+		x402gin.WithSettlementQueue(queue),
+	), func(c *gin.Context) {
+		facilitator.record("handler")
+		c.String(http.StatusOK, "success")
+	})
+	router.GET("/receipts/:id", gin.WrapH(queue))
+
+	paymentPayloadJson, err := json.Marshal(middlewaretest.NewPaymentPayload())
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	settleResponseJson, err := base64.StdEncoding.DecodeString(w.Header().Get("X-PAYMENT-RESPONSE"))
+	require.NoError(t, err)
+	var settleResponse types.SettleResponse
+	require.NoError(t, json.Unmarshal(settleResponseJson, &settleResponse))
+	assert.True(t, settleResponse.Pending())
+	require.NotEmpty(t, settleResponse.ReceiptID)
+
+	select {
+	case <-facilitator.settled:
+	case <-time.After(time.Second):
+		t.Fatal("payment not settled")
+	}
+	assert.Equal(t, []string{"handler", "settle"}, facilitator.recorded())
+
+	// The receipt can be queried once settled
+	var receipt settlement.Receipt
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/receipts/"+settleResponse.ReceiptID, nil))
+		return w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &receipt) == nil && receipt.Status == settlement.StatusSettled
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, receipt.Response.Success)
+}
@@ -0,0 +1,220 @@
+package gin
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/coinbase/x402/go/pkg/middleware"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// RouteConfig is the payment configuration of a route in a RoutesConfig.
+// Fields left empty fall back to the options passed to RoutesMiddleware, except the price:
+// the price options (WithPrice, WithPriceFunc) do not apply to routes.
+type RouteConfig struct {
+	// Price is the price of the route, see types.USD and types.TokenAmount
+	Price types.Price
+	// Network is the registered network to accept payments on (ex: "base")
+	Network           string
+	Description       string
+	MimeType          string
+	MaxTimeoutSeconds int
+	OutputSchema      *json.RawMessage
+	CustomPaywallHTML string
+	Resource          string
+}
+
+// RoutesConfig maps route patterns to their payment configuration.
+//
+// A pattern is an optional HTTP verb followed by a path, e.g. "GET /weather/[city]".
+// Patterns without a verb match any method. In paths, "*" matches any characters and
+// "[param]" matches a single path segment.
+type RoutesConfig map[string]RouteConfig
+
+// routePattern is a compiled RoutesConfig entry
+type routePattern struct {
+	verb    string
+	path    string
+	pattern *regexp.Regexp
+	engine  *middleware.Engine
+}
+
+var routeParamRegex = regexp.MustCompile(`\\\[[^\]]+\\\]`)
+
+// routeParamPathRegex matches the route params of an unquoted path pattern
+var routeParamPathRegex = regexp.MustCompile(`\[[^\]]+\]`)
+
+// RoutesMiddleware is the Gin middleware for the resource server using the x402payment protocol
+// with per-route prices. It is meant to be mounted once with r.Use.
+//
+// Requests are matched against the route patterns, preferring the most specific pattern when
+// several match: the one with the most literal characters, then with fewer wildcards and params,
+// then the one with a verb. Requests not matching any route are passed through without payment.
+// The routes share a single facilitator client or pool.
+// It panics if a route pattern, price or network is invalid, or if a route is not supported by the
+// facilitator with SupportedCheckRequire.
+func RoutesMiddleware(routes RoutesConfig, payTo string, opts ...Options) gin.HandlerFunc {
+	patterns, err := computeRoutePatterns(routes, payTo, middleware.NewOptions(opts...))
+	if err != nil {
+		panic(err)
+	}
+
+	return func(c *gin.Context) {
+		route := findMatchingRoute(patterns, c.Request.URL.Path, c.Request.Method)
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		handlePayment(c, route.engine)
+	}
+}
+
+// computeRoutePatterns compiles the routes config into route patterns
+func computeRoutePatterns(routes RoutesConfig, payTo string, defaults *PaymentMiddlewareOptions) ([]*routePattern, error) {
+	patterns := make([]*routePattern, 0, len(routes))
+
+	// Share the facilitator, its health checks and its cached supported payment kinds between
+	// the engines of the routes
+	shared := *defaults
+	shared.Facilitator = middleware.NewFacilitator(defaults)
+	defaults = &shared
+
+	for pattern, config := range routes {
+		verb, path := "*", strings.TrimSpace(pattern)
+		if fields := strings.Fields(pattern); len(fields) == 2 {
+			verb, path = strings.ToUpper(fields[0]), fields[1]
+		} else if len(fields) != 1 {
+			return nil, fmt.Errorf("invalid route pattern: %q", pattern)
+		}
+
+		options, err := routeOptions(config, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config for route %q: %w", pattern, err)
+		}
+
+		// Token prices are ERC-20 token amounts on EVM networks, and SPL token amounts on Solana
+		requirements := types.PaymentRequirements{Scheme: types.SchemeExact}
+		if err := requirements.SetPrice(config.Price, options.NetworkName()); err != nil {
+			return nil, fmt.Errorf("invalid price for route %q: %w", pattern, err)
+		}
+
+		// Make wildcards non-greedy and route params match a single path segment
+		expr := regexp.QuoteMeta(path)
+		expr = strings.ReplaceAll(expr, `\*`, ".*?")
+		expr = routeParamRegex.ReplaceAllString(expr, "[^/]+")
+
+		compiled, err := regexp.Compile("(?i)^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid route pattern: %q: %w", pattern, err)
+		}
+
+		engine, err := middleware.NewEngineContext(context.Background(), config.Price, payTo, options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config for route %q: %w", pattern, err)
+		}
+
+		patterns = append(patterns, &routePattern{
+			verb:    verb,
+			path:    path,
+			pattern: compiled,
+			engine:  engine,
+		})
+	}
+
+	slices.SortFunc(patterns, compareSpecificity)
+	return patterns, nil
+}
+
+// compareSpecificity orders route patterns from the most to the least specific, so that the
+// matching route does not depend on the iteration order of the RoutesConfig
+func compareSpecificity(a, b *routePattern) int {
+	aLiteral, aWildcards, aParams := specificity(a.path)
+	bLiteral, bWildcards, bParams := specificity(b.path)
+
+	return cmp.Or(
+		cmp.Compare(bLiteral, aLiteral),
+		cmp.Compare(aWildcards, bWildcards),
+		cmp.Compare(aParams, bParams),
+		cmp.Compare(isAnyVerb(a.verb), isAnyVerb(b.verb)),
+		strings.Compare(a.verb+" "+a.path, b.verb+" "+b.path),
+	)
+}
+
+// specificity returns the number of literal characters, wildcards and params of a path pattern
+func specificity(path string) (literal, wildcards, params int) {
+	wildcards = strings.Count(path, "*")
+	literal = len(path) - wildcards
+	for _, param := range routeParamPathRegex.FindAllString(path, -1) {
+		params++
+		literal -= len(param)
+	}
+	return literal, wildcards, params
+}
+
+// isAnyVerb returns 1 for patterns matching any method, so that they sort after the others
+func isAnyVerb(verb string) int {
+	if verb == "*" {
+		return 1
+	}
+	return 0
+}
+
+// findMatchingRoute returns the most specific route pattern matching the path and method, or nil.
+// The patterns are ordered from the most specific, see compareSpecificity.
+func findMatchingRoute(patterns []*routePattern, path, method string) *routePattern {
+	for _, route := range patterns {
+		if route.verb != "*" && route.verb != strings.ToUpper(method) {
+			continue
+		}
+		if route.pattern.MatchString(path) {
+			return route
+		}
+	}
+
+	return nil
+}
+
+// routeOptions returns the middleware options of a route, falling back to the defaults
+func routeOptions(config RouteConfig, defaults *PaymentMiddlewareOptions) (*PaymentMiddlewareOptions, error) {
+	options := *defaults
+
+	// The price of the route is always its own
+	options.Price = nil
+	options.PriceFunc = nil
+
+	if config.Network != "" {
+		if _, err := types.NetworkFamily(config.Network); err != nil {
+			return nil, err
+		}
+		options.Network = config.Network
+	}
+
+	if config.Description != "" {
+		options.Description = config.Description
+	}
+	if config.MimeType != "" {
+		options.MimeType = config.MimeType
+	}
+	if config.MaxTimeoutSeconds != 0 {
+		options.MaxTimeoutSeconds = config.MaxTimeoutSeconds
+	}
+	if config.OutputSchema != nil {
+		options.OutputSchema = config.OutputSchema
+	}
+	if config.CustomPaywallHTML != "" {
+		options.CustomPaywallHTML = config.CustomPaywallHTML
+	}
+	if config.Resource != "" {
+		options.Resource = config.Resource
+	}
+
+	return &options, nil
+}
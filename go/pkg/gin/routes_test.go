@@ -0,0 +1,269 @@
+package gin_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	x402gin "github.com/coinbase/x402/go/pkg/gin"
+	"github.com/coinbase/x402/go/pkg/middleware/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// setupRoutesTest creates a router using the routes middleware with a catch-all handler.
+func setupRoutesTest(t *testing.T, routes x402gin.RoutesConfig, opts ...x402gin.Options) *gin.Engine {
+	t.Helper()
+
+	facilitatorServer := newTestFacilitatorServer(t, NewTestConfig())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	allOpts := append([]x402gin.Options{x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{
+		URL: facilitatorServer.URL,
+	})}, opts...)
+	router.Use(x402gin.RoutesMiddleware(routes, middlewaretest.PayTo, allOpts...))
+	router.NoRoute(func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	return router
+}
+
+// requestRequirements performs an unpaid request and returns the advertised payment requirements, if any.
+func requestRequirements(t *testing.T, router *gin.Engine, method, path string) (int, *types.PaymentRequirements) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPaymentRequired {
+		return w.Code, nil
+	}
+
+	var response struct {
+		Accepts []*types.PaymentRequirements `json:"accepts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Accepts, 1)
+
+	return w.Code, response.Accepts[0]
+}
+
+func TestRoutesMiddleware_Matching(t *testing.T) {
+	router := setupRoutesTest(t, x402gin.RoutesConfig{
+		"GET /weather/[city]": {Price: types.USD("$0.01"), Description: "Weather"},
+		"/weather/*":          {Price: types.USD("$0.001")},
+		"POST /premium/*":     {Price: types.USD("1"), Network: "base"},
+		"/static/report.pdf":  {Price: types.USD("$0.50"), MimeType: "application/pdf"},
+		"/precise":            {Price: types.USD("0.29 USDC")},
+	})
+
+	testCases := []struct {
+		name                string
+		method              string
+		path                string
+		expectedStatus      int
+		expectedAmount      string
+		expectedNetwork     string
+		expectedDescription string
+	}{
+		{
+			name:                "verb and param match",
+			method:              "GET",
+			path:                "/weather/london",
+			expectedStatus:      http.StatusPaymentRequired,
+			expectedAmount:      "10000",
+			expectedNetwork:     "base-sepolia",
+			expectedDescription: "Weather",
+		},
+		{
+			name:            "param matches a single segment only",
+			method:          "GET",
+			path:            "/weather/london/today",
+			expectedStatus:  http.StatusPaymentRequired,
+			expectedAmount:  "1000",
+			expectedNetwork: "base-sepolia",
+		},
+		{
+			name:            "wildcard matches any verb",
+			method:          "DELETE",
+			path:            "/weather/london",
+			expectedStatus:  http.StatusPaymentRequired,
+			expectedAmount:  "1000",
+			expectedNetwork: "base-sepolia",
+		},
+		{
+			name:            "per-route network",
+			method:          "POST",
+			path:            "/premium/content",
+			expectedStatus:  http.StatusPaymentRequired,
+			expectedAmount:  "1000000",
+			expectedNetwork: "base",
+		},
+		{
+			name:           "verb mismatch passes through",
+			method:         "GET",
+			path:           "/premium/content",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "paths match case-insensitively",
+			method:          "GET",
+			path:            "/STATIC/report.PDF",
+			expectedStatus:  http.StatusPaymentRequired,
+			expectedAmount:  "500000",
+			expectedNetwork: "base-sepolia",
+		},
+		{
+			name:            "exact decimal price",
+			method:          "GET",
+			path:            "/precise",
+			expectedStatus:  http.StatusPaymentRequired,
+			expectedAmount:  "290000",
+			expectedNetwork: "base-sepolia",
+		},
+		{
+			name:           "unmatched route is free",
+			method:         "GET",
+			path:           "/free",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, requirements := requestRequirements(t, router, tc.method, tc.path)
+			assert.Equal(t, tc.expectedStatus, status)
+			if tc.expectedStatus != http.StatusPaymentRequired {
+				return
+			}
+
+			assert.Equal(t, tc.expectedAmount, requirements.MaxAmountRequired)
+			assert.Equal(t, tc.expectedNetwork, requirements.Network)
+			assert.Equal(t, tc.expectedDescription, requirements.Description)
+		})
+	}
+}
+
+func TestRoutesMiddleware_ValidPayment(t *testing.T) {
+	config := NewTestConfig()
+	router := setupRoutesTest(t, x402gin.RoutesConfig{
+		"GET /weather/[city]": {Price: types.USD("$0.01")},
+	})
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/weather/london", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "success", w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+}
+
+func TestRoutesMiddleware_InvalidConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		routes x402gin.RoutesConfig
+	}{
+		{name: "invalid price", routes: x402gin.RoutesConfig{"/a": {Price: types.USD("free")}}},
+		{name: "price too precise", routes: x402gin.RoutesConfig{"/a": {Price: types.USD("$0.0000001")}}},
+		{name: "invalid token amount", routes: x402gin.RoutesConfig{"/a": {Price: types.TokenAmount("1.5", types.ERC20Asset{Address: "0x60a3E35Cc302bFA44Cb288Bc5a4F316Fdb1adb42", Decimals: 6})}}},
+		{name: "unsupported network", routes: x402gin.RoutesConfig{"/a": {Price: types.USD("$1"), Network: "unknown"}}},
+		{name: "invalid pattern", routes: x402gin.RoutesConfig{"GET /a b": {Price: types.USD("$1")}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Panics(t, func() {
+				x402gin.RoutesMiddleware(tc.routes, middlewaretest.PayTo)
+			})
+		})
+	}
+}
+
+func TestRoutesMiddleware_Specificity(t *testing.T) {
+	eurc := types.ERC20Asset{
+		Address:  "0x08210F9170F89Ab7658F0B5E3fF39b0E03C594D4",
+		Decimals: 6,
+		EIP712:   types.EIP712Domain{Name: "EURC", Version: "2"},
+	}
+	routes := x402gin.RoutesConfig{
+		"/items/[id]":     {Price: types.USD("$0.01")},
+		"GET /items/[id]": {Price: types.USD("$0.02")},
+		"/items/new":      {Price: types.TokenAmount("30000", eurc)},
+		"/items/*":        {Price: types.USD("$0.04")},
+	}
+
+	testCases := []struct {
+		method         string
+		path           string
+		expectedAmount string
+		expectedAsset  string
+	}{
+		{method: "GET", path: "/items/42", expectedAmount: "20000"},
+		{method: "POST", path: "/items/42", expectedAmount: "10000"},
+		{method: "GET", path: "/items/new", expectedAmount: "30000", expectedAsset: eurc.Address},
+		{method: "GET", path: "/items/42/reviews", expectedAmount: "40000"},
+	}
+
+	// The matching route does not depend on the iteration order of the routes
+	for range 10 {
+		router := setupRoutesTest(t, routes)
+		for _, tc := range testCases {
+			status, requirements := requestRequirements(t, router, tc.method, tc.path)
+			require.Equal(t, http.StatusPaymentRequired, status)
+			assert.Equal(t, tc.expectedAmount, requirements.MaxAmountRequired, "%s %s", tc.method, tc.path)
+			if tc.expectedAsset != "" {
+				assert.Equal(t, tc.expectedAsset, requirements.Asset)
+			}
+		}
+	}
+}
+
+func TestRoutesMiddleware_RoutePriceOverridesDefault(t *testing.T) {
+	router := setupRoutesTest(t, x402gin.RoutesConfig{
+		"/basic":   {Price: types.USD("$0.50")},
+		"/premium": {Price: types.USD("$2")},
+	}, x402gin.WithPrice(types.USD("$0.01")))
+
+	_, requirements := requestRequirements(t, router, "GET", "/basic")
+	assert.Equal(t, "500000", requirements.MaxAmountRequired)
+	_, requirements = requestRequirements(t, router, "GET", "/premium")
+	assert.Equal(t, "2000000", requirements.MaxAmountRequired)
+}
+
+func TestRoutesMiddleware_SharedFacilitator(t *testing.T) {
+	var supportedCalls atomic.Int32
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		supportedCalls.Add(1)
+		json.NewEncoder(w).Encode(types.SupportedPaymentKindsResponse{
+			Kinds: []types.SupportedPaymentKind{{X402Version: 1, Scheme: types.SchemeExact, Network: "base-sepolia"}},
+		})
+	}))
+	t.Cleanup(facilitatorServer.Close)
+
+	x402gin.RoutesMiddleware(x402gin.RoutesConfig{
+		"/a": {Price: types.USD("$0.01")},
+		"/b": {Price: types.USD("$0.02")},
+		"/c": {Price: types.USD("$0.03")},
+	}, middlewaretest.PayTo,
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithSupportedCheck(x402gin.SupportedCheckRequire),
+	)
+
+	// The supported payment kinds are checked once for all the routes
+	assert.Equal(t, int32(1), supportedCalls.Load())
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// DiscoveryPath is the path the catalog of paid resources is served at, following the contract of
+// the facilitators' discovery endpoint
+const DiscoveryPath = "/discovery/resources"
+
+// Default and maximum number of resources listed per page
+const (
+	DefaultDiscoveryLimit = 20
+	MaxDiscoveryLimit     = 100
+)
+
+// MaxCatalogResources is the maximum number of resources of a catalog, further resources are not
+// listed
+const MaxCatalogResources = 1000
+
+// Catalog lists the paid resources of the middlewares it is given to with WithCatalog, for agents
+// to discover them. It is an http.Handler serving the resources at DiscoveryPath.
+type Catalog struct {
+	mu        sync.RWMutex
+	resources map[string]types.DiscoveredResource
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{resources: map[string]types.DiscoveredResource{}}
+}
+
+// Register adds a resource to the catalog, replacing the resource of the same URL. It reports
+// whether the resource was added, which it is not once the catalog has MaxCatalogResources.
+func (c *Catalog) Register(resource types.DiscoveredResource) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.resources[resource.Resource]; !ok && len(c.resources) >= MaxCatalogResources {
+		return false
+	}
+	c.resources[resource.Resource] = resource
+	return true
+}
+
+// contains reports whether the catalog lists the resource of url
+func (c *Catalog) contains(url string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.resources[url]
+	return ok
+}
+
+// List returns the page of the resources of the catalog selected by req, ordered by URL
+func (c *Catalog) List(req types.ListDiscoveryResourcesRequest) *types.ListDiscoveryResourcesResponse {
+	c.mu.RLock()
+	resources := make([]types.DiscoveredResource, 0, len(c.resources))
+	for _, resource := range c.resources {
+		if req.Type == "" || resource.Type == req.Type {
+			resources = append(resources, resource)
+		}
+	}
+	c.mu.RUnlock()
+
+	slices.SortFunc(resources, func(a, b types.DiscoveredResource) int {
+		return strings.Compare(a.Resource, b.Resource)
+	})
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultDiscoveryLimit
+	}
+	limit = min(limit, MaxDiscoveryLimit)
+	offset := min(max(req.Offset, 0), len(resources))
+	end := min(offset+limit, len(resources))
+
+	return &types.ListDiscoveryResourcesResponse{
+		X402Version: x402Version,
+		Items:       resources[offset:end],
+		Pagination: types.DiscoveryPagination{
+			Limit:  limit,
+			Offset: offset,
+			Total:  len(resources),
+		},
+	}
+}
+
+// ServeHTTP lists the resources of the catalog, filtered and paginated by the type, limit and
+// offset query parameters
+func (c *Catalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := listRequest(r.URL.Query())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse(http.StatusBadRequest, err).Body)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.List(req))
+}
+
+// listRequest parses the query parameters of a discovery request
+func listRequest(query url.Values) (types.ListDiscoveryResourcesRequest, error) {
+	req := types.ListDiscoveryResourcesRequest{Type: query.Get("type")}
+
+	var err error
+	if limit := query.Get("limit"); limit != "" {
+		if req.Limit, err = strconv.Atoi(limit); err != nil {
+			return req, &types.ValidationError{Field: "limit", Reason: "must be an integer"}
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		if req.Offset, err = strconv.Atoi(offset); err != nil {
+			return req, &types.ValidationError{Field: "offset", Reason: "must be an integer"}
+		}
+	}
+
+	return req, nil
+}
+
+// register adds the resource of the payment requirements to the catalog of the options, if any and
+// not listed yet. Resources depending on the request are registered once a payment for them is
+// verified, so that unpaid requests can't add arbitrary paths to the catalog.
+func (e *Engine) register(accepts []*types.PaymentRequirements) {
+	if e.options.Catalog == nil || len(accepts) == 0 || accepts[0].Resource == "" ||
+		e.options.Catalog.contains(accepts[0].Resource) {
+		return
+	}
+
+	resource := types.DiscoveredResource{
+		Resource:    accepts[0].Resource,
+		Type:        types.ResourceTypeHTTP,
+		X402Version: x402Version,
+		LastUpdated: time.Now().UTC(),
+		Metadata:    e.options.DiscoveryMetadata,
+	}
+	for _, requirements := range accepts {
+		resource.Accepts = append(resource.Accepts, *requirements)
+	}
+	e.options.Catalog.Register(resource)
+}
+
+// registerStatic registers the resource when the engine is created, when it does not depend on the
+// requests
+func (e *Engine) registerStatic() {
+	if e.options.Catalog == nil || e.options.Resource == "" || e.options.PriceFunc != nil {
+		return
+	}
+
+	accepts, err := e.requirements(&http.Request{URL: &url.URL{}})
+	if err != nil {
+		return
+	}
+	e.register(accepts)
+}
@@ -0,0 +1,474 @@
+// Package middleware is the framework agnostic core of the x402 payment middlewares.
+// The framework adapters (gin, x402http, ...) only translate between their request and
+// response types and the Engine, so that payments behave identically across frameworks.
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/money"
+	// Registers the Solana networks and the exact SVM scheme
+	_ "github.com/coinbase/x402/go/pkg/svm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+const x402Version = types.X402Version
+
+const (
+	// PaymentHeader is the request header carrying the base64 encoded payment payload
+	PaymentHeader = "X-PAYMENT"
+	// PaymentResponseHeader is the response header carrying the base64 encoded settlement response
+	PaymentResponseHeader = "X-PAYMENT-RESPONSE"
+)
+
+// Engine gates requests behind an x402 payment of a fixed price
+type Engine struct {
+	price       types.Price
+	payTo       string
+	options     *PaymentMiddlewareOptions
+	facilitator facilitatorclient.Facilitator
+}
+
+// Payment is a verified payment awaiting settlement
+type Payment struct {
+	Payload *types.PaymentPayload
+	// Requirements are the payment requirements matched by the payload
+	Requirements *types.PaymentRequirements
+	// Accepts are all the payment requirements accepted for the request
+	Accepts []*types.PaymentRequirements
+
+	mu sync.Mutex
+	// usage is the amount used by an upto payment, see ReportUsage
+	usage *big.Int
+	// settling is set once the payment is being settled, when usage can no longer be reported
+	settling bool
+	// receiptID is the receipt of the payment held in the settlement queue, if any
+	receiptID string
+	// facilitator is the URL of the pool member that verified the payment, if any, see
+	// facilitatorclient.WithPoolMember
+	facilitator string
+}
+
+// Response is a response the adapter must write instead of serving the request
+type Response struct {
+	StatusCode int
+	// HTML is the paywall page served to web browsers, when set it takes precedence over Body
+	HTML string
+	// Body is the JSON response body
+	Body map[string]any
+}
+
+// NewEngine creates an engine charging price to payTo, unless overridden by the options.
+// It panics when checking the supported payment kinds with SupportedCheckRequire fails, see
+// NewEngineContext.
+func NewEngine(price types.Price, payTo string, options *PaymentMiddlewareOptions) *Engine {
+	engine, err := NewEngineContext(context.Background(), price, payTo, options)
+	if err != nil {
+		panic(err)
+	}
+	return engine
+}
+
+// NewEngineContext creates an engine like NewEngine, but returns the error of checking the supported
+// payment kinds with SupportedCheckRequire, a check bounded by ctx and the facilitator timeout.
+func NewEngineContext(ctx context.Context, price types.Price, payTo string, options *PaymentMiddlewareOptions) (*Engine, error) {
+	if options.Price != nil {
+		price = *options.Price
+	}
+
+	engine := &Engine{
+		price:       price,
+		payTo:       payTo,
+		options:     options,
+		facilitator: NewFacilitator(options),
+	}
+	if err := engine.checkSupported(ctx); err != nil {
+		return nil, err
+	}
+	engine.registerStatic()
+
+	return engine, nil
+}
+
+// NewFacilitator returns the facilitator the engines of options verify and settle payments with:
+// the facilitator of the options, or a pool or client of their facilitator configs. The supported
+// payment kinds are cached when they are checked. Engines sharing options can share a facilitator
+// by setting it as the Facilitator of the options.
+func NewFacilitator(options *PaymentMiddlewareOptions) facilitatorclient.Facilitator {
+	facilitator := options.Facilitator
+	if facilitator == nil {
+		facilitator = newFacilitatorClient(options)
+	}
+	if options.SupportedCheck != SupportedCheckNone {
+		// The supported payment kinds are needed by each request, or by each engine created
+		facilitator = facilitatorclient.NewCachingFacilitator(facilitator, facilitatorclient.DefaultSupportedCacheTTL)
+	}
+	return facilitator
+}
+
+// newFacilitatorClient returns a pool or client of the facilitator configs of the options
+func newFacilitatorClient(options *PaymentMiddlewareOptions) facilitatorclient.Facilitator {
+	var clientOptions []facilitatorclient.ClientOption
+	if options.FacilitatorTimeout > 0 {
+		clientOptions = append(clientOptions, facilitatorclient.WithTimeout(options.FacilitatorTimeout))
+	}
+	clientOptions = append(clientOptions, options.FacilitatorClientOptions...)
+
+	if options.FacilitatorPool != nil {
+		return facilitatorclient.NewPool(*options.FacilitatorPool, clientOptions...)
+	}
+	return facilitatorclient.NewFacilitatorClient(options.FacilitatorConfig, clientOptions...)
+}
+
+// Verify builds the payment requirements of the request and verifies its payment.
+// It returns the verified payment, or the response to write if the request must not be served.
+func (e *Engine) Verify(r *http.Request) (*Payment, *Response) {
+	e.log(r.Context(), slog.LevelDebug, "checking payment", "url", r.URL.String())
+
+	accepts, err := e.requirements(r)
+	if err != nil {
+		e.log(r.Context(), slog.LevelError, "failed to build payment requirements", "error", err)
+		return nil, ErrorResponse(http.StatusInternalServerError, err)
+	}
+	accepts, err = e.filterSupported(r.Context(), accepts)
+	if err != nil {
+		e.log(r.Context(), slog.LevelError, "failed to filter payment requirements", "error", err)
+		return nil, ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	paymentPayload, err := types.DecodePaymentPayloadFromBase64(r.Header.Get(PaymentHeader))
+	var versionErr *types.UnsupportedVersionError
+	var schemeErr *types.UnsupportedSchemeError
+	if errors.As(err, &versionErr) || errors.As(err, &schemeErr) {
+		e.log(r.Context(), slog.LevelDebug, "unsupported payment", "error", err)
+		return nil, paymentRequiredResponse(err.Error(), accepts)
+	}
+	if err != nil {
+		if isWebBrowser(r) {
+			html := e.options.CustomPaywallHTML
+			if html == "" {
+				html = getPaywallHtml(e.options)
+			}
+			return nil, &Response{StatusCode: http.StatusPaymentRequired, HTML: html}
+		}
+
+		return nil, paymentRequiredResponse("X-PAYMENT header is required", accepts)
+	}
+	if err := paymentPayload.Validate(); err != nil {
+		e.log(r.Context(), slog.LevelDebug, "invalid payment payload", "error", err)
+		return nil, paymentRequiredResponse(err.Error(), accepts)
+	}
+
+	paymentRequirements := findMatchingRequirements(accepts, paymentPayload)
+	if paymentRequirements == nil {
+		e.log(r.Context(), slog.LevelDebug, "no matching payment requirements", "scheme", paymentPayload.Scheme, "network", paymentPayload.Network)
+		return nil, paymentRequiredResponse("Unable to find matching payment requirements", accepts)
+	}
+
+	// Verify payment, recording the pool member that verified it so that it settles it
+	var facilitator string
+	ctx := facilitatorclient.WithPoolMember(r.Context(), &facilitator)
+	response, err := e.facilitator.VerifyContext(ctx, paymentPayload, paymentRequirements)
+	if err != nil {
+		e.log(r.Context(), slog.LevelError, "failed to verify payment", "error", err)
+		return nil, facilitatorErrorResponse(err)
+	}
+
+	if err := response.Err(); err != nil {
+		e.log(r.Context(), slog.LevelDebug, "invalid payment", "reason", response.Reason())
+		return nil, invalidPaymentResponse(response, accepts)
+	}
+
+	e.log(r.Context(), slog.LevelDebug, "payment verified", "network", paymentRequirements.Network)
+	e.register(accepts)
+
+	return &Payment{
+		Payload:      paymentPayload,
+		Requirements: paymentRequirements,
+		Accepts:      accepts,
+		facilitator:  facilitator,
+	}, nil
+}
+
+// Settle settles a verified payment, with the values of the request context ctx. The handler may
+// already have run, so the settlement is not canceled with ctx and is only bounded by the
+// facilitator timeout.
+// It returns the X-PAYMENT-RESPONSE header value, or the response to write instead of the handler's,
+// which includes a settlement failing before the handler runs, see SettleBeforeHandler.
+// The header value is empty when an upto payment has no usage to charge.
+func (e *Engine) Settle(ctx context.Context, payment *Payment) (string, *Response) {
+	requirements := payment.settlementRequirements()
+	if requirements == nil {
+		e.log(ctx, slog.LevelDebug, "no usage reported, skipping settlement")
+		return "", nil
+	}
+
+	settleCtx := facilitatorclient.WithPoolMember(context.WithoutCancel(ctx), &payment.facilitator)
+	settleResponse, err := e.facilitator.SettleContext(settleCtx, payment.Payload, requirements)
+	if err != nil {
+		e.log(ctx, slog.LevelError, "failed to settle payment", "error", err)
+		return "", paymentRequiredResponse(err.Error(), payment.Accepts)
+	}
+	if err := settleResponse.Err(); err != nil {
+		e.log(ctx, slog.LevelWarn, "settlement failed", "reason", settleResponse.Reason())
+		// Once the handler has run, a failed settlement is reported in the X-PAYMENT-RESPONSE header
+		if e.options.SettlementMode == SettleBeforeHandler {
+			return "", paymentRequiredResponse(string(settleResponse.Reason()), payment.Accepts)
+		}
+	}
+
+	settleResponseHeader, err := settleResponse.EncodeToBase64String()
+	if err != nil {
+		e.log(ctx, slog.LevelError, "failed to encode settlement response", "error", err)
+		return "", ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return settleResponseHeader, nil
+}
+
+// requirements builds the payment requirements accepted for the request, one per payment option.
+// The requirements are validated, so that invalid options are not sent to the facilitator.
+func (e *Engine) requirements(r *http.Request) ([]*types.PaymentRequirements, error) {
+	resource := e.options.Resource
+	if resource == "" {
+		rootURL := e.options.ResourceRootURL
+		if rootURL == "" {
+			rootURL = requestRootURL(r)
+		}
+		resource = rootURL + r.URL.Path
+	}
+
+	basePrice := e.price
+	if e.options.PriceFunc != nil {
+		price, err := e.options.PriceFunc(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute price: %w", err)
+		}
+		basePrice = price
+	}
+
+	paymentOptions := e.options.PaymentOptions
+	if len(paymentOptions) == 0 {
+		paymentOptions = []PaymentOption{{Network: e.options.NetworkName()}}
+	}
+
+	accepts := make([]*types.PaymentRequirements, 0, len(paymentOptions))
+	for _, option := range paymentOptions {
+		paymentRequirements := &types.PaymentRequirements{
+			Scheme:            types.SchemeExact,
+			Resource:          resource,
+			Description:       e.options.Description,
+			MimeType:          e.options.MimeType,
+			PayTo:             e.payTo,
+			MaxTimeoutSeconds: e.options.MaxTimeoutSeconds,
+			OutputSchema:      e.options.OutputSchema,
+		}
+
+		price := basePrice
+		if option.Price != nil {
+			price = *option.Price
+		}
+		if err := paymentRequirements.SetPrice(price, option.Network); err != nil {
+			return nil, err
+		}
+		if option.PayTo != "" {
+			paymentRequirements.PayTo = option.PayTo
+		}
+		if err := mergeExtra(paymentRequirements, option.Extra); err != nil {
+			return nil, err
+		}
+		if e.scheme(option.Network) == types.SchemeUpto {
+			if err := setUpto(paymentRequirements, e.options.UptoSpender); err != nil {
+				return nil, err
+			}
+		}
+		if err := paymentRequirements.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid payment requirements: %w", err)
+		}
+
+		accepts = append(accepts, paymentRequirements)
+	}
+
+	return accepts, nil
+}
+
+// requestRootURL returns the scheme and host the request was sent to
+func requestRootURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// mergeExtra adds values to the extra information of the payment requirements
+func mergeExtra(requirements *types.PaymentRequirements, values map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	extra := map[string]any{}
+	if requirements.Extra != nil {
+		if err := json.Unmarshal(*requirements.Extra, &extra); err != nil {
+			return fmt.Errorf("failed to unmarshal extra: %w", err)
+		}
+	}
+	for key, value := range values {
+		extra[key] = value
+	}
+
+	extraBytes, err := json.Marshal(extra)
+	if err != nil {
+		return fmt.Errorf("failed to marshal extra: %w", err)
+	}
+	rawMessage := json.RawMessage(extraBytes)
+	requirements.Extra = &rawMessage
+	return nil
+}
+
+// findMatchingRequirements returns the accepted payment requirements matching the payload's
+// scheme and network, preferring the ones paid to the payload's recipient, or nil
+func findMatchingRequirements(accepts []*types.PaymentRequirements, payload *types.PaymentPayload) *types.PaymentRequirements {
+	var match *types.PaymentRequirements
+	for _, requirements := range accepts {
+		if requirements.Scheme != payload.Scheme || requirements.Network != payload.Network {
+			continue
+		}
+		if payload.Payload != nil && payload.Payload.Authorization != nil &&
+			strings.EqualFold(payload.Payload.Authorization.To, requirements.PayTo) {
+			return requirements
+		}
+		if match == nil {
+			match = requirements
+		}
+	}
+
+	return match
+}
+
+// log logs a message with the logger of the options, if any
+func (e *Engine) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if e.options.Logger != nil {
+		e.options.Logger.Log(ctx, level, msg, args...)
+	}
+}
+
+// WriteResponse writes a response of the engine to a net/http response writer
+func WriteResponse(w http.ResponseWriter, response *Response) {
+	if response.HTML != "" {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(response.StatusCode)
+		w.Write([]byte(response.HTML))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+	json.NewEncoder(w).Encode(response.Body)
+}
+
+// ResponseBuffer captures the response of the protected handler so that it is only
+// sent once the payment is settled
+type ResponseBuffer struct {
+	StatusCode int
+	Body       bytes.Buffer
+	written    bool
+}
+
+// NewResponseBuffer creates an empty response buffer
+func NewResponseBuffer() *ResponseBuffer {
+	return &ResponseBuffer{StatusCode: http.StatusOK}
+}
+
+// WriteHeader records the first status code written
+func (b *ResponseBuffer) WriteHeader(code int) {
+	if !b.written {
+		b.StatusCode = code
+		b.written = true
+	}
+}
+
+// Write buffers the body
+func (b *ResponseBuffer) Write(p []byte) (int, error) {
+	if !b.written {
+		b.WriteHeader(http.StatusOK)
+	}
+	return b.Body.Write(p)
+}
+
+// USDAmount returns the price of a decimal USD amount (ex: 0.01 for 1 cent), rounded to
+// the precision of USDC
+func USDAmount(amount *big.Float) types.Price {
+	return types.USD(amount.Text('f', money.USDCDecimals))
+}
+
+// isWebBrowser reports whether the request comes from a web browser expecting an HTML paywall
+func isWebBrowser(r *http.Request) bool {
+	userAgent := r.Header.Get("User-Agent")
+	acceptHeader := r.Header.Get("Accept")
+	return strings.Contains(acceptHeader, "text/html") && strings.Contains(userAgent, "Mozilla")
+}
+
+// paymentRequiredResponse is a 402 response advertising the accepted payment requirements
+func paymentRequiredResponse(reason any, accepts []*types.PaymentRequirements) *Response {
+	return &Response{
+		StatusCode: http.StatusPaymentRequired,
+		Body: map[string]any{
+			"error":       reason,
+			"accepts":     accepts,
+			"x402Version": x402Version,
+		},
+	}
+}
+
+// invalidPaymentResponse is a 402 response with the reason the facilitator found the payment
+// invalid, and the payer if known
+func invalidPaymentResponse(response *types.VerifyResponse, accepts []*types.PaymentRequirements) *Response {
+	resp := paymentRequiredResponse(string(response.Reason()), accepts)
+	if response.Payer != nil && *response.Payer != "" {
+		resp.Body["payer"] = *response.Payer
+	}
+	return resp
+}
+
+// facilitatorErrorResponse is the error response of a failed facilitator call: a gateway timeout
+// when the facilitator did not answer in time, a bad gateway when it could not be reached and
+// service unavailable while its circuit breaker is open
+func facilitatorErrorResponse(err error) *Response {
+	switch {
+	case errors.Is(err, facilitatorclient.ErrCircuitOpen):
+		return ErrorResponse(http.StatusServiceUnavailable, err)
+	case errors.Is(err, facilitatorclient.ErrTimeout):
+		return ErrorResponse(http.StatusGatewayTimeout, err)
+	case errors.Is(err, facilitatorclient.ErrNetwork):
+		return ErrorResponse(http.StatusBadGateway, err)
+	default:
+		return ErrorResponse(http.StatusInternalServerError, err)
+	}
+}
+
+// ErrorResponse is an error response without payment requirements
+func ErrorResponse(statusCode int, err error) *Response {
+	return &Response{
+		StatusCode: statusCode,
+		Body: map[string]any{
+			"error":       err.Error(),
+			"x402Version": x402Version,
+		},
+	}
+}
+
+// getPaywallHtml is the default paywall HTML for the PaymentMiddleware.
+func getPaywallHtml(_ *PaymentMiddlewareOptions) string {
+	return "<html><body>Payment Required</body></html>"
+}
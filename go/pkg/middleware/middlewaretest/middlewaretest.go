@@ -0,0 +1,513 @@
+// Package middlewaretest provides the test suite shared by the framework adapters of the
+// payment middleware, so that they are all held to the same behavior.
+package middlewaretest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/middleware"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// PayTo is the address the suite's middlewares are paid to
+const PayTo = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+
+// Payer is the payer reported by the suite's facilitator
+const Payer = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+
+// Server serves a request and returns its response
+type Server func(req *http.Request) *http.Response
+
+// NewServer creates the server under test. It must protect GET /protected with the adapter's
+// payment middleware built from amount, payTo and opts, behind a handler responding
+// 201 Created with the body "success".
+type NewServer func(t *testing.T, amount *big.Float, payTo string, opts ...middleware.Options) Server
+
+// ServeHandler adapts an http.Handler to a Server
+func ServeHandler(handler http.Handler) Server {
+	return func(req *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Result()
+	}
+}
+
+// NewPaymentPayload returns a payment payload accepted by the suite's facilitator
+func NewPaymentPayload() *types.PaymentPayload {
+	return &types.PaymentPayload{
+		X402Version: 1,
+		Scheme:      "exact",
+		Network:     "base-sepolia",
+		Payload: &types.ExactEvmPayload{
+			Signature: "0x2d6a7588d6acca505cbf0d9a4a227e0c52c6c34008c8e8986a1283259764173608a2ce6496642e377d6da8dbbf5836e9bd15092f9ecab05ded3d6293af148b571c",
+			Authorization: &types.ExactEvmPayloadAuthorization{
+				From:        Payer,
+				To:          PayTo,
+				Value:       "1000000",
+				ValidAfter:  "1745323800",
+				ValidBefore: "1745323985",
+				Nonce:       "0xf3746613c2d920b5fdabc0856f2aeb2d4f88ee6037b8cc5d04a71a4462f13480",
+			},
+		},
+	}
+}
+
+// NewFacilitatorServer creates a facilitator answering /verify and /settle with the given results
+func NewFacilitatorServer(t *testing.T, verifyStatus int, isValid bool, settleStatus int, settled bool) *httptest.Server {
+	t.Helper()
+
+	payer := Payer
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/verify":
+			var req struct {
+				PaymentPayload      *types.PaymentPayload      `json:"paymentPayload"`
+				PaymentRequirements *types.PaymentRequirements `json:"paymentRequirements"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+
+			// Reject requirements not matching the payload, which the middleware must not send
+			reason := types.ErrorReasonInsufficientFunds
+			valid := isValid
+			if req.PaymentPayload == nil || req.PaymentRequirements == nil ||
+				req.PaymentPayload.Network != req.PaymentRequirements.Network ||
+				!strings.EqualFold(req.PaymentPayload.Payload.Authorization.To, req.PaymentRequirements.PayTo) {
+				reason = types.ErrorReasonInvalidPaymentRequirements
+				valid = false
+			}
+
+			w.WriteHeader(verifyStatus)
+			json.NewEncoder(w).Encode(types.VerifyResponse{
+				IsValid:       valid,
+				InvalidReason: types.ReasonPtr(reason),
+				Payer:         &payer,
+			})
+		case "/settle":
+			w.WriteHeader(settleStatus)
+			json.NewEncoder(w).Encode(types.SettleResponse{
+				Success:     settled,
+				Transaction: "0xtesthash",
+				Network:     "base-sepolia",
+				Payer:       &payer,
+			})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Run runs the payment middleware test suite against the servers created by newServer
+func Run(t *testing.T, newServer NewServer) {
+	paymentPayloadJson, err := json.Marshal(NewPaymentPayload())
+	require.NoError(t, err)
+	payment := base64.StdEncoding.EncodeToString(paymentPayloadJson)
+
+	unsupportedPayload := NewPaymentPayload()
+	unsupportedPayload.X402Version = 2
+	unsupportedPayloadJson, err := json.Marshal(unsupportedPayload)
+	require.NoError(t, err)
+	unsupportedPayment := base64.StdEncoding.EncodeToString(unsupportedPayloadJson)
+
+	malformedPayload := NewPaymentPayload()
+	malformedPayload.Payload.Authorization.Nonce = "0x1234"
+	malformedPayloadJson, err := json.Marshal(malformedPayload)
+	require.NoError(t, err)
+	malformedPayment := base64.StdEncoding.EncodeToString(malformedPayloadJson)
+
+	testCases := []struct {
+		name         string
+		headers      map[string]string
+		opts         []middleware.Options
+		verifyStatus int
+		isValid      bool
+		settleStatus int
+		settled      bool
+
+		expectedStatus      int
+		expectedBody        string
+		expectedError       string
+		expectedNetworks    []string
+		expectedSchemes     []string
+		expectedAmount      string
+		expectedAsset       string
+		expectedResource    string
+		expectedPayer       string
+		expectSettleSuccess *bool
+	}{
+		{
+			name:             "no payment header",
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "X-PAYMENT header is required",
+			expectedNetworks: []string{"base-sepolia"},
+			expectedAmount:   "1000000",
+			expectedAsset:    "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			expectedResource: "http://example.com/protected",
+		},
+		{
+			name:             "resource root URL",
+			opts:             []middleware.Options{middleware.WithResourceRootURL("https://api.example.com")},
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "X-PAYMENT header is required",
+			expectedNetworks: []string{"base-sepolia"},
+			expectedResource: "https://api.example.com/protected",
+		},
+		{
+			name: "invalid payment requirements",
+			opts: []middleware.Options{middleware.WithPaymentOptions(
+				middleware.PaymentOption{Network: "base-sepolia", PayTo: "0xTestAddress"},
+			)},
+			headers:        map[string]string{"X-PAYMENT": payment},
+			verifyStatus:   http.StatusOK,
+			isValid:        true,
+			settleStatus:   http.StatusOK,
+			settled:        true,
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  `invalid payment requirements: invalid payTo: "0xTestAddress" is not an EVM address`,
+		},
+		{
+			name: "token price",
+			opts: []middleware.Options{middleware.WithPrice(types.TokenAmount("2500", types.ERC20Asset{
+				Address:  "0x08210F9170F89Ab7658F0B5E3fF39b0E03C594D4",
+				Decimals: 6,
+				EIP712:   types.EIP712Domain{Name: "EURC", Version: "2"},
+			}))},
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "X-PAYMENT header is required",
+			expectedNetworks: []string{"base-sepolia"},
+			expectedAmount:   "2500",
+			expectedAsset:    "0x08210F9170F89Ab7658F0B5E3fF39b0E03C594D4",
+		},
+		{
+			name:             "no payment header on mainnet",
+			opts:             []middleware.Options{middleware.WithTestnet(false)},
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "X-PAYMENT header is required",
+			expectedNetworks: []string{"base"},
+		},
+		{
+			name:             "no payment header on avalanche",
+			opts:             []middleware.Options{middleware.WithNetwork("avalanche")},
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "X-PAYMENT header is required",
+			expectedNetworks: []string{"avalanche"},
+		},
+		{
+			name: "multiple payment options",
+			opts: []middleware.Options{middleware.WithPaymentOptions(
+				middleware.PaymentOption{Network: "base"},
+				middleware.PaymentOption{Network: "base-sepolia", PayTo: "0x857b06519E91e3A54538791bDbb0E22373e36b66"},
+				middleware.PaymentOption{Network: "base-sepolia"},
+			)},
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "X-PAYMENT header is required",
+			expectedNetworks: []string{"base", "base-sepolia", "base-sepolia"},
+		},
+		{
+			name: "solana payment option",
+			opts: []middleware.Options{middleware.WithPaymentOptions(middleware.PaymentOption{
+				Network: "solana-devnet",
+				PayTo:   "2wKupLR9q6wXYppw8Gr2NvWxKBUqm4PPJKkQfoxHDBg4",
+				Extra:   map[string]any{"feePayer": "EwWqGE4ZFKLofuestmU4LDdK7XM1N4ALgdZccwYugwGd"},
+			})},
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "X-PAYMENT header is required",
+			expectedNetworks: []string{"solana-devnet"},
+			expectedAmount:   "1000000",
+			expectedAsset:    "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU",
+		},
+		{
+			name: "upto with solana payment option",
+			opts: []middleware.Options{
+				middleware.WithUpto("0x857b06519E91e3A54538791bDbb0E22373e36b66"),
+				middleware.WithPaymentOptions(
+					middleware.PaymentOption{Network: "base-sepolia"},
+					middleware.PaymentOption{
+						Network: "solana-devnet",
+						PayTo:   "2wKupLR9q6wXYppw8Gr2NvWxKBUqm4PPJKkQfoxHDBg4",
+						Extra:   map[string]any{"feePayer": "EwWqGE4ZFKLofuestmU4LDdK7XM1N4ALgdZccwYugwGd"},
+					},
+				),
+			},
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "X-PAYMENT header is required",
+			expectedNetworks: []string{"base-sepolia", "solana-devnet"},
+			expectedSchemes:  []string{"upto", "exact"},
+		},
+		{
+			name:    "payment matching one of multiple payment options",
+			headers: map[string]string{"X-PAYMENT": payment},
+			opts: []middleware.Options{middleware.WithPaymentOptions(
+				middleware.PaymentOption{Network: "avalanche"},
+				middleware.PaymentOption{Network: "base-sepolia", PayTo: "0x857b06519E91e3A54538791bDbb0E22373e36b66"},
+				middleware.PaymentOption{Network: "base-sepolia"},
+			)},
+			verifyStatus:        http.StatusOK,
+			isValid:             true,
+			settleStatus:        http.StatusOK,
+			settled:             true,
+			expectedStatus:      http.StatusCreated,
+			expectedBody:        "success",
+			expectSettleSuccess: boolPtr(true),
+		},
+		{
+			name:             "payment not matching any payment option",
+			headers:          map[string]string{"X-PAYMENT": payment},
+			opts:             []middleware.Options{middleware.WithPaymentOptions(middleware.PaymentOption{Network: "base"})},
+			verifyStatus:     http.StatusOK,
+			isValid:          true,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "Unable to find matching payment requirements",
+			expectedNetworks: []string{"base"},
+		},
+		{
+			name:             "unsupported x402 version",
+			headers:          map[string]string{"X-PAYMENT": unsupportedPayment},
+			verifyStatus:     http.StatusOK,
+			isValid:          true,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "unsupported x402Version: 2",
+			expectedNetworks: []string{"base-sepolia"},
+		},
+		{
+			name:             "malformed payment payload",
+			headers:          map[string]string{"X-PAYMENT": malformedPayment},
+			verifyStatus:     http.StatusOK,
+			isValid:          true,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "invalid authorization.nonce: must be 32 hex encoded bytes",
+			expectedNetworks: []string{"base-sepolia"},
+		},
+		{
+			name:           "unsupported network",
+			opts:           []middleware.Options{middleware.WithNetwork("unknown")},
+			verifyStatus:   http.StatusOK,
+			settleStatus:   http.StatusOK,
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  "unsupported network: unknown",
+		},
+		{
+			name:           "web browser request",
+			headers:        map[string]string{"Accept": "text/html", "User-Agent": "Mozilla/5.0"},
+			opts:           []middleware.Options{middleware.WithCustomPaywallHTML("<html><body>Custom Paywall</body></html>")},
+			verifyStatus:   http.StatusOK,
+			settleStatus:   http.StatusOK,
+			expectedStatus: http.StatusPaymentRequired,
+			expectedBody:   "Custom Paywall",
+		},
+		{
+			name:    "valid payment",
+			headers: map[string]string{"X-PAYMENT": payment},
+			opts: []middleware.Options{
+				middleware.WithDescription("Test Description"),
+				middleware.WithMimeType("application/json"),
+				middleware.WithMaxTimeoutSeconds(120),
+				middleware.WithResource("https://example.com/protected"),
+			},
+			verifyStatus:        http.StatusOK,
+			isValid:             true,
+			settleStatus:        http.StatusOK,
+			settled:             true,
+			expectedStatus:      http.StatusCreated,
+			expectedBody:        "success",
+			expectSettleSuccess: boolPtr(true),
+		},
+		{
+			name:             "verification fails",
+			headers:          map[string]string{"X-PAYMENT": payment},
+			verifyStatus:     http.StatusOK,
+			settleStatus:     http.StatusOK,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "insufficient_funds",
+			expectedPayer:    Payer,
+			expectedNetworks: []string{"base-sepolia"},
+		},
+		{
+			name:           "verification server error",
+			headers:        map[string]string{"X-PAYMENT": payment},
+			verifyStatus:   http.StatusInternalServerError,
+			settleStatus:   http.StatusOK,
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  "failed to verify payment: 500 Internal Server Error",
+		},
+		{
+			name:                "settlement fails",
+			headers:             map[string]string{"X-PAYMENT": payment},
+			verifyStatus:        http.StatusOK,
+			isValid:             true,
+			settleStatus:        http.StatusOK,
+			expectedStatus:      http.StatusCreated,
+			expectedBody:        "success",
+			expectSettleSuccess: boolPtr(false),
+		},
+		{
+			name:             "settlement server error",
+			headers:          map[string]string{"X-PAYMENT": payment},
+			verifyStatus:     http.StatusOK,
+			isValid:          true,
+			settleStatus:     http.StatusInternalServerError,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "failed to settle payment: 500 Internal Server Error",
+			expectedNetworks: []string{"base-sepolia"},
+		},
+		{
+			name:                "settle before handler",
+			headers:             map[string]string{"X-PAYMENT": payment},
+			opts:                []middleware.Options{middleware.WithSettlementMode(middleware.SettleBeforeHandler)},
+			verifyStatus:        http.StatusOK,
+			isValid:             true,
+			settleStatus:        http.StatusOK,
+			settled:             true,
+			expectedStatus:      http.StatusCreated,
+			expectedBody:        "success",
+			expectSettleSuccess: boolPtr(true),
+		},
+		{
+			name:             "settle before handler server error",
+			headers:          map[string]string{"X-PAYMENT": payment},
+			opts:             []middleware.Options{middleware.WithSettlementMode(middleware.SettleBeforeHandler)},
+			verifyStatus:     http.StatusOK,
+			isValid:          true,
+			settleStatus:     http.StatusInternalServerError,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "failed to settle payment: 500 Internal Server Error",
+			expectedNetworks: []string{"base-sepolia"},
+		},
+		{
+			name:             "settle before handler failed",
+			headers:          map[string]string{"X-PAYMENT": payment},
+			opts:             []middleware.Options{middleware.WithSettlementMode(middleware.SettleBeforeHandler)},
+			verifyStatus:     http.StatusOK,
+			isValid:          true,
+			settleStatus:     http.StatusOK,
+			settled:          false,
+			expectedStatus:   http.StatusPaymentRequired,
+			expectedError:    "unexpected_settle_error",
+			expectedNetworks: []string{"base-sepolia"},
+		},
+		{
+			name:           "settle async failed",
+			headers:        map[string]string{"X-PAYMENT": payment},
+			opts:           []middleware.Options{middleware.WithSettlementMode(middleware.SettleAsync)},
+			verifyStatus:   http.StatusOK,
+			isValid:        true,
+			settleStatus:   http.StatusOK,
+			settled:        false,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   "success",
+		},
+		{
+			name:           "settle async server error",
+			headers:        map[string]string{"X-PAYMENT": payment},
+			opts:           []middleware.Options{middleware.WithSettlementMode(middleware.SettleAsync)},
+			verifyStatus:   http.StatusOK,
+			isValid:        true,
+			settleStatus:   http.StatusInternalServerError,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   "success",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			facilitatorServer := NewFacilitatorServer(t, tc.verifyStatus, tc.isValid, tc.settleStatus, tc.settled)
+
+			opts := append([]middleware.Options{middleware.WithFacilitatorConfig(&types.FacilitatorConfig{
+				URL: facilitatorServer.URL,
+			})}, tc.opts...)
+			server := newServer(t, big.NewFloat(1.0), PayTo, opts...)
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			resp := server(req)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			if tc.expectedBody != "" {
+				assert.Contains(t, string(body), tc.expectedBody)
+			}
+
+			if tc.expectedError != "" {
+				var response map[string]any
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.expectedError, response["error"])
+				assert.EqualValues(t, 1, response["x402Version"])
+				if tc.expectedPayer != "" {
+					assert.Equal(t, tc.expectedPayer, response["payer"])
+				}
+
+				if tc.expectedNetworks != nil {
+					accepts, ok := response["accepts"].([]any)
+					require.True(t, ok)
+					require.Len(t, accepts, len(tc.expectedNetworks))
+					for i, network := range tc.expectedNetworks {
+						assert.Equal(t, network, accepts[i].(map[string]any)["network"])
+					}
+					for i, scheme := range tc.expectedSchemes {
+						assert.Equal(t, scheme, accepts[i].(map[string]any)["scheme"])
+					}
+					if tc.expectedAmount != "" {
+						assert.Equal(t, tc.expectedAmount, accepts[0].(map[string]any)["maxAmountRequired"])
+					}
+					if tc.expectedAsset != "" {
+						assert.Equal(t, tc.expectedAsset, accepts[0].(map[string]any)["asset"])
+					}
+					if tc.expectedResource != "" {
+						assert.Equal(t, tc.expectedResource, accepts[0].(map[string]any)["resource"])
+					}
+				} else {
+					assert.NotContains(t, response, "accepts")
+				}
+			}
+
+			if tc.expectSettleSuccess == nil {
+				assert.Empty(t, resp.Header.Get("X-PAYMENT-RESPONSE"))
+				return
+			}
+
+			settleResponse, err := types.DecodeSettleResponseFromBase64(resp.Header.Get("X-PAYMENT-RESPONSE"))
+			require.NoError(t, err)
+			assert.Equal(t, *tc.expectSettleSuccess, settleResponse.Success)
+			assert.Equal(t, "0xtesthash", settleResponse.Transaction)
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,304 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/settlement"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// PaymentMiddlewareOptions is the options for the PaymentMiddleware.
+type PaymentMiddlewareOptions struct {
+	Description       string
+	MimeType          string
+	MaxTimeoutSeconds int
+	OutputSchema      *json.RawMessage
+	FacilitatorConfig *types.FacilitatorConfig
+	// FacilitatorTimeout bounds each call to the facilitator, when set, instead of the defaults of
+	// facilitatorclient
+	FacilitatorTimeout time.Duration
+	// FacilitatorClientOptions configure the facilitator client, for example its retry policy
+	FacilitatorClientOptions []facilitatorclient.ClientOption
+	// Facilitator verifies and settles the payments instead of a client of FacilitatorConfig
+	Facilitator facilitatorclient.Facilitator
+	// FacilitatorPool lists several facilitators to fail over between, instead of FacilitatorConfig
+	FacilitatorPool *facilitatorclient.PoolConfig
+	// SupportedCheck checks the payment options against the payment kinds the facilitator supports
+	SupportedCheck SupportedCheck
+	// SettlementMode is when payments are settled relative to the handler
+	SettlementMode SettlementMode
+	// SettlementQueue settles the payments of the SettleAsync mode from a durable outbox, when set
+	SettlementQueue *settlement.Queue
+	// Catalog lists the resource for discovery, it is not listed when nil
+	Catalog *Catalog
+	// DiscoveryMetadata is the metadata of the resource in the catalog
+	DiscoveryMetadata map[string]any
+	// Network is the name of the registered network to accept payments on, see types.Networks
+	Network string
+	// Deprecated: use Network. Testnet only applies when Network is empty.
+	Testnet           bool
+	CustomPaywallHTML string
+	Resource          string
+	ResourceRootURL   string
+	// Price overrides the amount of the middleware
+	Price *types.Price
+	// PriceFunc computes the price of each request, overriding Price
+	PriceFunc PriceFunc
+	// PaymentOptions are the accepted payment options, when empty the payment is accepted on Network
+	PaymentOptions []PaymentOption
+	// Logger logs the payments handled by the middleware, nothing is logged when nil
+	Logger *slog.Logger
+	// UptoSpender is the facilitator account settling upto payments. When set, EVM payments use the
+	// upto scheme and the price is the maximum amount charged, see ReportUsage.
+	UptoSpender string
+}
+
+// PaymentOption is a way of paying for a resource, advertised in the accepts of the 402 response
+type PaymentOption struct {
+	// Network is the name of the registered network to pay on
+	Network string
+	// PayTo is the address to pay, defaults to the middleware's address
+	PayTo string
+	// Price is the price in this payment option, defaults to the middleware's price.
+	// A token price must be in a token deployed on Network.
+	Price *types.Price
+	// Extra is added to the extra information of the payment requirements, for example the
+	// "feePayer" paying the fees of Solana payments
+	Extra map[string]any
+}
+
+// Options is the type for the options for the PaymentMiddleware.
+type Options func(*PaymentMiddlewareOptions)
+
+// PriceFunc computes the price of a request
+type PriceFunc func(r *http.Request) (types.Price, error)
+
+// NewOptions returns the default options with opts applied
+func NewOptions(opts ...Options) *PaymentMiddlewareOptions {
+	options := &PaymentMiddlewareOptions{
+		FacilitatorConfig: &types.FacilitatorConfig{
+			URL: facilitatorclient.DefaultFacilitatorURL,
+		},
+		MaxTimeoutSeconds: 60,
+		Testnet:           true,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// WithDescription is an option for the PaymentMiddleware to set the description.
+func WithDescription(description string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Description = description
+	}
+}
+
+// WithMimeType is an option for the PaymentMiddleware to set the mime type.
+func WithMimeType(mimeType string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.MimeType = mimeType
+	}
+}
+
+// WithMaxDeadlineSeconds is an option for the PaymentMiddleware to set the max timeout seconds.
+func WithMaxTimeoutSeconds(maxTimeoutSeconds int) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.MaxTimeoutSeconds = maxTimeoutSeconds
+	}
+}
+
+// WithOutputSchema is an option for the PaymentMiddleware to set the output schema.
+func WithOutputSchema(outputSchema *json.RawMessage) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.OutputSchema = outputSchema
+	}
+}
+
+// WithFacilitatorConfig is an option for the PaymentMiddleware to set the facilitator config.
+func WithFacilitatorConfig(config *types.FacilitatorConfig) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.FacilitatorConfig = config
+	}
+}
+
+// WithFacilitatorTimeout is an option for the PaymentMiddleware to bound each call to the
+// facilitator, within the deadline of the request being served.
+func WithFacilitatorTimeout(timeout time.Duration) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.FacilitatorTimeout = timeout
+	}
+}
+
+// WithFacilitatorClientOptions is an option for the PaymentMiddleware to configure its facilitator
+// client, for example to retry failed calls with facilitatorclient.WithRetryPolicy or fail them
+// fast while the facilitator is down with facilitatorclient.WithCircuitBreaker.
+func WithFacilitatorClientOptions(opts ...facilitatorclient.ClientOption) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.FacilitatorClientOptions = append(options.FacilitatorClientOptions, opts...)
+	}
+}
+
+// WithFacilitator is an option for the PaymentMiddleware to verify and settle payments with f
+// instead of a client of the facilitator config, for example a local facilitator.Facilitator, a
+// fake in tests, or a facilitator client wrapped with the facilitatorclient decorators.
+func WithFacilitator(f facilitatorclient.Facilitator) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Facilitator = f
+	}
+}
+
+// WithFacilitatorPool is an option for the PaymentMiddleware to verify payments with the healthiest
+// of several facilitators, failing over between them, and to settle each payment with the
+// facilitator that verified it. See facilitatorclient.Pool. The health checks of the pool run for
+// as long as the process, a pool given to WithFacilitator can be closed instead.
+func WithFacilitatorPool(config facilitatorclient.PoolConfig) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.FacilitatorPool = &config
+	}
+}
+
+// WithSupportedCheck is an option for the PaymentMiddleware to check the payment options against the
+// payment kinds the facilitator supports: SupportedCheckRequire refuses to create the middleware
+// when an option is not supported, SupportedCheckFilter only advertises the supported options.
+func WithSupportedCheck(check SupportedCheck) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.SupportedCheck = check
+	}
+}
+
+// WithSettlementMode is an option for the PaymentMiddleware to choose when payments are settled:
+// after the handler (the default), before it, or in the background once it has responded.
+func WithSettlementMode(mode SettlementMode) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.SettlementMode = mode
+	}
+}
+
+// WithSettlementQueue is an option for the PaymentMiddleware to settle payments asynchronously with
+// queue: verified payments are written to the queue's outbox and settled by its workers once the
+// handler has run, and the X-PAYMENT-RESPONSE header is a pending receipt. The queue must be running,
+// see settlement.Queue.Run. Requests must be served within settlement.HoldTimeout, after which their
+// payments are settled at their full price.
+func WithSettlementQueue(queue *settlement.Queue) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.SettlementMode = SettleAsync
+		options.SettlementQueue = queue
+	}
+}
+
+// WithCatalog is an option for the PaymentMiddleware to list its resource in catalog, with its
+// payment requirements. Resources depending on the request are listed once a payment for them is
+// verified.
+func WithCatalog(catalog *Catalog) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Catalog = catalog
+	}
+}
+
+// WithDiscoveryMetadata is an option for the PaymentMiddleware to set the metadata of its resource
+// in the catalog, see WithCatalog.
+func WithDiscoveryMetadata(metadata map[string]any) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.DiscoveryMetadata = metadata
+	}
+}
+
+// WithNetwork is an option for the PaymentMiddleware to set the network to accept payments on.
+// The network must be registered, see types.RegisterNetwork.
+func WithNetwork(network string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Network = network
+	}
+}
+
+// WithPrice is an option for the PaymentMiddleware to set the price, overriding its amount.
+// It allows charging in any EIP-3009 token, see types.TokenAmount.
+func WithPrice(price types.Price) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Price = &price
+	}
+}
+
+// WithPriceFunc is an option for the PaymentMiddleware to compute the price of each request,
+// for example from its query parameters or the authenticated user. Payment options with
+// their own price are not affected.
+func WithPriceFunc(priceFunc PriceFunc) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.PriceFunc = priceFunc
+	}
+}
+
+// WithPaymentOptions is an option for the PaymentMiddleware to accept payments through several
+// payment options, for example on different networks. It replaces the payment on Network.
+func WithPaymentOptions(paymentOptions ...PaymentOption) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.PaymentOptions = append(options.PaymentOptions, paymentOptions...)
+	}
+}
+
+// WithUpto is an option for the PaymentMiddleware to accept upto payments, authorizing the
+// facilitator account spender to charge up to the price. The handler reports the amount
+// actually used with ReportUsage, which is charged once the request is served. Upto payments
+// are only accepted on EVM networks, the payment options on other networks stay exact.
+func WithUpto(spender string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.UptoSpender = spender
+	}
+}
+
+// WithLogger is an option for the PaymentMiddleware to log the payments it handles with logger.
+// Failures are logged as errors and warnings, and each step of the payments at the debug level.
+func WithLogger(logger *slog.Logger) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Logger = logger
+	}
+}
+
+// WithTestnet is an option for the PaymentMiddleware to set the testnet flag.
+//
+// Deprecated: use WithNetwork("base-sepolia") or WithNetwork("base").
+func WithTestnet(testnet bool) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Testnet = testnet
+		options.Network = ""
+	}
+}
+
+// NetworkName returns the network to accept payments on
+func (options *PaymentMiddlewareOptions) NetworkName() string {
+	if options.Network != "" {
+		return options.Network
+	}
+	if options.Testnet {
+		return "base-sepolia"
+	}
+	return "base"
+}
+
+// WithCustomPaywallHTML is an option for the PaymentMiddleware to set the custom paywall HTML.
+func WithCustomPaywallHTML(customPaywallHTML string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.CustomPaywallHTML = customPaywallHTML
+	}
+}
+
+// WithResource is an option for the PaymentMiddleware to set the resource.
+func WithResource(resource string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Resource = resource
+	}
+}
+
+func WithResourceRootURL(resourceRootURL string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.ResourceRootURL = resourceRootURL
+	}
+}
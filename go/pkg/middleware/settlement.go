@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/settlement"
+)
+
+// SettlementMode is when payments are settled relative to the handler, see WithSettlementMode
+type SettlementMode int
+
+const (
+	// SettleAfterHandler settles the payment once the handler has run, buffering its response.
+	// When the facilitator can't settle the payment, the response is replaced with a 402, but the
+	// handler's side effects remain. A settlement the facilitator reports as failed is reported in
+	// the X-PAYMENT-RESPONSE header of the handler's response.
+	SettleAfterHandler SettlementMode = iota
+	// SettleBeforeHandler settles the payment before running the handler, which only runs once the
+	// payment is settled. Upto payments are charged their maximum amount, as the usage is not known yet.
+	SettleBeforeHandler
+	// SettleAsync runs the handler and streams its response without waiting for the settlement,
+	// which happens in the background. Failed settlements, and settlements the facilitator reports
+	// as failed, are only logged and the response has no X-PAYMENT-RESPONSE header, unless settling
+	// with a queue, see WithSettlementQueue.
+	SettleAsync
+)
+
+func (m SettlementMode) String() string {
+	switch m {
+	case SettleAfterHandler:
+		return "settle-after-handler"
+	case SettleBeforeHandler:
+		return "settle-before-handler"
+	case SettleAsync:
+		return "settle-async"
+	default:
+		return fmt.Sprintf("SettlementMode(%d)", int(m))
+	}
+}
+
+// SettlementMode returns when the engine's payments are settled
+func (e *Engine) SettlementMode() SettlementMode {
+	return e.options.SettlementMode
+}
+
+// HoldSettlement writes a verified payment to the settlement queue before the handler runs, when
+// settling with a queue. It returns the pending X-PAYMENT-RESPONSE header value, empty without a
+// queue, or the response to write if the payment can't be queued.
+func (e *Engine) HoldSettlement(ctx context.Context, payment *Payment) (string, *Response) {
+	if e.options.SettlementQueue == nil {
+		return "", nil
+	}
+
+	holdCtx := facilitatorclient.WithPoolMember(ctx, &payment.facilitator)
+	receipt, err := e.options.SettlementQueue.Hold(holdCtx, payment.Payload, payment.Requirements)
+	if err != nil {
+		e.log(ctx, slog.LevelError, "failed to queue settlement", "error", err)
+		return "", ErrorResponse(http.StatusInternalServerError, err)
+	}
+	payment.receiptID = receipt.ID
+
+	settleResponseHeader, err := settlement.PendingResponse(receipt).EncodeToBase64String()
+	if err != nil {
+		e.log(ctx, slog.LevelError, "failed to encode settlement response", "error", err)
+		return "", ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return settleResponseHeader, nil
+}
+
+// SettleAsync settles a verified payment in the background, once the handler has responded. Like
+// Settle, the settlement is not canceled with ctx, and its failures are logged, as nobody is left
+// to report them to. Payments held in the settlement queue are released to its workers.
+func (e *Engine) SettleAsync(ctx context.Context, payment *Payment) {
+	if payment.receiptID == "" {
+		go e.Settle(ctx, payment)
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	queue := e.options.SettlementQueue
+	requirements := payment.settlementRequirements()
+	if requirements == nil {
+		e.log(ctx, slog.LevelDebug, "no usage reported, skipping settlement")
+		if err := queue.Cancel(ctx, payment.receiptID); err != nil {
+			e.log(ctx, slog.LevelError, "failed to cancel settlement", "error", err)
+		}
+		return
+	}
+	if err := queue.Release(ctx, payment.receiptID, requirements); err != nil {
+		e.log(ctx, slog.LevelError, "failed to release settlement", "error", err)
+	}
+}
+
+// CancelSettlement cancels the settlement of a payment held in the settlement queue, when the
+// request was not served
+func (e *Engine) CancelSettlement(ctx context.Context, payment *Payment) {
+	if payment.receiptID == "" {
+		return
+	}
+	if err := e.options.SettlementQueue.Cancel(context.WithoutCancel(ctx), payment.receiptID); err != nil {
+		e.log(ctx, slog.LevelError, "failed to cancel settlement", "error", err)
+	}
+}
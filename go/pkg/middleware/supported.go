@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// SupportedCheck is how the payment options are checked against the payment kinds supported by
+// the facilitator, see WithSupportedCheck
+type SupportedCheck int
+
+const (
+	// SupportedCheckNone does not check the payment options, a payment the facilitator can't
+	// settle fails its verification
+	SupportedCheckNone SupportedCheck = iota
+	// SupportedCheckRequire refuses to create the middleware when the facilitator does not support
+	// one of the payment options
+	SupportedCheckRequire
+	// SupportedCheckFilter removes the payment options the facilitator does not support from the
+	// accepts of each request
+	SupportedCheckFilter
+)
+
+// DefaultSupportedCheckTimeout bounds the check of the supported payment kinds when the engine is
+// created, without a facilitator timeout
+const DefaultSupportedCheckTimeout = 10 * time.Second
+
+// ErrUnsupportedPaymentKind is returned when the facilitator can't settle a payment option
+var ErrUnsupportedPaymentKind = errors.New("payment kind not supported by the facilitator")
+
+// CheckSupported checks that the facilitator supports the scheme and network of every payment
+// option. Unsupported options are reported with an error matching ErrUnsupportedPaymentKind.
+func (e *Engine) CheckSupported(ctx context.Context) error {
+	supported, err := e.facilitator.SupportedContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch supported payment kinds: %w", err)
+	}
+
+	var errs []error
+	for _, network := range e.networks() {
+		if scheme := e.scheme(network); !supported.Supports(scheme, network) {
+			errs = append(errs, fmt.Errorf("%w: %s on %s", ErrUnsupportedPaymentKind, scheme, network))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkSupported applies SupportedCheckRequire when the engine is created, returning an error when
+// an option is not supported. The check is bounded by the facilitator timeout, or
+// DefaultSupportedCheckTimeout, and skipped when the facilitator can't be reached, to not make
+// starting the server depend on the facilitator being up.
+func (e *Engine) checkSupported(ctx context.Context) error {
+	if e.options.SupportedCheck != SupportedCheckRequire {
+		return nil
+	}
+
+	timeout := e.options.FacilitatorTimeout
+	if timeout <= 0 {
+		timeout = DefaultSupportedCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := e.CheckSupported(ctx)
+	if errors.Is(err, ErrUnsupportedPaymentKind) {
+		return err
+	}
+	if err != nil {
+		e.log(ctx, slog.LevelWarn, "skipping supported payment kinds check", "error", err)
+	}
+	return nil
+}
+
+// filterSupported removes the requirements the facilitator does not support, when filtering with
+// SupportedCheckFilter. The requirements are kept when the supported kinds can't be fetched.
+func (e *Engine) filterSupported(ctx context.Context, accepts []*types.PaymentRequirements) ([]*types.PaymentRequirements, error) {
+	if e.options.SupportedCheck != SupportedCheckFilter {
+		return accepts, nil
+	}
+
+	supported, err := e.facilitator.SupportedContext(ctx)
+	if err != nil {
+		e.log(ctx, slog.LevelWarn, "failed to fetch supported payment kinds", "error", err)
+		return accepts, nil
+	}
+
+	filtered := make([]*types.PaymentRequirements, 0, len(accepts))
+	for _, requirements := range accepts {
+		if supported.Supports(requirements.Scheme, requirements.Network) {
+			filtered = append(filtered, requirements)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("%w: none of the payment options is supported", ErrUnsupportedPaymentKind)
+	}
+
+	return filtered, nil
+}
+
+// scheme returns the scheme of the payment options on network. Only EVM payments use the upto
+// scheme, the payment options on other networks stay exact.
+func (e *Engine) scheme(network string) string {
+	if family, err := types.NetworkFamily(network); err == nil && family == types.NetworkFamilyEVM && e.options.UptoSpender != "" {
+		return types.SchemeUpto
+	}
+	return types.SchemeExact
+}
+
+// networks returns the networks of the payment options
+func (e *Engine) networks() []string {
+	if len(e.options.PaymentOptions) == 0 {
+		return []string{e.options.NetworkName()}
+	}
+
+	networks := make([]string, 0, len(e.options.PaymentOptions))
+	for _, option := range e.options.PaymentOptions {
+		networks = append(networks, option.Network)
+	}
+	return networks
+}
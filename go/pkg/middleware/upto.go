@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// paymentContextKey is the request context key of the verified payment
+type paymentContextKey struct{}
+
+// ContextWithPayment returns a copy of ctx carrying the verified payment, for ReportUsage
+func ContextWithPayment(ctx context.Context, payment *Payment) context.Context {
+	return context.WithValue(ctx, paymentContextKey{}, payment)
+}
+
+// PaymentFromContext returns the verified payment of the request context, if any
+func PaymentFromContext(ctx context.Context) (*Payment, bool) {
+	payment, ok := ctx.Value(paymentContextKey{}).(*Payment)
+	return payment, ok
+}
+
+// ReportUsage reports the amount, in atomic units of the asset, used by the request of an upto
+// payment. It is charged when the payment is settled instead of the maximum amount required.
+// Reporting again replaces the previous amount.
+func ReportUsage(ctx context.Context, amount *big.Int) error {
+	payment, ok := PaymentFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("no payment found in request context")
+	}
+	return payment.ReportUsage(amount)
+}
+
+// ReportUsage reports the amount, in atomic units of the asset, used by an upto payment
+func (p *Payment) ReportUsage(amount *big.Int) error {
+	if p.Requirements.Scheme != types.SchemeUpto {
+		return fmt.Errorf("usage can only be reported for %s payments, got %s", types.SchemeUpto, p.Requirements.Scheme)
+	}
+	if amount == nil || amount.Sign() < 0 {
+		return fmt.Errorf("invalid usage amount: %v", amount)
+	}
+
+	maxAmount, ok := new(big.Int).SetString(p.Requirements.MaxAmountRequired, 10)
+	if !ok {
+		return fmt.Errorf("invalid maxAmountRequired: %q", p.Requirements.MaxAmountRequired)
+	}
+	if amount.Cmp(maxAmount) > 0 {
+		return fmt.Errorf("usage %s exceeds the maximum amount %s", amount, maxAmount)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.settling {
+		return fmt.Errorf("usage reported after the payment was settled")
+	}
+	p.usage = new(big.Int).Set(amount)
+	return nil
+}
+
+// settlementRequirements returns the requirements to settle the payment with. Upto payments are
+// settled for their reported usage, or the maximum amount when no usage was reported, and nil
+// requirements are returned when there is nothing to charge.
+func (p *Payment) settlementRequirements() *types.PaymentRequirements {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.settling = true
+	if p.Requirements.Scheme != types.SchemeUpto {
+		return p.Requirements
+	}
+
+	if p.usage == nil {
+		return p.Requirements
+	}
+	if p.usage.Sign() == 0 {
+		return nil
+	}
+
+	requirements := *p.Requirements
+	requirements.MaxAmountRequired = p.usage.String()
+	return &requirements
+}
+
+// setUpto switches the payment requirements to the upto scheme, adding the permit spender to
+// their extra information
+func setUpto(requirements *types.PaymentRequirements, spender string) error {
+	requirements.Scheme = types.SchemeUpto
+	return mergeExtra(requirements, map[string]any{"spender": spender})
+}
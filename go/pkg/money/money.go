@@ -0,0 +1,109 @@
+// Package money parses decimal money amounts into exact atomic token amounts, without the
+// binary rounding of floating point numbers.
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// USDCDecimals is the number of decimals of USDC
+const USDCDecimals = 6
+
+// amountRegex matches an optional "$" prefix, a decimal number and an optional unit suffix
+var amountRegex = regexp.MustCompile(`^(\$)?\s*([0-9]+(?:\.[0-9]*)?|\.[0-9]+)\s*([A-Za-z]+)?$`)
+
+// Amount is an exact decimal amount
+type Amount struct {
+	// Value is the amount without its decimal point, the amount being Value * 10^-Scale
+	Value *big.Int
+	// Scale is the number of decimal places of the amount
+	Scale int
+	// Unit is the unit of the amount, "USD" for amounts prefixed with "$", "" if not specified
+	Unit string
+}
+
+// Parse parses a decimal amount such as "$0.01", "0.000001" or "1.5 USDC"
+func Parse(s string) (Amount, error) {
+	matches := amountRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return Amount{}, fmt.Errorf("invalid amount: %q", s)
+	}
+
+	unit := strings.ToUpper(matches[3])
+	if matches[1] == "$" {
+		if unit != "" && unit != "USD" {
+			return Amount{}, fmt.Errorf("invalid amount: %q", s)
+		}
+		unit = "USD"
+	}
+
+	integer, fraction, _ := strings.Cut(matches[2], ".")
+	value, _ := new(big.Int).SetString(integer+fraction, 10)
+
+	return Amount{
+		Value: value,
+		Scale: len(fraction),
+		Unit:  unit,
+	}, nil
+}
+
+// AtomicUnits converts the amount to the atomic units of an asset with the given decimals.
+// It fails if the amount has more decimal places than the asset.
+func (a Amount) AtomicUnits(decimals int) (*big.Int, error) {
+	value := new(big.Int).Set(a.Value)
+
+	// Trailing zeros do not count towards the precision
+	scale := a.Scale
+	ten := big.NewInt(10)
+	for scale > decimals {
+		quotient, remainder := new(big.Int).QuoRem(value, ten, new(big.Int))
+		if remainder.Sign() != 0 {
+			return nil, fmt.Errorf("amount %s has more than %d decimal places", a, decimals)
+		}
+		value, scale = quotient, scale-1
+	}
+
+	return value.Mul(value, new(big.Int).Exp(ten, big.NewInt(int64(decimals-scale)), nil)), nil
+}
+
+// String returns the decimal representation of the amount
+func (a Amount) String() string {
+	digits := a.Value.String()
+	if a.Scale > 0 {
+		if len(digits) <= a.Scale {
+			digits = strings.Repeat("0", a.Scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-a.Scale] + "." + digits[len(digits)-a.Scale:]
+	}
+	if a.Unit != "" {
+		digits += " " + a.Unit
+	}
+
+	return digits
+}
+
+// ParseAtomicUnits parses a decimal amount into the atomic units of an asset with the given decimals
+func ParseAtomicUnits(s string, decimals int) (*big.Int, error) {
+	amount, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return amount.AtomicUnits(decimals)
+}
+
+// ParseUSDC parses a USD or USDC amount such as "$0.01" or "1.5 USDC" into USDC atomic units
+func ParseUSDC(s string) (*big.Int, error) {
+	amount, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Unit != "" && amount.Unit != "USD" && amount.Unit != "USDC" {
+		return nil, fmt.Errorf("unsupported unit %s in amount %q, expected USD or USDC", amount.Unit, s)
+	}
+
+	return amount.AtomicUnits(USDCDecimals)
+}
@@ -0,0 +1,70 @@
+package money_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/money"
+)
+
+func TestParseUSDC(t *testing.T) {
+	testCases := []struct {
+		amount        string
+		expected      string
+		expectedError string
+	}{
+		{amount: "$0.01", expected: "10000"},
+		{amount: "0.000001", expected: "1"},
+		{amount: "1.5 USDC", expected: "1500000"},
+		{amount: "1.5usdc", expected: "1500000"},
+		{amount: "$ 2", expected: "2000000"},
+		{amount: "2 USD", expected: "2000000"},
+		{amount: "0.29", expected: "290000"},
+		{amount: "0.1000000", expected: "100000"},
+		{amount: ".5", expected: "500000"},
+		{amount: "10.", expected: "10000000"},
+		{amount: "0", expected: "0"},
+		{amount: "123456789012345678901234567890", expected: "123456789012345678901234567890000000"},
+		{amount: "0.0000001", expectedError: "amount 0.0000001 has more than 6 decimal places"},
+		{amount: "1.5 EURC", expectedError: `unsupported unit EURC in amount "1.5 EURC", expected USD or USDC`},
+		{amount: "$1 EUR", expectedError: `invalid amount: "$1 EUR"`},
+		{amount: "-1", expectedError: `invalid amount: "-1"`},
+		{amount: "1e6", expectedError: `invalid amount: "1e6"`},
+		{amount: "1,000", expectedError: `invalid amount: "1,000"`},
+		{amount: "", expectedError: `invalid amount: ""`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.amount, func(t *testing.T) {
+			units, err := money.ParseUSDC(tc.amount)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, units.String())
+		})
+	}
+}
+
+func TestParseAtomicUnits(t *testing.T) {
+	units, err := money.ParseAtomicUnits("1.000000000000000001", 18)
+	require.NoError(t, err)
+	assert.Equal(t, "1000000000000000001", units.String())
+
+	units, err = money.ParseAtomicUnits("12.34", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "1234", units.String())
+
+	_, err = money.ParseAtomicUnits("12.345", 2)
+	assert.EqualError(t, err, "amount 12.345 has more than 2 decimal places")
+}
+
+func TestAmount_String(t *testing.T) {
+	amount, err := money.Parse("$0.0500")
+	require.NoError(t, err)
+	assert.Equal(t, "0.0500 USD", amount.String())
+	assert.Equal(t, 4, amount.Scale)
+}
@@ -0,0 +1,170 @@
+package settlement
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// receiptsBucket is the bucket of the receipts, by ID
+	receiptsBucket = []byte("receipts")
+	// dueBucket indexes the pending receipts by the time they are due, see indexKey
+	dueBucket = []byte("due")
+	// doneBucket indexes the settled, failed and canceled receipts by their last update, see indexKey
+	doneBucket = []byte("done")
+)
+
+// BoltOutbox is an Outbox storing the receipts in a BoltDB file, which survives restarts.
+// The pending receipts are indexed by the time they are due, so that polling the outbox does not
+// read the receipts that are done.
+type BoltOutbox struct {
+	db *bolt.DB
+}
+
+var _ Outbox = (*BoltOutbox)(nil)
+
+// OpenBoltOutbox opens, or creates, the BoltDB outbox file at path. The file is locked until the
+// outbox is closed.
+func OpenBoltOutbox(path string) (*BoltOutbox, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{receiptsBucket, dueBucket, doneBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &BoltOutbox{db: db}, nil
+}
+
+// Close closes the outbox file
+func (o *BoltOutbox) Close() error {
+	return o.db.Close()
+}
+
+func (o *BoltOutbox) Put(ctx context.Context, receipt *Receipt) error {
+	receiptJson, err := json.Marshal(receipt)
+	if err != nil {
+		return fmt.Errorf("failed to marshal receipt: %w", err)
+	}
+
+	return o.db.Update(func(tx *bolt.Tx) error {
+		receipts := tx.Bucket(receiptsBucket)
+
+		// Replace the index entry of the previous version of the receipt
+		if previousJson := receipts.Get([]byte(receipt.ID)); previousJson != nil {
+			var previous Receipt
+			if err := json.Unmarshal(previousJson, &previous); err != nil {
+				return fmt.Errorf("failed to unmarshal receipt %s: %w", receipt.ID, err)
+			}
+			bucket, key := indexEntry(&previous)
+			if err := tx.Bucket(bucket).Delete(key); err != nil {
+				return err
+			}
+		}
+
+		bucket, key := indexEntry(receipt)
+		if err := tx.Bucket(bucket).Put(key, nil); err != nil {
+			return err
+		}
+		return receipts.Put([]byte(receipt.ID), receiptJson)
+	})
+}
+
+func (o *BoltOutbox) Get(ctx context.Context, id string) (*Receipt, error) {
+	var receipt *Receipt
+	err := o.db.View(func(tx *bolt.Tx) error {
+		receiptJson := tx.Bucket(receiptsBucket).Get([]byte(id))
+		if receiptJson == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(receiptJson, &receipt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+func (o *BoltOutbox) Due(ctx context.Context, now time.Time, limit int) ([]*Receipt, error) {
+	var due []*Receipt
+	err := o.db.View(func(tx *bolt.Tx) error {
+		receipts := tx.Bucket(receiptsBucket)
+		end := indexKey(now, "")
+
+		cursor := tx.Bucket(dueBucket).Cursor()
+		for key, _ := cursor.First(); key != nil && len(due) < limit && bytes.Compare(key[:8], end) <= 0; key, _ = cursor.Next() {
+			id := key[8:]
+			var receipt Receipt
+			if err := json.Unmarshal(receipts.Get(id), &receipt); err != nil {
+				return fmt.Errorf("failed to unmarshal receipt %s: %w", id, err)
+			}
+			due = append(due, &receipt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return due, nil
+}
+
+func (o *BoltOutbox) Prune(ctx context.Context, before time.Time) error {
+	return o.db.Update(func(tx *bolt.Tx) error {
+		receipts := tx.Bucket(receiptsBucket)
+		done := tx.Bucket(doneBucket)
+		end := indexKey(before, "")
+
+		var keys [][]byte
+		cursor := done.Cursor()
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key, end) < 0; key, _ = cursor.Next() {
+			keys = append(keys, bytes.Clone(key))
+		}
+		for _, key := range keys {
+			if err := receipts.Delete(key[8:]); err != nil {
+				return err
+			}
+			if err := done.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// indexEntry returns the index bucket and key of a receipt: pending receipts are indexed by the
+// time they are due, the others by their last update
+func indexEntry(receipt *Receipt) ([]byte, []byte) {
+	if receipt.Status == StatusPending {
+		return dueBucket, indexKey(receipt.dueAt(), receipt.ID)
+	}
+	return doneBucket, indexKey(receipt.UpdatedAt, receipt.ID)
+}
+
+// indexKey returns an index key ordered by t, then by the receipt id. Times before the Unix epoch,
+// such as the zero time, are ordered first.
+func indexKey(t time.Time, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	if t.After(time.Unix(0, 0)) {
+		binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	}
+	return append(key, id...)
+}
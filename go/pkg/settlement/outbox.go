@@ -0,0 +1,162 @@
+// Package settlement settles verified payments asynchronously. Payments are written to a durable
+// Outbox and settled by the workers of a Queue, with retries, while the resource server answers
+// with a pending receipt that can be queried later.
+package settlement
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// Status is the status of a settlement
+type Status string
+
+const (
+	// StatusPending is the status of a payment waiting to be settled
+	StatusPending Status = "pending"
+	// StatusSettled is the status of a settled payment
+	StatusSettled Status = "settled"
+	// StatusFailed is the status of a payment whose settlement failed for good
+	StatusFailed Status = "failed"
+	// StatusCanceled is the status of a payment that had nothing to settle, such as an upto payment
+	// without usage or a request that failed
+	StatusCanceled Status = "canceled"
+)
+
+// HoldTimeout is how long a receipt is held before its payment is settled anyway, as the process
+// holding it is assumed to have exited, see Queue.Hold. The payment is then settled with the
+// requirements it was held with, so an upto payment is charged its maximum amount, including when
+// the handler of the request is still running.
+const HoldTimeout = 5 * time.Minute
+
+// ErrNotFound is returned for unknown receipt IDs
+var ErrNotFound = errors.New("receipt not found")
+
+// Receipt is the settlement of a payment
+type Receipt struct {
+	ID           string                     `json:"id"`
+	Status       Status                     `json:"status"`
+	Payload      *types.PaymentPayload      `json:"paymentPayload,omitempty"`
+	Requirements *types.PaymentRequirements `json:"paymentRequirements"`
+	// Facilitator is the URL of the pool member that verified the payment, which settles it, see
+	// facilitatorclient.WithPoolMember
+	Facilitator string `json:"facilitator,omitempty"`
+	// Held receipts are not settled until released, or for HoldTimeout, see Queue.Hold
+	Held bool `json:"held,omitempty"`
+	// Attempts is the number of failed settlement attempts
+	Attempts int `json:"attempts"`
+	// NextAttempt is when the payment is settled next, while pending
+	NextAttempt time.Time `json:"nextAttempt"`
+	// Response is the response of the facilitator once settled, or rejected
+	Response *types.SettleResponse `json:"settleResponse,omitempty"`
+	// Error is the error of the last attempt
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// dueAt returns when a pending receipt must be settled: at its next attempt, and no sooner than
+// HoldTimeout after its last update while held
+func (r *Receipt) dueAt() time.Time {
+	if r.Held {
+		if heldUntil := r.UpdatedAt.Add(HoldTimeout); heldUntil.After(r.NextAttempt) {
+			return heldUntil
+		}
+	}
+	return r.NextAttempt
+}
+
+// due reports whether the receipt must be settled at now
+func (r *Receipt) due(now time.Time) bool {
+	return r.Status == StatusPending && !r.dueAt().After(now)
+}
+
+// done reports whether the receipt was last updated before the given time, with a final status
+func (r *Receipt) done(before time.Time) bool {
+	return r.Status != StatusPending && r.UpdatedAt.Before(before)
+}
+
+// Outbox stores receipts durably until their payments are settled
+type Outbox interface {
+	// Put inserts or replaces a receipt
+	Put(ctx context.Context, receipt *Receipt) error
+	// Get returns the receipt of id, or ErrNotFound
+	Get(ctx context.Context, id string) (*Receipt, error)
+	// Due returns at most limit pending receipts to settle at now, that are not held for less than
+	// HoldTimeout, by the time they are due
+	Due(ctx context.Context, now time.Time, limit int) ([]*Receipt, error)
+	// Prune deletes the settled, failed and canceled receipts last updated before the given time
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// MemoryOutbox is an Outbox keeping the receipts in memory, which are lost when the process exits
+type MemoryOutbox struct {
+	mu       sync.Mutex
+	receipts map[string]Receipt
+}
+
+var _ Outbox = (*MemoryOutbox)(nil)
+
+// NewMemoryOutbox creates an empty in-memory outbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{receipts: map[string]Receipt{}}
+}
+
+func (o *MemoryOutbox) Put(ctx context.Context, receipt *Receipt) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.receipts[receipt.ID] = *receipt
+	return nil
+}
+
+func (o *MemoryOutbox) Get(ctx context.Context, id string) (*Receipt, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	receipt, ok := o.receipts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &receipt, nil
+}
+
+func (o *MemoryOutbox) Due(ctx context.Context, now time.Time, limit int) ([]*Receipt, error) {
+	o.mu.Lock()
+	var due []*Receipt
+	for _, receipt := range o.receipts {
+		if receipt.due(now) {
+			due = append(due, &receipt)
+		}
+	}
+	o.mu.Unlock()
+
+	return firstDue(due, limit), nil
+}
+
+func (o *MemoryOutbox) Prune(ctx context.Context, before time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for id, receipt := range o.receipts {
+		if receipt.done(before) {
+			delete(o.receipts, id)
+		}
+	}
+	return nil
+}
+
+// firstDue returns the limit receipts to settle first
+func firstDue(due []*Receipt, limit int) []*Receipt {
+	slices.SortFunc(due, func(a, b *Receipt) int {
+		return a.dueAt().Compare(b.dueAt())
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
@@ -0,0 +1,130 @@
+package settlement_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/settlement"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// testOutboxes returns an outbox of each implementation
+func testOutboxes(t *testing.T) map[string]settlement.Outbox {
+	t.Helper()
+
+	boltOutbox, err := settlement.OpenBoltOutbox(filepath.Join(t.TempDir(), "outbox.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { boltOutbox.Close() })
+
+	return map[string]settlement.Outbox{
+		"memory": settlement.NewMemoryOutbox(),
+		"bolt":   boltOutbox,
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia", MaxAmountRequired: "1000"}
+	receipts := []*settlement.Receipt{
+		{ID: "later", Status: settlement.StatusPending, Requirements: requirements, NextAttempt: now.Add(-time.Second)},
+		{ID: "first", Status: settlement.StatusPending, Requirements: requirements, NextAttempt: now.Add(-time.Minute)},
+		{ID: "future", Status: settlement.StatusPending, Requirements: requirements, NextAttempt: now.Add(time.Minute)},
+		{ID: "held", Status: settlement.StatusPending, Requirements: requirements, Held: true, NextAttempt: now.Add(-time.Minute), UpdatedAt: now},
+		{ID: "abandoned", Status: settlement.StatusPending, Requirements: requirements, Held: true, NextAttempt: now.Add(-time.Hour), UpdatedAt: now.Add(-settlement.HoldTimeout)},
+		{ID: "settled", Status: settlement.StatusSettled, Requirements: requirements, NextAttempt: now.Add(-time.Minute)},
+	}
+
+	for name, outbox := range testOutboxes(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, receipt := range receipts {
+				require.NoError(t, outbox.Put(ctx, receipt))
+			}
+
+			receipt, err := outbox.Get(ctx, "first")
+			require.NoError(t, err)
+			assert.Equal(t, "1000", receipt.Requirements.MaxAmountRequired)
+			assert.True(t, receipt.NextAttempt.Equal(now.Add(-time.Minute)))
+
+			_, err = outbox.Get(ctx, "unknown")
+			assert.ErrorIs(t, err, settlement.ErrNotFound)
+
+			due, err := outbox.Due(ctx, now, 10)
+			require.NoError(t, err)
+			require.Len(t, due, 3)
+			assert.Equal(t, "first", due[0].ID)
+			assert.Equal(t, "later", due[1].ID)
+			assert.Equal(t, "abandoned", due[2].ID)
+
+			due, err = outbox.Due(ctx, now, 1)
+			require.NoError(t, err)
+			require.Len(t, due, 1)
+			assert.Equal(t, "first", due[0].ID)
+
+			// Receipts are replaced
+			receipt.Status = settlement.StatusSettled
+			require.NoError(t, outbox.Put(ctx, receipt))
+			due, err = outbox.Due(ctx, now, 10)
+			require.NoError(t, err)
+			require.Len(t, due, 2)
+			assert.Equal(t, "later", due[0].ID)
+		})
+	}
+}
+
+func TestOutbox_Prune(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	receipts := []*settlement.Receipt{
+		{ID: "settled", Status: settlement.StatusSettled, UpdatedAt: now.Add(-time.Hour)},
+		{ID: "failed", Status: settlement.StatusFailed, UpdatedAt: now.Add(-time.Hour)},
+		{ID: "recent", Status: settlement.StatusSettled, UpdatedAt: now},
+		{ID: "pending", Status: settlement.StatusPending, NextAttempt: now.Add(-time.Hour), UpdatedAt: now.Add(-time.Hour)},
+	}
+
+	for name, outbox := range testOutboxes(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, receipt := range receipts {
+				require.NoError(t, outbox.Put(ctx, receipt))
+			}
+
+			require.NoError(t, outbox.Prune(ctx, now.Add(-time.Minute)))
+
+			for _, id := range []string{"settled", "failed"} {
+				_, err := outbox.Get(ctx, id)
+				assert.ErrorIs(t, err, settlement.ErrNotFound, id)
+			}
+			for _, id := range []string{"recent", "pending"} {
+				_, err := outbox.Get(ctx, id)
+				assert.NoError(t, err, id)
+			}
+
+			due, err := outbox.Due(ctx, now, 10)
+			require.NoError(t, err)
+			require.Len(t, due, 1)
+			assert.Equal(t, "pending", due[0].ID)
+		})
+	}
+}
+
+func TestBoltOutbox_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+
+	outbox, err := settlement.OpenBoltOutbox(path)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Put(context.Background(), &settlement.Receipt{ID: "receipt", Status: settlement.StatusPending}))
+	require.NoError(t, outbox.Close())
+
+	outbox, err = settlement.OpenBoltOutbox(path)
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	receipt, err := outbox.Get(context.Background(), "receipt")
+	require.NoError(t, err)
+	assert.Equal(t, settlement.StatusPending, receipt.Status)
+}
@@ -0,0 +1,387 @@
+package settlement
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// DefaultRetryPolicy makes up to 10 attempts of the settlements failing to reach the facilitator,
+// over up to about 8 minutes
+var DefaultRetryPolicy = facilitatorclient.RetryPolicy{
+	MaxAttempts:    10,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Minute,
+}
+
+// Defaults of the queue options
+const (
+	DefaultWorkers      = 4
+	DefaultPollInterval = time.Second
+	DefaultRetention    = 7 * 24 * time.Hour
+)
+
+// pruneInterval is the interval between the deletions of the receipts older than the retention
+const pruneInterval = time.Hour
+
+// leaseDuration is how long a dispatched receipt is not dispatched again, should the attempt not
+// complete, for example when the process exits during a settlement
+const leaseDuration = 5 * time.Minute
+
+// Queue settles the payments of an Outbox with a pool of workers. Settlements the facilitator did
+// not accept are retried according to the retry policy, see RetryPolicy.Retryable; other failures,
+// such as a settlement rejected by the facilitator, fail for good. EIP-3009 authorizations can only
+// be used once, so a payment retried after being submitted is rejected rather than settled twice.
+// Receipts are kept for the retention once their payment is settled, failed or canceled.
+type Queue struct {
+	outbox       Outbox
+	facilitator  facilitatorclient.Facilitator
+	workers      int
+	retryPolicy  facilitatorclient.RetryPolicy
+	pollInterval time.Duration
+	retention    time.Duration
+	logger       *slog.Logger
+
+	wake chan struct{}
+	// mu serializes the updates of the receipts, between the workers and the release of held receipts
+	mu sync.Mutex
+}
+
+// QueueOption configures a Queue
+type QueueOption func(*Queue)
+
+// WithWorkers sets the number of payments settled concurrently
+func WithWorkers(workers int) QueueOption {
+	return func(q *Queue) {
+		q.workers = workers
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the settlements failing to reach the facilitator
+func WithRetryPolicy(policy facilitatorclient.RetryPolicy) QueueOption {
+	return func(q *Queue) {
+		q.retryPolicy = policy
+	}
+}
+
+// WithPollInterval sets the interval between the checks of the outbox for payments to settle
+func WithPollInterval(interval time.Duration) QueueOption {
+	return func(q *Queue) {
+		q.pollInterval = interval
+	}
+}
+
+// WithRetention sets how long the receipts are kept once their payment is settled, failed or
+// canceled. They are kept forever when retention is not positive.
+func WithRetention(retention time.Duration) QueueOption {
+	return func(q *Queue) {
+		q.retention = retention
+	}
+}
+
+// WithLogger sets the logger of the settlement outcomes and failures, nothing is logged by default
+func WithLogger(logger *slog.Logger) QueueOption {
+	return func(q *Queue) {
+		q.logger = logger
+	}
+}
+
+// NewQueue creates a queue settling the payments of outbox with facilitator. The payments are
+// settled once the queue runs, see Run.
+func NewQueue(outbox Outbox, facilitator facilitatorclient.Facilitator, opts ...QueueOption) *Queue {
+	q := &Queue{
+		outbox:       outbox,
+		facilitator:  facilitator,
+		workers:      DefaultWorkers,
+		retryPolicy:  DefaultRetryPolicy,
+		pollInterval: DefaultPollInterval,
+		retention:    DefaultRetention,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
+
+// Enqueue writes a verified payment to the outbox, to be settled by the workers. The receipt
+// records the pool member of ctx, see facilitatorclient.WithPoolMember, which settles the payment.
+func (q *Queue) Enqueue(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*Receipt, error) {
+	receipt, err := q.put(ctx, payload, requirements, false)
+	if err != nil {
+		return nil, err
+	}
+
+	q.notify()
+	return receipt, nil
+}
+
+// Hold writes a verified payment to the outbox without settling it until it is released with
+// Release, for example once the request has been served and the usage of an upto payment is known.
+// Payments held for longer than HoldTimeout are settled with requirements, which charges an upto
+// payment its maximum amount: requests must be served within HoldTimeout.
+// Like Enqueue, the receipt records the pool member of ctx.
+func (q *Queue) Hold(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*Receipt, error) {
+	return q.put(ctx, payload, requirements, true)
+}
+
+// Release settles a held payment with requirements
+func (q *Queue) Release(ctx context.Context, id string, requirements *types.PaymentRequirements) error {
+	err := q.update(ctx, id, func(receipt *Receipt) {
+		receipt.Held = false
+		receipt.Requirements = requirements
+		receipt.NextAttempt = time.Now()
+	})
+	if err != nil {
+		return err
+	}
+
+	q.notify()
+	return nil
+}
+
+// Cancel cancels the settlement of a held payment
+func (q *Queue) Cancel(ctx context.Context, id string) error {
+	return q.update(ctx, id, func(receipt *Receipt) {
+		receipt.Held = false
+		receipt.Status = StatusCanceled
+	})
+}
+
+// Receipt returns the receipt of id, or ErrNotFound
+func (q *Queue) Receipt(ctx context.Context, id string) (*Receipt, error) {
+	return q.outbox.Get(ctx, id)
+}
+
+// Run settles the payments of the outbox until ctx is done, including the payments left pending
+// by a previous run, and the payments held for longer than the hold timeout, which were left held
+// by a process that exited. It returns once the settlements in progress have completed.
+func (q *Queue) Run(ctx context.Context) error {
+	receipts := make(chan *Receipt)
+
+	var wg sync.WaitGroup
+	for range max(q.workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for receipt := range receipts {
+				q.settle(ctx, receipt)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(receipts)
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		if err := q.dispatch(ctx, receipts); err != nil {
+			q.log(ctx, slog.LevelError, "failed to dispatch settlements", "error", err)
+		}
+		if q.retention > 0 && time.Since(lastPrune) >= pruneInterval {
+			lastPrune = time.Now()
+			if err := q.outbox.Prune(ctx, lastPrune.Add(-q.retention)); err != nil {
+				q.log(ctx, slog.LevelError, "failed to prune receipts", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// ServeHTTP answers the receipt whose ID is the last segment of the request path, without its
+// signed payment payload
+func (q *Queue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	receipt, err := q.Receipt(r.Context(), path.Base(r.URL.Path))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+	default:
+		receipt.Payload = nil
+		json.NewEncoder(w).Encode(receipt)
+	}
+}
+
+// PendingResponse returns the settlement response of a pending receipt, for the
+// X-PAYMENT-RESPONSE header
+func PendingResponse(receipt *Receipt) *types.SettleResponse {
+	return &types.SettleResponse{
+		Network:   receipt.Requirements.Network,
+		Status:    types.SettleStatusPending,
+		ReceiptID: receipt.ID,
+	}
+}
+
+// put writes a new pending receipt to the outbox
+func (q *Queue) put(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, held bool) (*Receipt, error) {
+	id, err := newReceiptID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	receipt := &Receipt{
+		ID:           id,
+		Status:       StatusPending,
+		Payload:      payload,
+		Requirements: requirements,
+		Facilitator:  facilitatorclient.PoolMemberURL(ctx),
+		Held:         held,
+		NextAttempt:  now,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := q.outbox.Put(ctx, receipt); err != nil {
+		return nil, fmt.Errorf("failed to write receipt: %w", err)
+	}
+
+	return receipt, nil
+}
+
+// update applies change to a held receipt
+func (q *Queue) update(ctx context.Context, id string, change func(receipt *Receipt)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	receipt, err := q.outbox.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !receipt.Held {
+		return fmt.Errorf("receipt %s is not held", id)
+	}
+
+	change(receipt)
+	receipt.UpdatedAt = time.Now()
+	return q.outbox.Put(ctx, receipt)
+}
+
+// notify wakes the dispatcher up
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch leases the due receipts and sends them to the workers
+func (q *Queue) dispatch(ctx context.Context, receipts chan<- *Receipt) error {
+	for {
+		q.mu.Lock()
+		now := time.Now()
+		due, err := q.outbox.Due(ctx, now, max(q.workers, 1))
+		for _, receipt := range due {
+			if err != nil {
+				break
+			}
+			receipt.Held = false
+			receipt.NextAttempt = now.Add(leaseDuration)
+			err = q.outbox.Put(ctx, receipt)
+		}
+		q.mu.Unlock()
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		for _, receipt := range due {
+			select {
+			case receipts <- receipt:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// settle makes a settlement attempt of the receipt and records its outcome. The attempt is not
+// canceled when the queue stops, as the transaction may already have been submitted, and is only
+// bounded by the facilitator timeout. Payments verified by a pool member are settled by it.
+func (q *Queue) settle(ctx context.Context, receipt *Receipt) {
+	ctx = context.WithoutCancel(ctx)
+	settleCtx := facilitatorclient.WithPoolMember(ctx, &receipt.Facilitator)
+	response, err := q.facilitator.SettleContext(settleCtx, receipt.Payload, receipt.Requirements)
+	now := time.Now()
+
+	switch {
+	case err == nil:
+		receipt.Response = response
+		receipt.Error = ""
+		receipt.Status = StatusSettled
+		if err := response.Err(); err != nil {
+			receipt.Error = err.Error()
+			receipt.Status = StatusFailed
+		}
+	case !retryable(q.retryPolicy, err):
+		receipt.Attempts++
+		receipt.Error = err.Error()
+		receipt.Status = StatusFailed
+	default:
+		receipt.Attempts++
+		receipt.Error = err.Error()
+		if receipt.Attempts >= q.retryPolicy.MaxAttempts {
+			receipt.Status = StatusFailed
+		} else {
+			receipt.NextAttempt = now.Add(q.retryPolicy.Backoff(receipt.Attempts))
+		}
+	}
+	receipt.UpdatedAt = now
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.outbox.Put(ctx, receipt); err != nil {
+		q.log(ctx, slog.LevelError, "failed to write receipt", "receipt", receipt.ID, "error", err)
+	}
+	switch receipt.Status {
+	case StatusSettled:
+		q.log(ctx, slog.LevelInfo, "payment settled", "receipt", receipt.ID, "transaction", receipt.Response.Transaction)
+	case StatusFailed:
+		q.log(ctx, slog.LevelError, "settlement failed", "receipt", receipt.ID, "error", receipt.Error)
+	}
+}
+
+// log logs a message with the logger of the queue, if any
+func (q *Queue) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if q.logger != nil {
+		q.logger.Log(ctx, level, msg, args...)
+	}
+}
+
+// retryable reports whether a failed settlement can be retried, which is only the case when the
+// facilitator did not accept it
+func retryable(policy facilitatorclient.RetryPolicy, err error) bool {
+	return errors.Is(err, facilitatorclient.ErrCircuitOpen) || policy.Retryable("settle", err)
+}
+
+// newReceiptID returns a random receipt ID
+func newReceiptID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate receipt ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
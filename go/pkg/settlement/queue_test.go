@@ -0,0 +1,291 @@
+package settlement_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/settlement"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// fakeFacilitator settles payments with the given errors in turn, then successfully
+type fakeFacilitator struct {
+	mu       sync.Mutex
+	errs     []error
+	response *types.SettleResponse
+	settled  []*types.PaymentRequirements
+	// members are the pool members of the settlements, see facilitatorclient.WithPoolMember
+	members []string
+}
+
+func (f *fakeFacilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	return &types.VerifyResponse{IsValid: true}, nil
+}
+
+func (f *fakeFacilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.settled = append(f.settled, requirements)
+	f.members = append(f.members, facilitatorclient.PoolMemberURL(ctx))
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	if f.response != nil {
+		return f.response, nil
+	}
+	return &types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: requirements.Network}, nil
+}
+
+func (f *fakeFacilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	return &types.SupportedPaymentKindsResponse{}, nil
+}
+
+func (f *fakeFacilitator) attempts() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.settled)
+}
+
+var (
+	testPayload      = &types.PaymentPayload{X402Version: 1, Scheme: "exact", Network: "base-sepolia"}
+	testRequirements = &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia", MaxAmountRequired: "1000"}
+	unreachable      = &facilitatorclient.RequestError{Op: "settle", Kind: facilitatorclient.ErrNetwork, Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	serverError      = &facilitatorclient.RequestError{Op: "settle", Kind: facilitatorclient.ErrRejected, StatusCode: 500, Status: "500 Internal Server Error"}
+)
+
+// runQueue runs the queue until the end of the test
+func runQueue(t *testing.T, queue *settlement.Queue) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queue.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitForStatus waits for the receipt of id to have status
+func waitForStatus(t *testing.T, queue *settlement.Queue, id string, status settlement.Status) *settlement.Receipt {
+	t.Helper()
+
+	var receipt *settlement.Receipt
+	require.Eventually(t, func() bool {
+		var err error
+		receipt, err = queue.Receipt(context.Background(), id)
+		return err == nil && receipt.Status == status
+	}, time.Second, 5*time.Millisecond)
+
+	return receipt
+}
+
+// fastRetryPolicy retries without slowing the tests down
+var fastRetryPolicy = facilitatorclient.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+func TestQueue(t *testing.T) {
+	testCases := []struct {
+		name             string
+		facilitator      *fakeFacilitator
+		expectedStatus   settlement.Status
+		expectedAttempts int
+		expectedError    string
+	}{
+		{
+			name:             "settled",
+			facilitator:      &fakeFacilitator{},
+			expectedStatus:   settlement.StatusSettled,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "retried when the facilitator can't be reached",
+			facilitator:      &fakeFacilitator{errs: []error{unreachable, unreachable}},
+			expectedStatus:   settlement.StatusSettled,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "failed after max attempts",
+			facilitator:      &fakeFacilitator{errs: []error{unreachable, unreachable, unreachable}},
+			expectedStatus:   settlement.StatusFailed,
+			expectedAttempts: 3,
+			expectedError:    "failed to send settle request: dial tcp: connection refused",
+		},
+		{
+			name:             "failed when the facilitator may have accepted it",
+			facilitator:      &fakeFacilitator{errs: []error{serverError}},
+			expectedStatus:   settlement.StatusFailed,
+			expectedAttempts: 1,
+			expectedError:    "failed to settle payment: 500 Internal Server Error",
+		},
+		{
+			name:             "failed when rejected",
+			facilitator:      &fakeFacilitator{response: &types.SettleResponse{ErrorReason: types.ReasonPtr(types.ErrorReasonInvalidTransactionState)}},
+			expectedStatus:   settlement.StatusFailed,
+			expectedAttempts: 1,
+			expectedError:    "settlement failed: invalid_transaction_state",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			queue := settlement.NewQueue(settlement.NewMemoryOutbox(), tc.facilitator,
+				settlement.WithRetryPolicy(fastRetryPolicy),
+				settlement.WithPollInterval(time.Millisecond),
+			)
+			runQueue(t, queue)
+
+			receipt, err := queue.Enqueue(context.Background(), testPayload, testRequirements)
+			require.NoError(t, err)
+			assert.Equal(t, settlement.StatusPending, receipt.Status)
+
+			receipt = waitForStatus(t, queue, receipt.ID, tc.expectedStatus)
+			assert.Equal(t, tc.expectedAttempts, tc.facilitator.attempts())
+			assert.Equal(t, tc.expectedError, receipt.Error)
+			if tc.expectedStatus == settlement.StatusSettled {
+				assert.Equal(t, "0xtesthash", receipt.Response.Transaction)
+			}
+		})
+	}
+}
+
+func TestQueue_Hold(t *testing.T) {
+	facilitator := &fakeFacilitator{}
+	queue := settlement.NewQueue(settlement.NewMemoryOutbox(), facilitator, settlement.WithPollInterval(time.Millisecond))
+	runQueue(t, queue)
+
+	held, err := queue.Hold(context.Background(), testPayload, testRequirements)
+	require.NoError(t, err)
+	canceled, err := queue.Hold(context.Background(), testPayload, testRequirements)
+	require.NoError(t, err)
+
+	// Held payments are not settled
+	time.Sleep(20 * time.Millisecond)
+	assert.Zero(t, facilitator.attempts())
+
+	usage := *testRequirements
+	usage.MaxAmountRequired = "400"
+	require.NoError(t, queue.Release(context.Background(), held.ID, &usage))
+	require.NoError(t, queue.Cancel(context.Background(), canceled.ID))
+
+	waitForStatus(t, queue, held.ID, settlement.StatusSettled)
+	waitForStatus(t, queue, canceled.ID, settlement.StatusCanceled)
+	require.Equal(t, 1, facilitator.attempts())
+	assert.Equal(t, "400", facilitator.settled[0].MaxAmountRequired)
+
+	// Only held payments can be released
+	assert.Error(t, queue.Release(context.Background(), held.ID, &usage))
+}
+
+func TestQueue_SettlesAbandonedHold(t *testing.T) {
+	outbox := settlement.NewMemoryOutbox()
+	abandoned := time.Now().Add(-settlement.HoldTimeout)
+	require.NoError(t, outbox.Put(context.Background(), &settlement.Receipt{
+		ID:           "abandoned",
+		Status:       settlement.StatusPending,
+		Payload:      testPayload,
+		Requirements: testRequirements,
+		Held:         true,
+		NextAttempt:  abandoned,
+		CreatedAt:    abandoned,
+		UpdatedAt:    abandoned,
+	}))
+
+	facilitator := &fakeFacilitator{}
+	queue := settlement.NewQueue(outbox, facilitator, settlement.WithPollInterval(time.Millisecond))
+	runQueue(t, queue)
+
+	receipt := waitForStatus(t, queue, "abandoned", settlement.StatusSettled)
+	assert.False(t, receipt.Held)
+	assert.Equal(t, 1, facilitator.attempts())
+}
+
+func TestQueue_PrunesOldReceipts(t *testing.T) {
+	outbox := settlement.NewMemoryOutbox()
+	settled := time.Now().Add(-time.Hour)
+	require.NoError(t, outbox.Put(context.Background(), &settlement.Receipt{
+		ID:           "settled",
+		Status:       settlement.StatusSettled,
+		Requirements: testRequirements,
+		UpdatedAt:    settled,
+	}))
+
+	queue := settlement.NewQueue(outbox, &fakeFacilitator{},
+		settlement.WithPollInterval(time.Millisecond),
+		settlement.WithRetention(time.Minute),
+	)
+	runQueue(t, queue)
+
+	assert.Eventually(t, func() bool {
+		_, err := queue.Receipt(context.Background(), "settled")
+		return errors.Is(err, settlement.ErrNotFound)
+	}, time.Second, time.Millisecond)
+}
+
+func TestQueue_SettlesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+
+	// The payment, verified by a pool member, is queued by a process exiting before settling it
+	outbox, err := settlement.OpenBoltOutbox(path)
+	require.NoError(t, err)
+	member := "https://facilitator.example.com"
+	ctx := facilitatorclient.WithPoolMember(context.Background(), &member)
+	receipt, err := settlement.NewQueue(outbox, &fakeFacilitator{}).Enqueue(ctx, testPayload, testRequirements)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Close())
+
+	outbox, err = settlement.OpenBoltOutbox(path)
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	facilitator := &fakeFacilitator{}
+	queue := settlement.NewQueue(outbox, facilitator, settlement.WithPollInterval(time.Millisecond))
+	runQueue(t, queue)
+
+	waitForStatus(t, queue, receipt.ID, settlement.StatusSettled)
+	assert.Equal(t, 1, facilitator.attempts())
+	facilitator.mu.Lock()
+	defer facilitator.mu.Unlock()
+	assert.Equal(t, []string{member}, facilitator.members)
+}
+
+func TestQueue_ServeHTTP(t *testing.T) {
+	queue := settlement.NewQueue(settlement.NewMemoryOutbox(), &fakeFacilitator{})
+	receipt, err := queue.Enqueue(context.Background(), testPayload, testRequirements)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	queue.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/receipts/"+receipt.ID, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var served settlement.Receipt
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, receipt.ID, served.ID)
+	assert.Equal(t, settlement.StatusPending, served.Status)
+	assert.Nil(t, served.Payload)
+
+	w = httptest.NewRecorder()
+	queue.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/receipts/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
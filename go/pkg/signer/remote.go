@@ -0,0 +1,106 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// RemoteSigner signs through a remote signer speaking the eth_signTypedData_v4 JSON-RPC method,
+// such as Clef or Web3Signer. The private key never leaves the remote signer.
+type RemoteSigner struct {
+	URL        string
+	HTTPClient *http.Client
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+
+	address common.Address
+}
+
+// rpcRequest is a JSON-RPC 2.0 request
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	Result string `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewRemoteSigner creates a signer for the account managed by the remote signer at url
+func NewRemoteSigner(url string, address common.Address) *RemoteSigner {
+	return &RemoteSigner{
+		URL:        url,
+		HTTPClient: http.DefaultClient,
+		address:    address,
+	}
+}
+
+// Address returns the address of the remote account
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTypedData requests a signature of the typed data from the remote signer
+func (s *RemoteSigner) SignTypedData(ctx context.Context, typedData apitypes.TypedData) ([]byte, error) {
+	reqBody, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "eth_signTypedData_v4",
+		Params:  []any{s.address.Hex(), typedData},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send sign request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to sign typed data: %s", resp.Status)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return nil, fmt.Errorf("failed to decode sign response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return nil, fmt.Errorf("remote signer error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+
+	signature, err := hexutil.Decode(rpcResp.Result)
+	if err != nil || len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid signature from remote signer: %q", rpcResp.Result)
+	}
+	if signature[crypto.RecoveryIDOffset] < 27 {
+		signature[crypto.RecoveryIDOffset] += 27
+	}
+
+	return signature, nil
+}
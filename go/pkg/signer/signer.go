@@ -0,0 +1,85 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+)
+
+// Signer signs EIP-712 typed data on behalf of an Ethereum account
+type Signer interface {
+	// Address returns the address of the signing account
+	Address() common.Address
+	// SignTypedData signs the EIP-712 typed data and returns the 65 byte [R || S || V] signature,
+	// with V being 27 or 28 as expected by EIP-3009 token contracts
+	SignTypedData(ctx context.Context, typedData apitypes.TypedData) ([]byte, error)
+}
+
+// PrivateKeySigner signs with a private key held in memory
+type PrivateKeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewPrivateKeySigner creates a signer from an ECDSA private key
+func NewPrivateKeySigner(key *ecdsa.PrivateKey) *PrivateKeySigner {
+	return &PrivateKeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+// NewPrivateKeySignerFromHex creates a signer from a hex encoded private key, with or without the 0x prefix
+func NewPrivateKeySignerFromHex(hexKey string) (*PrivateKeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	return NewPrivateKeySigner(key), nil
+}
+
+// NewKeystoreSigner creates a signer from an encrypted Ethereum JSON keystore file
+func NewKeystoreSigner(path, password string) (*PrivateKeySigner, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+	}
+
+	return NewPrivateKeySigner(key.PrivateKey), nil
+}
+
+// Address returns the address of the private key
+func (s *PrivateKeySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTypedData signs the EIP-712 digest of the typed data
+func (s *PrivateKeySigner) SignTypedData(_ context.Context, typedData apitypes.TypedData) ([]byte, error) {
+	hash, err := evm.HashTypedData(typedData)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign typed data: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return signature, nil
+}
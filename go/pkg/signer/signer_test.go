@@ -0,0 +1,152 @@
+package signer_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+	"github.com/coinbase/x402/go/pkg/signer"
+)
+
+const testPrivateKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func newTestTypedData() apitypes.TypedData {
+	auth := &evm.TransferWithAuthorization{
+		From:        common.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"),
+		To:          common.HexToAddress("0x209693Bc6afc0C5328bA36FaF03C514EF312287C"),
+		Value:       big.NewInt(10000),
+		ValidAfter:  big.NewInt(1740672089),
+		ValidBefore: big.NewInt(1740672154),
+		Nonce:       [32]byte{1, 2, 3},
+	}
+
+	return auth.TypedData(evm.Domain{
+		Name:              "USDC",
+		Version:           "2",
+		ChainID:           big.NewInt(84532),
+		VerifyingContract: common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"),
+	})
+}
+
+// assertRecoverable checks that the signature recovers to the signer's address.
+func assertRecoverable(t *testing.T, s signer.Signer) {
+	t.Helper()
+
+	typedData := newTestTypedData()
+	signature, err := s.SignTypedData(context.Background(), typedData)
+	require.NoError(t, err)
+	require.Len(t, signature, crypto.SignatureLength)
+	assert.Contains(t, []byte{27, 28}, signature[crypto.RecoveryIDOffset])
+
+	recovered, err := evm.RecoverTypedDataSigner(typedData, signature)
+	require.NoError(t, err)
+	assert.Equal(t, s.Address(), recovered)
+}
+
+func TestPrivateKeySigner(t *testing.T) {
+	s, err := signer.NewPrivateKeySignerFromHex(testPrivateKey)
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"), s.Address())
+
+	assertRecoverable(t, s)
+
+	_, err = signer.NewPrivateKeySignerFromHex("0xnotakey")
+	assert.Error(t, err)
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, err := crypto.HexToECDSA(testPrivateKey[2:])
+	require.NoError(t, err)
+
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, "correct horse", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	require.NoError(t, os.WriteFile(path, keyJSON, 0o600))
+
+	s, err := signer.NewKeystoreSigner(path, "correct horse")
+	require.NoError(t, err)
+	assertRecoverable(t, s)
+
+	_, err = signer.NewKeystoreSigner(path, "wrong password")
+	assert.Error(t, err)
+}
+
+func TestRemoteSigner(t *testing.T) {
+	local, err := signer.NewPrivateKeySignerFromHex(testPrivateKey)
+	require.NoError(t, err)
+
+	var capturedAuthHeader string
+
+	// Stub remote signer implementing eth_signTypedData_v4 with the local key
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedAuthHeader = r.Header.Get("Authorization")
+
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_signTypedData_v4" || len(req.Params) != 2 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		var address string
+		var typedData apitypes.TypedData
+		json.Unmarshal(req.Params[0], &address)
+		json.Unmarshal(req.Params[1], &typedData)
+
+		if common.HexToAddress(address) != local.Address() {
+			json.NewEncoder(w).Encode(map[string]any{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"error":   map[string]any{"code": -32000, "message": "unknown account"},
+			})
+			return
+		}
+
+		signature, err := local.SignTypedData(r.Context(), typedData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Return the signature with a 0/1 recovery id to check it is normalized
+		signature[crypto.RecoveryIDOffset] -= 27
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"result":  hexutil.Encode(signature),
+		})
+	}))
+	defer server.Close()
+
+	remote := signer.NewRemoteSigner(server.URL, local.Address())
+	remote.Headers = map[string]string{"Authorization": "Bearer test-token"}
+
+	assertRecoverable(t, remote)
+	assert.Equal(t, "Bearer test-token", capturedAuthHeader)
+
+	unknown := signer.NewRemoteSigner(server.URL, common.HexToAddress("0x209693Bc6afc0C5328bA36FaF03C514EF312287C"))
+	_, err = unknown.SignTypedData(context.Background(), newTestTypedData())
+	assert.ErrorContains(t, err, "unknown account")
+}
@@ -0,0 +1,285 @@
+package svm
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/x402/go/pkg/money"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ExactSvmPayload represents the payload for an exact SVM payment: a base64 encoded SPL token
+// TransferChecked transaction signed by the payer, and left for the fee payer to sign
+type ExactSvmPayload struct {
+	Transaction string `json:"transaction"`
+}
+
+// transferCheckedInstruction is the SPL token TransferChecked instruction discriminator
+const transferCheckedInstruction = 12
+
+// SetPrice sets the network of exact SVM payment requirements, with the amount and mint of the
+// price. USD prices are paid in the network's USDC, token prices in the mint of their asset
+// address, which has no EIP-712 domain.
+func SetPrice(requirements *types.PaymentRequirements, price types.Price, network string) error {
+	solanaNetwork, err := LookupNetwork(network)
+	if err != nil {
+		return err
+	}
+
+	if price.Token == nil {
+		amount, err := money.ParseUSDC(price.Money)
+		if err != nil {
+			return fmt.Errorf("invalid money amount: %w", err)
+		}
+		if amount.Sign() <= 0 {
+			return fmt.Errorf("invalid money amount: %q must be positive", price.Money)
+		}
+
+		requirements.Network = solanaNetwork.Name
+		requirements.MaxAmountRequired = amount.String()
+		requirements.Asset = solanaNetwork.USDCMint
+		return nil
+	}
+
+	amount, ok := new(big.Int).SetString(price.Token.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return fmt.Errorf("invalid token amount: %q", price.Token.Amount)
+	}
+	if _, err := ParsePublicKey(price.Token.Asset.Address); err != nil {
+		return fmt.Errorf("invalid mint: %w", err)
+	}
+
+	requirements.Network = solanaNetwork.Name
+	requirements.MaxAmountRequired = price.Token.Amount
+	requirements.Asset = price.Token.Asset.Address
+	return nil
+}
+
+// FeePayer returns the fee payer of the requirements, read from their extra field, if any
+func FeePayer(requirements *types.PaymentRequirements) (PublicKey, bool, error) {
+	var extra struct {
+		FeePayer string `json:"feePayer"`
+	}
+	if requirements.Extra != nil {
+		if err := json.Unmarshal(*requirements.Extra, &extra); err != nil {
+			return PublicKey{}, false, fmt.Errorf("failed to unmarshal extra: %w", err)
+		}
+	}
+	if extra.FeePayer == "" {
+		return PublicKey{}, false, nil
+	}
+
+	feePayer, err := ParsePublicKey(extra.FeePayer)
+	if err != nil {
+		return PublicKey{}, false, fmt.Errorf("invalid fee payer: %w", err)
+	}
+	return feePayer, true, nil
+}
+
+// NewExactTransaction builds the unsigned transaction of an exact SVM payment of the requirements
+// from the owner's associated token account. The fee is paid by the fee payer of the requirements,
+// or by the owner if they have none.
+func NewExactTransaction(requirements *types.PaymentRequirements, owner PublicKey, decimals uint8, recentBlockhash [32]byte) (*Transaction, error) {
+	mint, err := ParsePublicKey(requirements.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset: %w", err)
+	}
+	payTo, err := ParsePublicKey(requirements.PayTo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payTo: %w", err)
+	}
+	amount, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+	if !ok || amount.Sign() < 0 || !amount.IsUint64() {
+		return nil, fmt.Errorf("invalid maxAmountRequired: %q", requirements.MaxAmountRequired)
+	}
+	feePayer, ok, err := FeePayer(requirements)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		feePayer = owner
+	}
+
+	source, err := AssociatedTokenAddress(owner, mint, TokenProgramID)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := AssociatedTokenAddress(payTo, mint, TokenProgramID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 10)
+	data[0] = transferCheckedInstruction
+	binary.LittleEndian.PutUint64(data[1:9], amount.Uint64())
+	data[9] = decimals
+
+	// Accounts are ordered writable signers, read-only signers, writable and read-only accounts
+	message := Message{
+		Header:          MessageHeader{NumRequiredSignatures: 1, NumReadonlyUnsignedAccounts: 2},
+		AccountKeys:     []PublicKey{feePayer},
+		RecentBlockhash: recentBlockhash,
+	}
+	if feePayer != owner {
+		message.Header.NumRequiredSignatures = 2
+		message.Header.NumReadonlySignedAccounts = 1
+		message.AccountKeys = append(message.AccountKeys, owner)
+	}
+	ownerIndex := uint8(len(message.AccountKeys) - 1)
+	message.AccountKeys = append(message.AccountKeys, source, destination, mint, TokenProgramID)
+	message.Instructions = []CompiledInstruction{{
+		ProgramIDIndex: ownerIndex + 4,
+		Accounts:       []uint8{ownerIndex + 1, ownerIndex + 3, ownerIndex + 2, ownerIndex},
+		Data:           data,
+	}}
+
+	return &Transaction{
+		Signatures: make([]Signature, message.Header.NumRequiredSignatures),
+		Message:    message,
+	}, nil
+}
+
+// CreateExactPaymentPayload creates an exact SVM payment payload for the requirements, a transfer
+// transaction signed by the key of the owner
+func CreateExactPaymentPayload(key ed25519.PrivateKey, x402Version int, requirements *types.PaymentRequirements, decimals uint8, recentBlockhash [32]byte) (*types.PaymentPayload, error) {
+	var owner PublicKey
+	copy(owner[:], key.Public().(ed25519.PublicKey))
+
+	tx, err := NewExactTransaction(requirements, owner, decimals, recentBlockhash)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Sign(key); err != nil {
+		return nil, err
+	}
+
+	return &types.PaymentPayload{
+		X402Version:   x402Version,
+		Scheme:        types.SchemeExact,
+		Network:       requirements.Network,
+		SchemePayload: &ExactSvmPayload{Transaction: tx.EncodeToBase64String()},
+	}, nil
+}
+
+// Verify verifies an exact SVM payment offline: the transaction must only transfer the
+// maxAmountRequired of the asset to the associated token account of payTo, besides compute budget
+// instructions, and be signed by the transfer authority. Whether the payer holds the funds and the
+// transaction lands is only known once it is submitted.
+//
+// An invalid payment is reported through the returned VerifyResponse; an error is only returned
+// when the requirements are malformed.
+func Verify(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	svmPayload, ok := payload.SchemePayload.(*ExactSvmPayload)
+	if !ok || payload.Scheme != types.SchemeExact || requirements.Scheme != types.SchemeExact {
+		return invalid(types.ErrorReasonInvalidScheme, ""), nil
+	}
+	if payload.Network != requirements.Network {
+		return invalid(types.ErrorReasonInvalidNetwork, ""), nil
+	}
+	network, err := LookupNetwork(requirements.Network)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidNetwork, ""), nil
+	}
+
+	mint, err := ParsePublicKey(requirements.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset: %w", err)
+	}
+	payTo, err := ParsePublicKey(requirements.PayTo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payTo: %w", err)
+	}
+	maxAmountRequired, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+	if !ok || !maxAmountRequired.IsUint64() {
+		return nil, fmt.Errorf("invalid maxAmountRequired: %q", requirements.MaxAmountRequired)
+	}
+	feePayer, hasFeePayer, err := FeePayer(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := DecodeTransactionFromBase64(svmPayload.Transaction)
+	if err != nil {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransaction, ""), nil
+	}
+	message := &tx.Message
+	if len(message.AddressTableLookups) > 0 {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransaction, ""), nil
+	}
+
+	// The transaction must transfer with a single TransferChecked instruction, only preceded by
+	// compute budget instructions
+	var transfer *CompiledInstruction
+	var tokenProgram PublicKey
+	for i := range message.Instructions {
+		instruction := &message.Instructions[i]
+		program := message.AccountKeys[instruction.ProgramIDIndex]
+		switch {
+		case program == ComputeBudgetProgramID && transfer == nil:
+		case (program == TokenProgramID || program == Token2022ProgramID) && transfer == nil:
+			transfer = instruction
+			tokenProgram = program
+		default:
+			return invalid(types.ErrorReasonInvalidExactSvmPayloadTransactionInstructions, ""), nil
+		}
+	}
+	if transfer == nil || len(transfer.Data) != 10 || transfer.Data[0] != transferCheckedInstruction || len(transfer.Accounts) < 4 {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransactionInstructions, ""), nil
+	}
+	for _, index := range transfer.Accounts {
+		if int(index) >= len(message.AccountKeys) {
+			return invalid(types.ErrorReasonInvalidExactSvmPayloadTransaction, ""), nil
+		}
+	}
+
+	ownerIndex := int(transfer.Accounts[3])
+	owner := message.AccountKeys[ownerIndex]
+	payer := owner.String()
+
+	// Verify the transfer is of the agreed upon amount, mint and recipient
+	if binary.LittleEndian.Uint64(transfer.Data[1:9]) != maxAmountRequired.Uint64() {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransactionAmountMismatch, payer), nil
+	}
+	if message.AccountKeys[transfer.Accounts[1]] != mint {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransactionMintMismatch, payer), nil
+	}
+	if requirements.Asset == network.USDCMint && transfer.Data[9] != USDCDecimals {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransactionAmountMismatch, payer), nil
+	}
+	destination, err := AssociatedTokenAddress(payTo, mint, tokenProgram)
+	if err != nil {
+		return nil, err
+	}
+	if message.AccountKeys[transfer.Accounts[2]] != destination {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransactionRecipientMismatch, payer), nil
+	}
+
+	// Verify the fee is paid by the fee payer of the requirements, who does not authorize the transfer
+	if hasFeePayer && (message.AccountKeys[0] != feePayer || owner == feePayer) {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransactionFeePayerMismatch, payer), nil
+	}
+
+	// Verify the transfer is signed by its authority
+	if !tx.VerifySignature(ownerIndex) {
+		return invalid(types.ErrorReasonInvalidExactSvmPayloadTransactionSignature, payer), nil
+	}
+
+	return &types.VerifyResponse{
+		IsValid: true,
+		Payer:   &payer,
+	}, nil
+}
+
+func invalid(reason types.ErrorReason, payer string) *types.VerifyResponse {
+	response := &types.VerifyResponse{
+		IsValid:       false,
+		InvalidReason: types.ReasonPtr(reason),
+	}
+	if payer != "" {
+		response.Payer = &payer
+	}
+	return response
+}
@@ -0,0 +1,262 @@
+package svm_test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/svm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// testEnv holds the keys of the payer, the fee payer and the recipient of exact SVM payments.
+type testEnv struct {
+	owner    ed25519.PrivateKey
+	feePayer ed25519.PrivateKey
+	payTo    svm.PublicKey
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	_, owner, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, feePayer, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	payTo, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	return &testEnv{owner: owner, feePayer: feePayer, payTo: publicKey(payTo)}
+}
+
+func publicKey(key ed25519.PublicKey) svm.PublicKey {
+	var publicKey svm.PublicKey
+	copy(publicKey[:], key)
+	return publicKey
+}
+
+func (e *testEnv) requirements(t *testing.T) *types.PaymentRequirements {
+	t.Helper()
+
+	requirements := &types.PaymentRequirements{
+		Scheme:            types.SchemeExact,
+		PayTo:             e.payTo.String(),
+		MaxTimeoutSeconds: 60,
+	}
+	require.NoError(t, requirements.SetPrice(types.USD("$0.01"), "solana-devnet"))
+
+	extra, err := json.Marshal(map[string]any{"feePayer": publicKey(e.feePayer.Public().(ed25519.PublicKey)).String()})
+	require.NoError(t, err)
+	rawMessage := json.RawMessage(extra)
+	requirements.Extra = &rawMessage
+
+	return requirements
+}
+
+// payload builds an exact SVM payload for the requirements, mutating the transaction before the owner signs it.
+func (e *testEnv) payload(t *testing.T, requirements *types.PaymentRequirements, mutate func(*svm.Transaction)) *types.PaymentPayload {
+	t.Helper()
+
+	tx, err := svm.NewExactTransaction(requirements, publicKey(e.owner.Public().(ed25519.PublicKey)), svm.USDCDecimals, [32]byte{1})
+	require.NoError(t, err)
+	if mutate != nil {
+		mutate(tx)
+	}
+	require.NoError(t, tx.Sign(e.owner))
+
+	return &types.PaymentPayload{
+		X402Version:   1,
+		Scheme:        types.SchemeExact,
+		Network:       requirements.Network,
+		SchemePayload: &svm.ExactSvmPayload{Transaction: tx.EncodeToBase64String()},
+	}
+}
+
+func TestSetPrice(t *testing.T) {
+	requirements := &types.PaymentRequirements{Scheme: types.SchemeExact}
+	require.NoError(t, requirements.SetPrice(types.USD("$0.01"), "solana"))
+	assert.Equal(t, "solana", requirements.Network)
+	assert.Equal(t, "10000", requirements.MaxAmountRequired)
+	assert.Equal(t, "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", requirements.Asset)
+
+	require.NoError(t, requirements.SetPrice(types.USD("$1"), "solana-devnet"))
+	assert.Equal(t, "1000000", requirements.MaxAmountRequired)
+	assert.Equal(t, "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU", requirements.Asset)
+
+	// SPL token prices have no EIP-712 domain
+	require.NoError(t, requirements.SetPrice(types.TokenAmount("2500", types.ERC20Asset{Address: "HzwqbKZw8HxMN6bF2yFZNrht3c2iXXzpKcFu7uBEDKtr", Decimals: 6}), "solana"))
+	assert.Equal(t, "2500", requirements.MaxAmountRequired)
+	assert.Equal(t, "HzwqbKZw8HxMN6bF2yFZNrht3c2iXXzpKcFu7uBEDKtr", requirements.Asset)
+
+	assert.Error(t, requirements.SetPrice(types.TokenAmount("1", types.ERC20Asset{Address: "0x036CbD53842c5426634e7929541eC2318f3dCF7e"}), "solana"))
+}
+
+func TestVerify_ValidPayment(t *testing.T) {
+	env := newTestEnv(t)
+	requirements := env.requirements(t)
+
+	// The payload goes through the X-PAYMENT header encoding
+	encoded, err := env.payload(t, requirements, nil).EncodeToBase64String()
+	require.NoError(t, err)
+	payload, err := types.DecodePaymentPayloadFromBase64(encoded)
+	require.NoError(t, err)
+
+	resp, err := svm.Verify(payload, requirements)
+	require.NoError(t, err)
+	assert.True(t, resp.IsValid)
+	require.NotNil(t, resp.Payer)
+	assert.Equal(t, publicKey(env.owner.Public().(ed25519.PublicKey)).String(), *resp.Payer)
+}
+
+func TestVerify_InvalidPayments(t *testing.T) {
+	otherMint := "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
+
+	testCases := []struct {
+		name               string
+		mutateTransaction  func(*svm.Transaction)
+		mutatePayload      func(*types.PaymentPayload)
+		mutateRequirements func(*types.PaymentRequirements)
+		expectedReason     types.ErrorReason
+	}{
+		{
+			name: "amount mismatch",
+			mutateRequirements: func(r *types.PaymentRequirements) {
+				r.MaxAmountRequired = "20000"
+			},
+			expectedReason: types.ErrorReasonInvalidExactSvmPayloadTransactionAmountMismatch,
+		},
+		{
+			name: "mint mismatch",
+			mutateRequirements: func(r *types.PaymentRequirements) {
+				r.Asset = otherMint
+			},
+			expectedReason: types.ErrorReasonInvalidExactSvmPayloadTransactionMintMismatch,
+		},
+		{
+			name: "recipient mismatch",
+			mutateRequirements: func(r *types.PaymentRequirements) {
+				r.PayTo = otherMint
+			},
+			expectedReason: types.ErrorReasonInvalidExactSvmPayloadTransactionRecipientMismatch,
+		},
+		{
+			name: "fee payer mismatch",
+			mutateRequirements: func(r *types.PaymentRequirements) {
+				extra := json.RawMessage(`{"feePayer":"` + otherMint + `"}`)
+				r.Extra = &extra
+			},
+			expectedReason: types.ErrorReasonInvalidExactSvmPayloadTransactionFeePayerMismatch,
+		},
+		{
+			name: "unexpected instruction",
+			mutateTransaction: func(tx *svm.Transaction) {
+				tx.Message.Instructions = append(tx.Message.Instructions, svm.CompiledInstruction{
+					ProgramIDIndex: 0,
+					Data:           []byte{2},
+				})
+			},
+			expectedReason: types.ErrorReasonInvalidExactSvmPayloadTransactionInstructions,
+		},
+		{
+			name: "tampered transaction",
+			mutatePayload: func(p *types.PaymentPayload) {
+				tx, err := svm.DecodeTransactionFromBase64(p.SchemePayload.(*svm.ExactSvmPayload).Transaction)
+				if err != nil {
+					panic(err)
+				}
+				tx.Message.RecentBlockhash[0] = 2
+				p.SchemePayload = &svm.ExactSvmPayload{Transaction: tx.EncodeToBase64String()}
+			},
+			expectedReason: types.ErrorReasonInvalidExactSvmPayloadTransactionSignature,
+		},
+		{
+			name: "malformed transaction",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.SchemePayload = &svm.ExactSvmPayload{Transaction: "AQID"}
+			},
+			expectedReason: types.ErrorReasonInvalidExactSvmPayloadTransaction,
+		},
+		{
+			name: "network mismatch",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.Network = "solana"
+			},
+			expectedReason: types.ErrorReasonInvalidNetwork,
+		},
+		{
+			name: "evm payload",
+			mutatePayload: func(p *types.PaymentPayload) {
+				p.SchemePayload = nil
+			},
+			expectedReason: types.ErrorReasonInvalidScheme,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			requirements := env.requirements(t)
+			payload := env.payload(t, requirements, tc.mutateTransaction)
+			if tc.mutatePayload != nil {
+				tc.mutatePayload(payload)
+			}
+			if tc.mutateRequirements != nil {
+				tc.mutateRequirements(requirements)
+			}
+
+			resp, err := svm.Verify(payload, requirements)
+			require.NoError(t, err)
+			assert.False(t, resp.IsValid)
+			require.NotNil(t, resp.InvalidReason)
+			assert.Equal(t, tc.expectedReason, resp.Reason())
+		})
+	}
+}
+
+func TestVerify_UnsignedTransaction(t *testing.T) {
+	env := newTestEnv(t)
+	requirements := env.requirements(t)
+
+	tx, err := svm.NewExactTransaction(requirements, publicKey(env.owner.Public().(ed25519.PublicKey)), svm.USDCDecimals, [32]byte{1})
+	require.NoError(t, err)
+	payload := &types.PaymentPayload{
+		X402Version:   1,
+		Scheme:        types.SchemeExact,
+		Network:       requirements.Network,
+		SchemePayload: &svm.ExactSvmPayload{Transaction: tx.EncodeToBase64String()},
+	}
+
+	resp, err := svm.Verify(payload, requirements)
+	require.NoError(t, err)
+	assert.False(t, resp.IsValid)
+	assert.Equal(t, types.ErrorReasonInvalidExactSvmPayloadTransactionSignature, resp.Reason())
+}
+
+func TestTransaction_RoundTrip(t *testing.T) {
+	env := newTestEnv(t)
+	requirements := env.requirements(t)
+
+	tx, err := svm.NewExactTransaction(requirements, publicKey(env.owner.Public().(ed25519.PublicKey)), svm.USDCDecimals, [32]byte{1})
+	require.NoError(t, err)
+	require.NoError(t, tx.Sign(env.owner))
+	require.NoError(t, tx.Sign(env.feePayer))
+	assert.Error(t, tx.Sign(ed25519.NewKeyFromSeed(make([]byte, 32))))
+
+	decoded, err := svm.DecodeTransaction(tx.Serialize())
+	require.NoError(t, err)
+	assert.Equal(t, tx, decoded)
+	assert.True(t, decoded.VerifySignature(0))
+	assert.True(t, decoded.VerifySignature(1))
+
+	// v0 messages are supported
+	tx.Message.Versioned = true
+	decoded, err = svm.DecodeTransaction(tx.Serialize())
+	require.NoError(t, err)
+	assert.True(t, decoded.Message.Versioned)
+
+	_, err = svm.DecodeTransaction(append(tx.Serialize(), 0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+// Package svm implements the x402 exact scheme on Solana (SVM) networks, where the payment payload
+// is a partially signed SPL token transfer transaction.
+package svm
+
+import (
+	"fmt"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// NetworkFamily is the network family of the Solana networks, see types.NetworkFamily
+const NetworkFamily = "svm"
+
+// USDCDecimals is the number of decimals of the USDC mints
+const USDCDecimals = 6
+
+// Network describes a Solana network payments can be made on
+type Network struct {
+	// Name is the network name used in payment requirements (ex: "solana-devnet")
+	Name string
+	// USDCMint is the address of the network's USDC SPL token mint
+	USDCMint string
+}
+
+var networks = map[string]Network{
+	"solana": {
+		Name:     "solana",
+		USDCMint: "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+	},
+	"solana-devnet": {
+		Name:     "solana-devnet",
+		USDCMint: "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU",
+	},
+}
+
+func init() {
+	for name := range networks {
+		if err := types.RegisterNetworkFamily(name, NetworkFamily); err != nil {
+			panic(err)
+		}
+	}
+
+	if err := types.RegisterPayloadScheme(types.PayloadScheme{
+		Scheme:     types.SchemeExact,
+		Family:     NetworkFamily,
+		NewPayload: func() any { return &ExactSvmPayload{} },
+		Validate: func(payload any) error {
+			if payload.(*ExactSvmPayload).Transaction == "" {
+				return fmt.Errorf("transaction is required")
+			}
+			return nil
+		},
+		SetPrice: SetPrice,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// LookupNetwork returns the Solana network with the given name
+func LookupNetwork(name string) (Network, error) {
+	network, ok := networks[name]
+	if !ok {
+		return Network{}, fmt.Errorf("unsupported network: %s", name)
+	}
+
+	return network, nil
+}
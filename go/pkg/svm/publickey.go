@@ -0,0 +1,99 @@
+package svm
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"filippo.io/edwards25519"
+)
+
+// PublicKey is a Solana account address
+type PublicKey [32]byte
+
+// Well known program addresses
+var (
+	SystemProgramID          = MustParsePublicKey("11111111111111111111111111111111")
+	TokenProgramID           = MustParsePublicKey("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+	Token2022ProgramID       = MustParsePublicKey("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
+	AssociatedTokenProgramID = MustParsePublicKey("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL")
+	ComputeBudgetProgramID   = MustParsePublicKey("ComputeBudget111111111111111111111111111111")
+)
+
+// maxSeedLength is the maximum length of a program address seed
+const maxSeedLength = 32
+
+var errProgramAddressOnCurve = errors.New("program address is on the ed25519 curve")
+
+// ParsePublicKey parses a base58 encoded address
+func ParsePublicKey(s string) (PublicKey, error) {
+	b, err := DecodeBase58(s)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	if len(b) != len(PublicKey{}) {
+		return PublicKey{}, fmt.Errorf("invalid address %q: expected 32 bytes, got %d", s, len(b))
+	}
+
+	var key PublicKey
+	copy(key[:], b)
+	return key, nil
+}
+
+// MustParsePublicKey parses a base58 encoded address, panicking if it is invalid
+func MustParsePublicKey(s string) PublicKey {
+	key, err := ParsePublicKey(s)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// String returns the base58 encoded address
+func (k PublicKey) String() string {
+	return EncodeBase58(k[:])
+}
+
+// CreateProgramAddress derives a program address from seeds, failing if the address is on the
+// ed25519 curve
+func CreateProgramAddress(seeds [][]byte, programID PublicKey) (PublicKey, error) {
+	hash := sha256.New()
+	for _, seed := range seeds {
+		if len(seed) > maxSeedLength {
+			return PublicKey{}, fmt.Errorf("program address seed is longer than %d bytes", maxSeedLength)
+		}
+		hash.Write(seed)
+	}
+	hash.Write(programID[:])
+	hash.Write([]byte("ProgramDerivedAddress"))
+
+	var address PublicKey
+	copy(address[:], hash.Sum(nil))
+	if _, err := new(edwards25519.Point).SetBytes(address[:]); err == nil {
+		return PublicKey{}, errProgramAddressOnCurve
+	}
+
+	return address, nil
+}
+
+// FindProgramAddress finds the program derived address of seeds with the highest valid bump seed
+func FindProgramAddress(seeds [][]byte, programID PublicKey) (PublicKey, uint8, error) {
+	for bump := 255; bump >= 0; bump-- {
+		address, err := CreateProgramAddress(append(seeds[:len(seeds):len(seeds)], []byte{byte(bump)}), programID)
+		if errors.Is(err, errProgramAddressOnCurve) {
+			continue
+		}
+		if err != nil {
+			return PublicKey{}, 0, err
+		}
+		return address, uint8(bump), nil
+	}
+
+	return PublicKey{}, 0, fmt.Errorf("unable to find a valid program address")
+}
+
+// AssociatedTokenAddress returns the associated token account of the owner for the mint
+func AssociatedTokenAddress(owner, mint, tokenProgramID PublicKey) (PublicKey, error) {
+	address, _, err := FindProgramAddress([][]byte{owner[:], tokenProgramID[:], mint[:]}, AssociatedTokenProgramID)
+	return address, err
+}
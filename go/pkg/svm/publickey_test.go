@@ -0,0 +1,67 @@
+package svm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/svm"
+)
+
+func TestBase58(t *testing.T) {
+	testCases := []struct {
+		decoded []byte
+		encoded string
+	}{
+		{decoded: []byte{}, encoded: ""},
+		{decoded: []byte{0, 0, 1}, encoded: "112"},
+		{decoded: []byte("hello world"), encoded: "StV1DL6CwTryKyV"},
+		{decoded: make([]byte, 32), encoded: "11111111111111111111111111111111"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.encoded, svm.EncodeBase58(tc.decoded))
+		decoded, err := svm.DecodeBase58(tc.encoded)
+		require.NoError(t, err)
+		assert.Equal(t, tc.decoded, decoded)
+	}
+
+	_, err := svm.DecodeBase58("0OIl")
+	assert.Error(t, err)
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key, err := svm.ParsePublicKey("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	require.NoError(t, err)
+	assert.Equal(t, "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", key.String())
+
+	_, err = svm.ParsePublicKey("0x209693Bc6afc0C5328bA36FaF03C514EF312287C")
+	assert.Error(t, err)
+	_, err = svm.ParsePublicKey("1111")
+	assert.Error(t, err)
+}
+
+func TestCreateProgramAddress(t *testing.T) {
+	programID := svm.MustParsePublicKey("BPFLoader1111111111111111111111111111111111")
+	seedKey := svm.MustParsePublicKey("SeedPubey1111111111111111111111111111111111")
+
+	testCases := []struct {
+		seeds    [][]byte
+		expected string
+	}{
+		{seeds: [][]byte{{}, {1}}, expected: "3gF2KMe9KiC6FNVBmfg9i267aMPvK37FewCip4eGBFcT"},
+		{seeds: [][]byte{[]byte("☉")}, expected: "7ytmC1nT1xY4RfxCV2ZgyA7UakC93do5ZdyhdF3EtPj7"},
+		{seeds: [][]byte{[]byte("Talking"), []byte("Squirrels")}, expected: "HwRVBufQ4haG5XSgpspwKtNd3PC9GM9m1196uJW36vds"},
+		{seeds: [][]byte{seedKey[:]}, expected: "GUs5qLUfsEHkcMB9T38vjr18ypEhRuNWiePW2LoK4E3K"},
+	}
+
+	for _, tc := range testCases {
+		address, err := svm.CreateProgramAddress(tc.seeds, programID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, address.String())
+	}
+
+	_, err := svm.CreateProgramAddress([][]byte{make([]byte, 33)}, programID)
+	assert.Error(t, err)
+}
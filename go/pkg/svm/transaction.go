@@ -0,0 +1,313 @@
+package svm
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Signature is an ed25519 transaction signature
+type Signature [ed25519.SignatureSize]byte
+
+// messageVersionPrefix marks versioned messages, legacy messages start with the number of signatures
+const messageVersionPrefix = 0x80
+
+// Transaction is a Solana transaction: a message and the signatures of its signers.
+// Signatures of signers that have not signed yet are zero.
+type Transaction struct {
+	Signatures []Signature
+	Message    Message
+}
+
+// MessageHeader describes the signer and read-only accounts of a message
+type MessageHeader struct {
+	NumRequiredSignatures       uint8
+	NumReadonlySignedAccounts   uint8
+	NumReadonlyUnsignedAccounts uint8
+}
+
+// Message is a legacy or v0 transaction message
+type Message struct {
+	// Versioned reports whether the message is a v0 message
+	Versioned       bool
+	Header          MessageHeader
+	AccountKeys     []PublicKey
+	RecentBlockhash [32]byte
+	Instructions    []CompiledInstruction
+	// AddressTableLookups are the address lookup tables of a v0 message
+	AddressTableLookups []AddressTableLookup
+}
+
+// CompiledInstruction is an instruction referencing its program and accounts by index in the
+// account keys of its message
+type CompiledInstruction struct {
+	ProgramIDIndex uint8
+	Accounts       []uint8
+	Data           []byte
+}
+
+// AddressTableLookup loads accounts of a v0 message from an address lookup table
+type AddressTableLookup struct {
+	AccountKey      PublicKey
+	WritableIndexes []uint8
+	ReadonlyIndexes []uint8
+}
+
+// DecodeTransactionFromBase64 decodes a base64 encoded wire transaction
+func DecodeTransactionFromBase64(encoded string) (*Transaction, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 string: %w", err)
+	}
+
+	return DecodeTransaction(data)
+}
+
+// DecodeTransaction decodes a wire transaction
+func DecodeTransaction(data []byte) (*Transaction, error) {
+	d := &decoder{data: data}
+
+	numSignatures := d.compactU16()
+	tx := &Transaction{Signatures: make([]Signature, 0, min(numSignatures, len(data)/ed25519.SignatureSize))}
+	for i := 0; i < numSignatures && d.err == nil; i++ {
+		var signature Signature
+		copy(signature[:], d.bytes(ed25519.SignatureSize))
+		tx.Signatures = append(tx.Signatures, signature)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid transaction: %w", d.err)
+	}
+
+	message, err := DecodeMessage(data[d.offset:])
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.Signatures) != int(message.Header.NumRequiredSignatures) {
+		return nil, fmt.Errorf("invalid transaction: %d signatures for %d required signers", len(tx.Signatures), message.Header.NumRequiredSignatures)
+	}
+	tx.Message = *message
+
+	return tx, nil
+}
+
+// DecodeMessage decodes a serialized legacy or v0 message
+func DecodeMessage(data []byte) (*Message, error) {
+	d := &decoder{data: data}
+	message := &Message{}
+
+	prefix := d.byte()
+	if prefix&messageVersionPrefix != 0 {
+		if version := prefix &^ messageVersionPrefix; version != 0 {
+			return nil, fmt.Errorf("unsupported message version: %d", version)
+		}
+		message.Versioned = true
+		message.Header.NumRequiredSignatures = d.byte()
+	} else {
+		message.Header.NumRequiredSignatures = prefix
+	}
+	message.Header.NumReadonlySignedAccounts = d.byte()
+	message.Header.NumReadonlyUnsignedAccounts = d.byte()
+
+	numAccounts := d.compactU16()
+	for i := 0; i < numAccounts && d.err == nil; i++ {
+		message.AccountKeys = append(message.AccountKeys, d.publicKey())
+	}
+	copy(message.RecentBlockhash[:], d.bytes(32))
+
+	numInstructions := d.compactU16()
+	for i := 0; i < numInstructions && d.err == nil; i++ {
+		instruction := CompiledInstruction{ProgramIDIndex: d.byte()}
+		instruction.Accounts = d.bytes(d.compactU16())
+		instruction.Data = d.bytes(d.compactU16())
+		message.Instructions = append(message.Instructions, instruction)
+	}
+
+	if message.Versioned {
+		numLookups := d.compactU16()
+		for i := 0; i < numLookups && d.err == nil; i++ {
+			lookup := AddressTableLookup{AccountKey: d.publicKey()}
+			lookup.WritableIndexes = d.bytes(d.compactU16())
+			lookup.ReadonlyIndexes = d.bytes(d.compactU16())
+			message.AddressTableLookups = append(message.AddressTableLookups, lookup)
+		}
+	}
+
+	if d.err == nil && d.offset != len(data) {
+		d.err = fmt.Errorf("%d trailing bytes", len(data)-d.offset)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid message: %w", d.err)
+	}
+	if int(message.Header.NumRequiredSignatures) > len(message.AccountKeys) {
+		return nil, fmt.Errorf("invalid message: %d signers for %d accounts", message.Header.NumRequiredSignatures, len(message.AccountKeys))
+	}
+	for _, instruction := range message.Instructions {
+		if int(instruction.ProgramIDIndex) >= len(message.AccountKeys) {
+			return nil, fmt.Errorf("invalid message: program index %d out of range", instruction.ProgramIDIndex)
+		}
+	}
+
+	return message, nil
+}
+
+// Serialize encodes the message, which is the data signed by the transaction signers
+func (m *Message) Serialize() []byte {
+	var buf bytes.Buffer
+
+	if m.Versioned {
+		buf.WriteByte(messageVersionPrefix)
+	}
+	buf.WriteByte(m.Header.NumRequiredSignatures)
+	buf.WriteByte(m.Header.NumReadonlySignedAccounts)
+	buf.WriteByte(m.Header.NumReadonlyUnsignedAccounts)
+
+	writeCompactU16(&buf, len(m.AccountKeys))
+	for _, key := range m.AccountKeys {
+		buf.Write(key[:])
+	}
+	buf.Write(m.RecentBlockhash[:])
+
+	writeCompactU16(&buf, len(m.Instructions))
+	for _, instruction := range m.Instructions {
+		buf.WriteByte(instruction.ProgramIDIndex)
+		writeCompactU16(&buf, len(instruction.Accounts))
+		buf.Write(instruction.Accounts)
+		writeCompactU16(&buf, len(instruction.Data))
+		buf.Write(instruction.Data)
+	}
+
+	if m.Versioned {
+		writeCompactU16(&buf, len(m.AddressTableLookups))
+		for _, lookup := range m.AddressTableLookups {
+			buf.Write(lookup.AccountKey[:])
+			writeCompactU16(&buf, len(lookup.WritableIndexes))
+			buf.Write(lookup.WritableIndexes)
+			writeCompactU16(&buf, len(lookup.ReadonlyIndexes))
+			buf.Write(lookup.ReadonlyIndexes)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// IsSigner reports whether the account at index must sign the message
+func (m *Message) IsSigner(index int) bool {
+	return index < int(m.Header.NumRequiredSignatures)
+}
+
+// Serialize encodes the transaction in the wire format
+func (tx *Transaction) Serialize() []byte {
+	var buf bytes.Buffer
+
+	writeCompactU16(&buf, len(tx.Signatures))
+	for _, signature := range tx.Signatures {
+		buf.Write(signature[:])
+	}
+	buf.Write(tx.Message.Serialize())
+
+	return buf.Bytes()
+}
+
+// EncodeToBase64String encodes the wire transaction in base64, as carried by SVM payloads
+func (tx *Transaction) EncodeToBase64String() string {
+	return base64.StdEncoding.EncodeToString(tx.Serialize())
+}
+
+// Sign signs the message with the key of one of its signers
+func (tx *Transaction) Sign(key ed25519.PrivateKey) error {
+	var signer PublicKey
+	copy(signer[:], key.Public().(ed25519.PublicKey))
+
+	for i := 0; i < int(tx.Message.Header.NumRequiredSignatures); i++ {
+		if tx.Message.AccountKeys[i] != signer {
+			continue
+		}
+		if len(tx.Signatures) != int(tx.Message.Header.NumRequiredSignatures) {
+			tx.Signatures = make([]Signature, tx.Message.Header.NumRequiredSignatures)
+		}
+		copy(tx.Signatures[i][:], ed25519.Sign(key, tx.Message.Serialize()))
+		return nil
+	}
+
+	return fmt.Errorf("%s is not a signer of the transaction", signer)
+}
+
+// VerifySignature reports whether the account at index has a valid signature of the message
+func (tx *Transaction) VerifySignature(index int) bool {
+	if !tx.Message.IsSigner(index) || index >= len(tx.Signatures) {
+		return false
+	}
+
+	key := tx.Message.AccountKeys[index]
+	return ed25519.Verify(key[:], tx.Message.Serialize(), tx.Signatures[index][:])
+}
+
+// decoder reads the wire format, recording the first error
+type decoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data)-d.offset {
+		d.err = errUnexpectedEnd
+		return nil
+	}
+
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	b := d.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) publicKey() PublicKey {
+	var key PublicKey
+	copy(key[:], d.bytes(len(key)))
+	return key
+}
+
+// compactU16 reads a compact-u16, the variable length encoding of lengths in the wire format
+func (d *decoder) compactU16() int {
+	value := 0
+	for i := 0; i < 3; i++ {
+		b := d.byte()
+		if d.err != nil {
+			return 0
+		}
+		value |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return value
+		}
+	}
+
+	d.err = errors.New("invalid compact-u16")
+	return 0
+}
+
+// writeCompactU16 writes a length as a compact-u16
+func writeCompactU16(buf *bytes.Buffer, value int) {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value == 0 {
+			buf.WriteByte(b)
+			return
+		}
+		buf.WriteByte(b | 0x80)
+	}
+}
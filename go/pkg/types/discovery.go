@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+// ResourceTypeHTTP is the type of resources served over HTTP
+const ResourceTypeHTTP = "http"
+
+// DiscoveredResource is a paid resource listed by a discovery endpoint
+type DiscoveredResource struct {
+	Resource    string                `json:"resource"`
+	Type        string                `json:"type"`
+	X402Version int                   `json:"x402Version"`
+	Accepts     []PaymentRequirements `json:"accepts"`
+	LastUpdated time.Time             `json:"lastUpdated"`
+	Metadata    map[string]any        `json:"metadata,omitempty"`
+}
+
+// ListDiscoveryResourcesRequest filters and paginates the resources of a discovery endpoint
+type ListDiscoveryResourcesRequest struct {
+	// Type is the type of the resources to list, all types are listed when empty
+	Type string
+	// Limit is the maximum number of resources to list, the endpoint's default when zero
+	Limit int
+	// Offset is the number of resources to skip
+	Offset int
+}
+
+// DiscoveryPagination is the pagination of a ListDiscoveryResourcesResponse
+type DiscoveryPagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
+
+// ListDiscoveryResourcesResponse represents the response from the discovery resources endpoint
+type ListDiscoveryResourcesResponse struct {
+	X402Version int                  `json:"x402Version"`
+	Items       []DiscoveredResource `json:"items"`
+	Pagination  DiscoveryPagination  `json:"pagination"`
+}
@@ -0,0 +1,128 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorReason is the reason a payment is invalid, see VerifyResponse.InvalidReason, or failed to
+// settle, see SettleResponse.ErrorReason. Reasons are errors, so that they can be matched with
+// errors.Is on the errors returned by VerifyResponse.Err and SettleResponse.Err.
+type ErrorReason string
+
+// Error returns the reason
+func (r ErrorReason) Error() string {
+	return string(r)
+}
+
+// Error reasons reported by facilitators. They include the reasons of the TypeScript
+// implementation, facilitators may report others.
+const (
+	ErrorReasonInsufficientFunds                                  ErrorReason = "insufficient_funds"
+	ErrorReasonInvalidExactEvmPayloadAuthorizationValidAfter      ErrorReason = "invalid_exact_evm_payload_authorization_valid_after"
+	ErrorReasonInvalidExactEvmPayloadAuthorizationValidBefore     ErrorReason = "invalid_exact_evm_payload_authorization_valid_before"
+	ErrorReasonInvalidExactEvmPayloadAuthorizationValue           ErrorReason = "invalid_exact_evm_payload_authorization_value"
+	ErrorReasonInvalidExactEvmPayloadSignature                    ErrorReason = "invalid_exact_evm_payload_signature"
+	ErrorReasonInvalidExactEvmPayloadRecipientMismatch            ErrorReason = "invalid_exact_evm_payload_recipient_mismatch"
+	ErrorReasonInvalidExactSvmPayloadTransaction                  ErrorReason = "invalid_exact_svm_payload_transaction"
+	ErrorReasonInvalidExactSvmPayloadTransactionAmountMismatch    ErrorReason = "invalid_exact_svm_payload_transaction_amount_mismatch"
+	ErrorReasonInvalidExactSvmPayloadTransactionInstructions      ErrorReason = "invalid_exact_svm_payload_transaction_instructions"
+	ErrorReasonInvalidExactSvmPayloadTransactionMintMismatch      ErrorReason = "invalid_exact_svm_payload_transaction_mint_mismatch"
+	ErrorReasonInvalidExactSvmPayloadTransactionRecipientMismatch ErrorReason = "invalid_exact_svm_payload_transaction_recipient_mismatch"
+	ErrorReasonInvalidExactSvmPayloadTransactionFeePayerMismatch  ErrorReason = "invalid_exact_svm_payload_transaction_fee_payer_mismatch"
+	ErrorReasonInvalidExactSvmPayloadTransactionSignature         ErrorReason = "invalid_exact_svm_payload_transaction_signature"
+	ErrorReasonInvalidNetwork                                     ErrorReason = "invalid_network"
+	ErrorReasonInvalidPayload                                     ErrorReason = "invalid_payload"
+	ErrorReasonInvalidPaymentRequirements                         ErrorReason = "invalid_payment_requirements"
+	ErrorReasonInvalidScheme                                      ErrorReason = "invalid_scheme"
+	ErrorReasonInvalidPayment                                     ErrorReason = "invalid_payment"
+	ErrorReasonPaymentExpired                                     ErrorReason = "payment_expired"
+	ErrorReasonUnsupportedScheme                                  ErrorReason = "unsupported_scheme"
+	ErrorReasonInvalidX402Version                                 ErrorReason = "invalid_x402_version"
+	ErrorReasonInvalidTransactionState                            ErrorReason = "invalid_transaction_state"
+	ErrorReasonUnexpectedVerifyError                              ErrorReason = "unexpected_verify_error"
+	ErrorReasonUnexpectedSettleError                              ErrorReason = "unexpected_settle_error"
+)
+
+var (
+	// ErrInvalidPayment is matched by the errors of payments the facilitator found invalid
+	ErrInvalidPayment = errors.New("invalid payment")
+	// ErrSettlementFailed is matched by the errors of payments the facilitator failed to settle
+	ErrSettlementFailed = errors.New("settlement failed")
+)
+
+// PaymentError is the error of a payment rejected by the facilitator. It matches with errors.Is
+// its kind, ErrInvalidPayment or ErrSettlementFailed, and its reason.
+type PaymentError struct {
+	// Kind is ErrInvalidPayment or ErrSettlementFailed
+	Kind   error
+	Reason ErrorReason
+	// Payer is the address of the payer, if known
+	Payer string
+	// Transaction is the settlement transaction, if any
+	Transaction string
+}
+
+func (e *PaymentError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Kind, e.Reason)
+}
+
+// Is reports whether target is the kind or the reason of the error
+func (e *PaymentError) Is(target error) bool {
+	return target == e.Kind || target == e.Reason
+}
+
+// Reason returns the reason the payment is invalid, or an empty reason if it is valid
+func (r *VerifyResponse) Reason() ErrorReason {
+	if r.IsValid {
+		return ""
+	}
+	if r.InvalidReason == nil || *r.InvalidReason == "" {
+		return ErrorReasonInvalidPayment
+	}
+	return ErrorReason(*r.InvalidReason)
+}
+
+// Err returns a *PaymentError if the payment is invalid, or nil
+func (r *VerifyResponse) Err() error {
+	if r.IsValid {
+		return nil
+	}
+
+	err := &PaymentError{Kind: ErrInvalidPayment, Reason: r.Reason()}
+	if r.Payer != nil {
+		err.Payer = *r.Payer
+	}
+	return err
+}
+
+// Reason returns the reason the settlement failed, or an empty reason if it succeeded or is pending
+func (r *SettleResponse) Reason() ErrorReason {
+	if r.Success || r.Pending() {
+		return ""
+	}
+	if r.ErrorReason == nil || *r.ErrorReason == "" {
+		return ErrorReasonUnexpectedSettleError
+	}
+	return ErrorReason(*r.ErrorReason)
+}
+
+// Err returns a *PaymentError if the settlement failed, or nil, including while it is pending
+func (r *SettleResponse) Err() error {
+	if r.Success || r.Pending() {
+		return nil
+	}
+
+	err := &PaymentError{Kind: ErrSettlementFailed, Reason: r.Reason(), Transaction: r.Transaction}
+	if r.Payer != nil {
+		err.Payer = *r.Payer
+	}
+	return err
+}
+
+// ReasonPtr returns a pointer to the reason, to set VerifyResponse.InvalidReason or
+// SettleResponse.ErrorReason
+func ReasonPtr(reason ErrorReason) *string {
+	s := string(reason)
+	return &s
+}
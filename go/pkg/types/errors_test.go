@@ -0,0 +1,85 @@
+package types_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestVerifyResponse_Err(t *testing.T) {
+	payer := "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+
+	testCases := []struct {
+		name           string
+		response       *types.VerifyResponse
+		expectedReason types.ErrorReason
+	}{
+		{
+			name:     "valid",
+			response: &types.VerifyResponse{IsValid: true, Payer: &payer},
+		},
+		{
+			name: "invalid with reason",
+			response: &types.VerifyResponse{
+				InvalidReason: types.ReasonPtr(types.ErrorReasonInsufficientFunds),
+				Payer:         &payer,
+			},
+			expectedReason: types.ErrorReasonInsufficientFunds,
+		},
+		{
+			name:           "invalid without reason",
+			response:       &types.VerifyResponse{},
+			expectedReason: types.ErrorReasonInvalidPayment,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedReason, tc.response.Reason())
+
+			err := tc.response.Err()
+			if tc.expectedReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			// Errors match their kind and reason, even when wrapped
+			wrapped := fmt.Errorf("failed to pay: %w", err)
+			assert.ErrorIs(t, wrapped, types.ErrInvalidPayment)
+			assert.ErrorIs(t, wrapped, tc.expectedReason)
+			assert.NotErrorIs(t, wrapped, types.ErrSettlementFailed)
+			assert.NotErrorIs(t, wrapped, types.ErrorReasonInvalidScheme)
+
+			var paymentErr *types.PaymentError
+			require.True(t, errors.As(wrapped, &paymentErr))
+			assert.Equal(t, tc.expectedReason, paymentErr.Reason)
+			if tc.response.Payer != nil {
+				assert.Equal(t, payer, paymentErr.Payer)
+			}
+		})
+	}
+}
+
+func TestSettleResponse_Err(t *testing.T) {
+	assert.NoError(t, (&types.SettleResponse{Success: true, Transaction: "0xtesthash"}).Err())
+
+	err := (&types.SettleResponse{
+		ErrorReason: types.ReasonPtr(types.ErrorReasonInvalidTransactionState),
+		Transaction: "0xtesthash",
+	}).Err()
+	assert.ErrorIs(t, err, types.ErrSettlementFailed)
+	assert.ErrorIs(t, err, types.ErrorReasonInvalidTransactionState)
+	assert.NotErrorIs(t, err, types.ErrInvalidPayment)
+	assert.EqualError(t, err, "settlement failed: invalid_transaction_state")
+
+	var paymentErr *types.PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, "0xtesthash", paymentErr.Transaction)
+
+	assert.ErrorIs(t, (&types.SettleResponse{}).Err(), types.ErrorReasonUnexpectedSettleError)
+}
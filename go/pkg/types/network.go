@@ -0,0 +1,160 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Network describes a network payments can be made on
+type Network struct {
+	// Name is the network name used in payment requirements (ex: "base-sepolia")
+	Name    string
+	ChainID int64
+	// USDCAddress is the address of the network's default USDC deployment
+	USDCAddress string
+	// USDCName and USDCVersion are the EIP-712 domain name and version of the USDC deployment
+	USDCName    string
+	USDCVersion string
+}
+
+var (
+	networksMu sync.RWMutex
+	networks   = map[string]Network{
+		"base-sepolia": {
+			Name:        "base-sepolia",
+			ChainID:     84532,
+			USDCAddress: "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			USDCName:    "USDC",
+			USDCVersion: "2",
+		},
+		"base": {
+			Name:        "base",
+			ChainID:     8453,
+			USDCAddress: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+			USDCName:    "USD Coin",
+			USDCVersion: "2",
+		},
+		"avalanche-fuji": {
+			Name:        "avalanche-fuji",
+			ChainID:     43113,
+			USDCAddress: "0x5425890298aed601595a70AB815c96711a31Bc65",
+			USDCName:    "USD Coin",
+			USDCVersion: "2",
+		},
+		"avalanche": {
+			Name:        "avalanche",
+			ChainID:     43114,
+			USDCAddress: "0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E",
+			USDCName:    "USDC",
+			USDCVersion: "2",
+		},
+	}
+)
+
+// RegisterNetwork adds a network to the registry, replacing any network with the same name
+func RegisterNetwork(network Network) error {
+	if network.Name == "" {
+		return fmt.Errorf("network name is required")
+	}
+	if network.ChainID <= 0 {
+		return fmt.Errorf("invalid chain ID for network %s: %d", network.Name, network.ChainID)
+	}
+
+	networksMu.Lock()
+	defer networksMu.Unlock()
+	networks[network.Name] = network
+
+	return nil
+}
+
+// LookupNetwork returns the registered network with the given name
+func LookupNetwork(name string) (Network, error) {
+	networksMu.RLock()
+	defer networksMu.RUnlock()
+
+	network, ok := networks[name]
+	if !ok {
+		return Network{}, fmt.Errorf("unsupported network: %s", name)
+	}
+
+	return network, nil
+}
+
+// NetworkFamilyEVM is the family of the EVM networks of the registry
+const NetworkFamilyEVM = "evm"
+
+var (
+	networkFamiliesMu sync.RWMutex
+	networkFamilies   = map[string]string{}
+)
+
+// RegisterNetworkFamily registers the family of a non-EVM network (ex: "svm" for "solana"), so that
+// its payment payloads are decoded with the schemes registered for the family
+func RegisterNetworkFamily(network, family string) error {
+	if network == "" || family == "" {
+		return fmt.Errorf("network and family are required")
+	}
+
+	networkFamiliesMu.Lock()
+	defer networkFamiliesMu.Unlock()
+	networkFamilies[network] = family
+
+	return nil
+}
+
+// NetworkFamily returns the family of a network: NetworkFamilyEVM for the registered networks,
+// or the family registered with RegisterNetworkFamily
+func NetworkFamily(network string) (string, error) {
+	if _, err := LookupNetwork(network); err == nil {
+		return NetworkFamilyEVM, nil
+	}
+
+	networkFamiliesMu.RLock()
+	defer networkFamiliesMu.RUnlock()
+
+	family, ok := networkFamilies[network]
+	if !ok {
+		return "", fmt.Errorf("unsupported network: %s", network)
+	}
+
+	return family, nil
+}
+
+// Networks returns the registered networks sorted by name
+func Networks() []Network {
+	networksMu.RLock()
+	defer networksMu.RUnlock()
+
+	result := make([]Network, 0, len(networks))
+	for _, network := range networks {
+		result = append(result, network)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result
+}
+
+// SetNetworkUSDC sets the network of the PaymentRequirements, with the network's USDC as asset
+// and its EIP-712 domain information in the Extra field
+func (p *PaymentRequirements) SetNetworkUSDC(name string) error {
+	network, err := LookupNetwork(name)
+	if err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(map[string]any{
+		"name":    network.USDCName,
+		"version": network.USDCVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal USDC info: %w", err)
+	}
+
+	rawMessage := json.RawMessage(jsonBytes)
+	p.Network = network.Name
+	p.Asset = network.USDCAddress
+	p.Extra = &rawMessage
+	return nil
+}
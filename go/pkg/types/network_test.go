@@ -0,0 +1,65 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestLookupNetwork(t *testing.T) {
+	testCases := []struct {
+		name            string
+		expectedChainID int64
+		expectedUSDC    string
+	}{
+		{name: "base", expectedChainID: 8453, expectedUSDC: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"},
+		{name: "base-sepolia", expectedChainID: 84532, expectedUSDC: "0x036CbD53842c5426634e7929541eC2318f3dCF7e"},
+		{name: "avalanche", expectedChainID: 43114, expectedUSDC: "0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E"},
+		{name: "avalanche-fuji", expectedChainID: 43113, expectedUSDC: "0x5425890298aed601595a70AB815c96711a31Bc65"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			network, err := types.LookupNetwork(tc.name)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedChainID, network.ChainID)
+			assert.Equal(t, tc.expectedUSDC, network.USDCAddress)
+			assert.Equal(t, "2", network.USDCVersion)
+		})
+	}
+
+	_, err := types.LookupNetwork("unknown")
+	assert.EqualError(t, err, "unsupported network: unknown")
+}
+
+func TestRegisterNetwork(t *testing.T) {
+	network := types.Network{
+		Name:        "test-chain",
+		ChainID:     31337,
+		USDCAddress: "0x5FbDB2315678afecb367f032d93F642f64180aa3",
+		USDCName:    "Test USDC",
+		USDCVersion: "1",
+	}
+	require.NoError(t, types.RegisterNetwork(network))
+
+	registered, err := types.LookupNetwork("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, network, registered)
+	assert.Contains(t, types.Networks(), network)
+
+	var requirements types.PaymentRequirements
+	require.NoError(t, requirements.SetNetworkUSDC("test-chain"))
+	assert.Equal(t, "test-chain", requirements.Network)
+	assert.Equal(t, network.USDCAddress, requirements.Asset)
+
+	var extra map[string]string
+	require.NoError(t, json.Unmarshal(*requirements.Extra, &extra))
+	assert.Equal(t, map[string]string{"name": "Test USDC", "version": "1"}, extra)
+
+	assert.Error(t, types.RegisterNetwork(types.Network{Name: "no-chain-id"}))
+	assert.Error(t, types.RegisterNetwork(types.Network{ChainID: 1}))
+}
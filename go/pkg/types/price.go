@@ -0,0 +1,150 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/x402/go/pkg/money"
+)
+
+// Price is the price of a resource, either a USD Money amount paid in the network's USDC
+// or an explicit ERC20TokenAmount
+type Price struct {
+	// Money is a USD denominated amount (ex: "$0.01", "0.01" or "0.01 USDC"), see money.ParseUSDC
+	Money string
+	// Token is an amount of a specific ERC-20 token, it takes precedence over Money
+	Token *ERC20TokenAmount
+}
+
+// ERC20TokenAmount is an amount of an ERC-20 token supporting EIP-3009
+type ERC20TokenAmount struct {
+	// Amount is the amount in the token's atomic units
+	Amount string     `json:"amount"`
+	Asset  ERC20Asset `json:"asset"`
+}
+
+// ERC20Asset describes an ERC-20 token supporting EIP-3009
+type ERC20Asset struct {
+	Address string `json:"address"`
+	// Decimals is the number of decimals of the token, see ParseTokenAmount
+	Decimals int `json:"decimals"`
+	// EIP712 is the domain the payers sign the EIP-3009 authorizations of the token with
+	EIP712 EIP712Domain `json:"eip712"`
+}
+
+// EIP712Domain is the EIP-712 domain name and version of a token
+type EIP712Domain struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// USD returns a price of amount US dollars (ex: "0.01")
+func USD(amount string) Price {
+	return Price{Money: amount}
+}
+
+// TokenAmount returns a price of amount atomic units of the asset
+func TokenAmount(amount string, asset ERC20Asset) Price {
+	return Price{Token: &ERC20TokenAmount{Amount: amount, Asset: asset}}
+}
+
+// ParseTokenAmount returns a price of the decimal amount (ex: "0.01") of the asset, converted to
+// atomic units with the asset's decimals
+func ParseTokenAmount(amount string, asset ERC20Asset) (Price, error) {
+	if asset.Decimals < 0 || asset.Decimals > 255 {
+		return Price{}, fmt.Errorf("invalid token decimals: %d", asset.Decimals)
+	}
+	units, err := money.ParseAtomicUnits(amount, asset.Decimals)
+	if err != nil {
+		return Price{}, fmt.Errorf("invalid token amount: %w", err)
+	}
+
+	return TokenAmount(units.String(), asset), nil
+}
+
+// Validate checks that the price is a positive money amount or a valid ERC-20 token amount, whose
+// asset has an address and an EIP-712 domain
+func (p Price) Validate() error {
+	if p.Token == nil {
+		_, err := usdcAtomicAmount(p.Money)
+		return err
+	}
+
+	amount, ok := new(big.Int).SetString(p.Token.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return fmt.Errorf("invalid token amount: %q", p.Token.Amount)
+	}
+	if p.Token.Asset.Address == "" {
+		return fmt.Errorf("token asset address is required")
+	}
+	if p.Token.Asset.EIP712.Name == "" || p.Token.Asset.EIP712.Version == "" {
+		return fmt.Errorf("token asset EIP-712 name and version are required")
+	}
+
+	return nil
+}
+
+// SetPrice sets the network of the PaymentRequirements, with the amount, asset and asset
+// information in the Extra field of the price. On non-EVM networks, the price is set by the
+// PayloadScheme registered for the scheme of the requirements.
+func (p *PaymentRequirements) SetPrice(price Price, network string) error {
+	if family, err := NetworkFamily(network); err == nil && family != NetworkFamilyEVM {
+		payloadScheme, err := LookupPayloadScheme(p.Scheme, network)
+		if err != nil {
+			return err
+		}
+		if payloadScheme.SetPrice == nil {
+			return fmt.Errorf("scheme %s does not support prices on network %s", p.Scheme, network)
+		}
+		return payloadScheme.SetPrice(p, price, network)
+	}
+
+	if price.Token == nil {
+		amount, err := usdcAtomicAmount(price.Money)
+		if err != nil {
+			return err
+		}
+		if err := p.SetNetworkUSDC(network); err != nil {
+			return err
+		}
+
+		p.MaxAmountRequired = amount
+		return nil
+	}
+
+	if _, err := LookupNetwork(network); err != nil {
+		return err
+	}
+	if err := price.Validate(); err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(map[string]any{
+		"name":    price.Token.Asset.EIP712.Name,
+		"version": price.Token.Asset.EIP712.Version,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal asset info: %w", err)
+	}
+
+	rawMessage := json.RawMessage(jsonBytes)
+	p.Network = network
+	p.MaxAmountRequired = price.Token.Amount
+	p.Asset = price.Token.Asset.Address
+	p.Extra = &rawMessage
+	return nil
+}
+
+// usdcAtomicAmount converts a USD amount to USDC atomic units
+func usdcAtomicAmount(amount string) (string, error) {
+	units, err := money.ParseUSDC(amount)
+	if err != nil {
+		return "", fmt.Errorf("invalid money amount: %w", err)
+	}
+	if units.Sign() <= 0 {
+		return "", fmt.Errorf("invalid money amount: %q must be positive", amount)
+	}
+
+	return units.String(), nil
+}
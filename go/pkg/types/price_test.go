@@ -0,0 +1,106 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestPaymentRequirements_SetPrice(t *testing.T) {
+	eurc := types.ERC20Asset{
+		Address:  "0x08210F9170F89Ab7658F0B5E3fF39b0E03C594D4",
+		Decimals: 6,
+		EIP712:   types.EIP712Domain{Name: "EURC", Version: "2"},
+	}
+
+	testCases := []struct {
+		name           string
+		price          types.Price
+		network        string
+		expectedAmount string
+		expectedAsset  string
+		expectedExtra  map[string]string
+		expectedError  string
+	}{
+		{
+			name:           "usd on base",
+			price:          types.USD("$0.01"),
+			network:        "base",
+			expectedAmount: "10000",
+			expectedAsset:  "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+			expectedExtra:  map[string]string{"name": "USD Coin", "version": "2"},
+		},
+		{
+			name:           "usd on avalanche-fuji",
+			price:          types.USD("0.001 USDC"),
+			network:        "avalanche-fuji",
+			expectedAmount: "1000",
+			expectedAsset:  "0x5425890298aed601595a70AB815c96711a31Bc65",
+			expectedExtra:  map[string]string{"name": "USD Coin", "version": "2"},
+		},
+		{
+			name:           "token amount",
+			price:          types.TokenAmount("1500000", eurc),
+			network:        "base-sepolia",
+			expectedAmount: "1500000",
+			expectedAsset:  eurc.Address,
+			expectedExtra:  map[string]string{"name": "EURC", "version": "2"},
+		},
+		{name: "invalid money", price: types.USD("free"), network: "base", expectedError: `invalid money amount: invalid amount: "free"`},
+		{name: "too precise money", price: types.USD("0.0000001"), network: "base", expectedError: "invalid money amount: amount 0.0000001 has more than 6 decimal places"},
+		{name: "zero money", price: types.USD("$0"), network: "base", expectedError: `invalid money amount: "$0" must be positive`},
+		{name: "invalid token amount", price: types.TokenAmount("1.5", eurc), network: "base", expectedError: `invalid token amount: "1.5"`},
+		{name: "unsupported network", price: types.TokenAmount("1", eurc), network: "unknown", expectedError: "unsupported network: unknown"},
+		{
+			name:          "token without eip712 domain",
+			price:         types.TokenAmount("1", types.ERC20Asset{Address: eurc.Address, Decimals: 6}),
+			network:       "base",
+			expectedError: "token asset EIP-712 name and version are required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requirements types.PaymentRequirements
+			err := requirements.SetPrice(tc.price, tc.network)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.network, requirements.Network)
+			assert.Equal(t, tc.expectedAmount, requirements.MaxAmountRequired)
+			assert.Equal(t, tc.expectedAsset, requirements.Asset)
+
+			var extra map[string]string
+			require.NoError(t, json.Unmarshal(*requirements.Extra, &extra))
+			assert.Equal(t, tc.expectedExtra, extra)
+		})
+	}
+}
+
+func TestParseTokenAmount(t *testing.T) {
+	eurc := types.ERC20Asset{
+		Address:  "0x08210F9170F89Ab7658F0B5E3fF39b0E03C594D4",
+		Decimals: 6,
+		EIP712:   types.EIP712Domain{Name: "EURC", Version: "2"},
+	}
+
+	price, err := types.ParseTokenAmount("1.5", eurc)
+	require.NoError(t, err)
+	assert.Equal(t, types.TokenAmount("1500000", eurc), price)
+
+	eurc.Decimals = 18
+	price, err = types.ParseTokenAmount("0.01", eurc)
+	require.NoError(t, err)
+	assert.Equal(t, "10000000000000000", price.Token.Amount)
+
+	eurc.Decimals = 2
+	_, err = types.ParseTokenAmount("0.001", eurc)
+	assert.Error(t, err)
+}
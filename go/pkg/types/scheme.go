@@ -0,0 +1,122 @@
+package types
+
+import (
+	"fmt"
+	"sync"
+)
+
+// X402Version is the version of the x402 protocol supported by this package
+const X402Version = 1
+
+// PayloadScheme describes how the payload of a payment scheme is decoded on a network family
+type PayloadScheme struct {
+	// Scheme is the payment scheme (ex: "exact")
+	Scheme string
+	// Family is the network family the payload applies to (ex: NetworkFamilyEVM)
+	Family string
+	// NewPayload returns a pointer to an empty payload to decode into
+	NewPayload func() any
+	// Validate checks the decoded payload, it may be nil
+	Validate func(payload any) error
+	// SetPrice sets the network, amount and asset of payment requirements on a network of the
+	// family, see PaymentRequirements.SetPrice. It is only used for non-EVM families and may be nil.
+	SetPrice func(requirements *PaymentRequirements, price Price, network string) error
+}
+
+// UnsupportedVersionError is returned when decoding a payment payload of an unsupported x402 version
+type UnsupportedVersionError struct {
+	Version int
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported x402Version: %d", e.Version)
+}
+
+// UnsupportedSchemeError is returned when decoding a payment payload whose scheme is not registered
+// for its network
+type UnsupportedSchemeError struct {
+	Scheme  string
+	Network string
+}
+
+func (e *UnsupportedSchemeError) Error() string {
+	return fmt.Sprintf("unsupported scheme %q on network %q", e.Scheme, e.Network)
+}
+
+type payloadSchemeKey struct {
+	scheme string
+	family string
+}
+
+var (
+	payloadSchemesMu sync.RWMutex
+	payloadSchemes   = map[payloadSchemeKey]PayloadScheme{}
+)
+
+func init() {
+	mustRegisterPayloadScheme(PayloadScheme{
+		Scheme:     SchemeExact,
+		Family:     NetworkFamilyEVM,
+		NewPayload: func() any { return &ExactEvmPayload{} },
+		Validate: func(payload any) error {
+			p := payload.(*ExactEvmPayload)
+			if p.Signature == "" || p.Authorization == nil {
+				return fmt.Errorf("signature and authorization are required")
+			}
+			return nil
+		},
+	})
+	mustRegisterPayloadScheme(PayloadScheme{
+		Scheme:     SchemeUpto,
+		Family:     NetworkFamilyEVM,
+		NewPayload: func() any { return &UptoEvmPayload{} },
+		Validate: func(payload any) error {
+			p := payload.(*UptoEvmPayload)
+			if p.Signature == "" || p.Permit == nil {
+				return fmt.Errorf("signature and permit are required")
+			}
+			return nil
+		},
+	})
+}
+
+// RegisterPayloadScheme adds a payload scheme to the registry, replacing any payload scheme with the
+// same scheme and family. Payloads of third party schemes are decoded into PaymentPayload.SchemePayload.
+func RegisterPayloadScheme(scheme PayloadScheme) error {
+	if scheme.Scheme == "" || scheme.Family == "" {
+		return fmt.Errorf("scheme and family are required")
+	}
+	if scheme.NewPayload == nil {
+		return fmt.Errorf("NewPayload is required for scheme %s", scheme.Scheme)
+	}
+
+	payloadSchemesMu.Lock()
+	defer payloadSchemesMu.Unlock()
+	payloadSchemes[payloadSchemeKey{scheme.Scheme, scheme.Family}] = scheme
+
+	return nil
+}
+
+func mustRegisterPayloadScheme(scheme PayloadScheme) {
+	if err := RegisterPayloadScheme(scheme); err != nil {
+		panic(err)
+	}
+}
+
+// LookupPayloadScheme returns the payload scheme registered for the scheme on the network's family
+func LookupPayloadScheme(scheme, network string) (PayloadScheme, error) {
+	family, err := NetworkFamily(network)
+	if err != nil {
+		return PayloadScheme{}, &UnsupportedSchemeError{Scheme: scheme, Network: network}
+	}
+
+	payloadSchemesMu.RLock()
+	defer payloadSchemesMu.RUnlock()
+
+	payloadScheme, ok := payloadSchemes[payloadSchemeKey{scheme, family}]
+	if !ok {
+		return PayloadScheme{}, &UnsupportedSchemeError{Scheme: scheme, Network: network}
+	}
+
+	return payloadScheme, nil
+}
@@ -0,0 +1,163 @@
+package types_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// testPayload is the payload of a third party scheme
+type testPayload struct {
+	Proof string `json:"proof"`
+}
+
+func encodePayload(t *testing.T, payload any) string {
+	t.Helper()
+
+	payloadJson, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(payloadJson)
+}
+
+func TestDecodePaymentPayload_Schemes(t *testing.T) {
+	exact := encodePayload(t, map[string]any{
+		"x402Version": 1,
+		"scheme":      "exact",
+		"network":     "base",
+		"payload": map[string]any{
+			"signature":     "0x01",
+			"authorization": map[string]any{"from": "0x857b06519E91e3A54538791bDbb0E22373e36b66", "value": "1000"},
+		},
+	})
+	payload, err := types.DecodePaymentPayloadFromBase64(exact)
+	require.NoError(t, err)
+	require.NotNil(t, payload.Payload)
+	assert.Equal(t, "1000", payload.Payload.Authorization.Value)
+	assert.Nil(t, payload.UptoPayload)
+
+	upto := encodePayload(t, map[string]any{
+		"x402Version": 1,
+		"scheme":      "upto",
+		"network":     "base-sepolia",
+		"payload": map[string]any{
+			"signature": "0x01",
+			"permit":    map[string]any{"owner": "0x857b06519E91e3A54538791bDbb0E22373e36b66", "value": "5000"},
+		},
+	})
+	payload, err = types.DecodePaymentPayloadFromBase64(upto)
+	require.NoError(t, err)
+	require.NotNil(t, payload.UptoPayload)
+	assert.Equal(t, "5000", payload.UptoPayload.Permit.Value)
+	assert.Nil(t, payload.Payload)
+
+	// The scheme payload is encoded back as is
+	encoded, err := payload.EncodeToBase64String()
+	require.NoError(t, err)
+	decoded, err := types.DecodePaymentPayloadFromBase64(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestDecodePaymentPayload_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		payload       map[string]any
+		versionError  bool
+		schemeError   bool
+		expectedError string
+	}{
+		{
+			name:         "unsupported version",
+			payload:      map[string]any{"x402Version": 2, "scheme": "exact", "network": "base"},
+			versionError: true,
+		},
+		{
+			name:         "missing version",
+			payload:      map[string]any{"scheme": "exact", "network": "base"},
+			versionError: true,
+		},
+		{
+			name:        "unknown scheme",
+			payload:     map[string]any{"x402Version": 1, "scheme": "stream", "network": "base"},
+			schemeError: true,
+		},
+		{
+			name:        "unknown network",
+			payload:     map[string]any{"x402Version": 1, "scheme": "exact", "network": "unknown"},
+			schemeError: true,
+		},
+		{
+			name: "invalid payload",
+			payload: map[string]any{
+				"x402Version": 1,
+				"scheme":      "exact",
+				"network":     "base",
+				"payload":     map[string]any{"signature": "0x01"},
+			},
+			expectedError: "invalid exact payload: signature and authorization are required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := types.DecodePaymentPayloadFromBase64(encodePayload(t, tc.payload))
+			require.Error(t, err)
+
+			var versionErr *types.UnsupportedVersionError
+			assert.Equal(t, tc.versionError, errors.As(err, &versionErr))
+			var schemeErr *types.UnsupportedSchemeError
+			assert.Equal(t, tc.schemeError, errors.As(err, &schemeErr))
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestRegisterPayloadScheme(t *testing.T) {
+	require.NoError(t, types.RegisterNetworkFamily("test-network", "test"))
+	require.NoError(t, types.RegisterPayloadScheme(types.PayloadScheme{
+		Scheme:     "exact",
+		Family:     "test",
+		NewPayload: func() any { return &testPayload{} },
+		Validate: func(payload any) error {
+			if payload.(*testPayload).Proof == "" {
+				return errors.New("proof is required")
+			}
+			return nil
+		},
+	}))
+
+	encoded := encodePayload(t, map[string]any{
+		"x402Version": 1,
+		"scheme":      "exact",
+		"network":     "test-network",
+		"payload":     map[string]any{"proof": "abc"},
+	})
+	payload, err := types.DecodePaymentPayloadFromBase64(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, &testPayload{Proof: "abc"}, payload.SchemePayload)
+	assert.Nil(t, payload.Payload)
+
+	reencoded, err := payload.EncodeToBase64String()
+	require.NoError(t, err)
+	decoded, err := types.DecodePaymentPayloadFromBase64(reencoded)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+
+	_, err = types.DecodePaymentPayloadFromBase64(encodePayload(t, map[string]any{
+		"x402Version": 1,
+		"scheme":      "exact",
+		"network":     "test-network",
+		"payload":     map[string]any{},
+	}))
+	assert.ErrorContains(t, err, "proof is required")
+
+	assert.Error(t, types.RegisterPayloadScheme(types.PayloadScheme{Scheme: "exact", Family: "test"}))
+}
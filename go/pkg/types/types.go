@@ -21,12 +21,101 @@ type PaymentRequirements struct {
 	Extra             *json.RawMessage `json:"extra,omitempty"`
 }
 
-// PaymentPayload represents the decoded payment payload for a client's payment
+// Payment schemes
+const (
+	// SchemeExact transfers a specific amount of funds
+	SchemeExact = "exact"
+	// SchemeUpto transfers up to an amount of funds, depending on the resources consumed
+	SchemeUpto = "upto"
+)
+
+// PaymentPayload represents the decoded payment payload for a client's payment.
+// The JSON payload is decoded with the PayloadScheme registered for the scheme and the network's
+// family: into Payload for exact EVM payments, into UptoPayload for upto EVM payments and into
+// SchemePayload for the schemes registered by third parties.
 type PaymentPayload struct {
 	X402Version int              `json:"x402Version"`
 	Scheme      string           `json:"scheme"`
 	Network     string           `json:"network"`
 	Payload     *ExactEvmPayload `json:"payload"`
+	UptoPayload *UptoEvmPayload  `json:"-"`
+	// SchemePayload is the payload of a third party scheme, see RegisterPayloadScheme
+	SchemePayload any `json:"-"`
+}
+
+// paymentPayloadJSON is the JSON representation of a PaymentPayload with its scheme specific payload
+type paymentPayloadJSON struct {
+	X402Version int             `json:"x402Version"`
+	Scheme      string          `json:"scheme"`
+	Network     string          `json:"network"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// MarshalJSON encodes the payload of the payment's scheme
+func (p PaymentPayload) MarshalJSON() ([]byte, error) {
+	var payload any = p.Payload
+	switch {
+	case p.SchemePayload != nil:
+		payload = p.SchemePayload
+	case p.Scheme == SchemeUpto:
+		payload = p.UptoPayload
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(paymentPayloadJSON{
+		X402Version: p.X402Version,
+		Scheme:      p.Scheme,
+		Network:     p.Network,
+		Payload:     payloadBytes,
+	})
+}
+
+// UnmarshalJSON decodes the payload with the PayloadScheme registered for the payment's scheme and
+// network. It returns an *UnsupportedSchemeError if none is registered.
+func (p *PaymentPayload) UnmarshalJSON(data []byte) error {
+	var raw paymentPayloadJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*p = PaymentPayload{
+		X402Version: raw.X402Version,
+		Scheme:      raw.Scheme,
+		Network:     raw.Network,
+	}
+
+	payloadScheme, err := LookupPayloadScheme(raw.Scheme, raw.Network)
+	if err != nil {
+		return err
+	}
+	if len(raw.Payload) == 0 || string(raw.Payload) == "null" {
+		return nil
+	}
+
+	payload := payloadScheme.NewPayload()
+	if err := json.Unmarshal(raw.Payload, payload); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", raw.Scheme, err)
+	}
+	if payloadScheme.Validate != nil {
+		if err := payloadScheme.Validate(payload); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", raw.Scheme, err)
+		}
+	}
+
+	switch payload := payload.(type) {
+	case *ExactEvmPayload:
+		p.Payload = payload
+	case *UptoEvmPayload:
+		p.UptoPayload = payload
+	default:
+		p.SchemePayload = payload
+	}
+
+	return nil
 }
 
 // ExactEvmPayloadAuthorization represents the payload for an exact EVM payment
@@ -46,6 +135,22 @@ type ExactEvmPayloadAuthorization struct {
 	Nonce       string `json:"nonce"`
 }
 
+// UptoEvmPayload represents the payload for an upto EVM payment, an EIP-2612 permit allowing
+// the spender to transfer up to the permitted value
+type UptoEvmPayload struct {
+	Signature string                `json:"signature"`
+	Permit    *UptoEvmPayloadPermit `json:"permit"`
+}
+
+// UptoEvmPayloadPermit represents the EIP-2612 permit EIP-712 typed data message of an upto EVM payment
+type UptoEvmPayloadPermit struct {
+	Owner    string `json:"owner"`
+	Spender  string `json:"spender"`
+	Value    string `json:"value"`
+	Nonce    string `json:"nonce"`
+	Deadline string `json:"deadline"`
+}
+
 // VerifyResponse represents the response from the verify endpoint
 type VerifyResponse struct {
 	IsValid       bool    `json:"isValid"`
@@ -60,6 +165,43 @@ type SettleResponse struct {
 	Transaction string  `json:"transaction"`
 	Network     string  `json:"network"`
 	Payer       *string `json:"payer,omitempty"`
+	// Status is SettleStatusPending when the payment is settled asynchronously by the resource
+	// server, the transaction being unknown yet
+	Status string `json:"status,omitempty"`
+	// ReceiptID identifies a pending settlement, to query its outcome from the resource server
+	ReceiptID string `json:"receiptId,omitempty"`
+}
+
+// SettleStatusPending is the status of a settlement queued by the resource server
+const SettleStatusPending = "pending"
+
+// Pending reports whether the payment is being settled asynchronously
+func (s *SettleResponse) Pending() bool {
+	return s.Status == SettleStatusPending
+}
+
+// SupportedPaymentKind represents a scheme and network pair a facilitator can verify and settle
+type SupportedPaymentKind struct {
+	X402Version int    `json:"x402Version"`
+	Scheme      string `json:"scheme"`
+	Network     string `json:"network"`
+	// Extra is scheme specific information, such as the permit spender of upto payments
+	Extra *json.RawMessage `json:"extra,omitempty"`
+}
+
+// SupportedPaymentKindsResponse represents the response from the supported endpoint
+type SupportedPaymentKindsResponse struct {
+	Kinds []SupportedPaymentKind `json:"kinds"`
+}
+
+// Supports reports whether the facilitator can verify and settle payments of scheme on network
+func (s *SupportedPaymentKindsResponse) Supports(scheme, network string) bool {
+	for _, kind := range s.Kinds {
+		if kind.Scheme == scheme && kind.Network == network {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *SettleResponse) EncodeToBase64String() (string, error) {
@@ -71,25 +213,66 @@ func (s *SettleResponse) EncodeToBase64String() (string, error) {
 	return base64.StdEncoding.EncodeToString(jsonBytes), nil
 }
 
-// DecodePaymentPayloadFromBase64 decodes a base64 encoded string into a PaymentPayload
+// EncodeToBase64String encodes the payment payload as the base64 value of the X-PAYMENT header
+func (p *PaymentPayload) EncodeToBase64String() (string, error) {
+	jsonBytes, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to base64 encode the payment payload: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(jsonBytes), nil
+}
+
+// DecodeSettleResponseFromBase64 decodes the base64 encoded X-PAYMENT-RESPONSE header into a SettleResponse
+func DecodeSettleResponseFromBase64(encoded string) (*SettleResponse, error) {
+	decodedBytes, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 string: %w", err)
+	}
+
+	var settleResponse SettleResponse
+	if err := json.Unmarshal(decodedBytes, &settleResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settle response: %w", err)
+	}
+
+	return &settleResponse, nil
+}
+
+// DecodePaymentPayloadFromBase64 decodes a base64 encoded string into a PaymentPayload.
+// It returns an *UnsupportedVersionError for payloads of another x402 version than X402Version,
+// and an *UnsupportedSchemeError for payloads of an unregistered scheme.
 func DecodePaymentPayloadFromBase64(encoded string) (*PaymentPayload, error) {
 	decodedBytes, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode base64 string: %w", err)
 	}
 
-	var payload PaymentPayload
-	if err := json.Unmarshal(decodedBytes, &payload); err != nil {
+	return DecodePaymentPayload(decodedBytes)
+}
+
+// DecodePaymentPayload decodes a JSON payment payload, checking its x402 version and scheme
+func DecodePaymentPayload(data []byte) (*PaymentPayload, error) {
+	var version struct {
+		X402Version int `json:"x402Version"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal payment payload: %w", err)
 	}
+	if version.X402Version != X402Version {
+		return nil, &UnsupportedVersionError{Version: version.X402Version}
+	}
 
-	// Set the x402Version after decoding, matching the TypeScript behavior
-	payload.X402Version = 1
+	var payload PaymentPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment payload: %w", err)
+	}
 
 	return &payload, nil
 }
 
 // SetUSDCInfo sets the USDC token information in the Extra field of PaymentRequirements
+//
+// Deprecated: use SetNetworkUSDC, which supports every registered network.
 func (p *PaymentRequirements) SetUSDCInfo(isTestnet bool) error {
 	usdcInfo := map[string]any{
 		"name":    "USDC",
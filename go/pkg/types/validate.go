@@ -0,0 +1,186 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var (
+	evmAddressRegex   = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	svmAddressRegex   = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32,44}$`)
+	hex32BytesRegex   = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+	evmSignatureRegex = regexp.MustCompile(`^0x[0-9a-fA-F]{130}$`)
+	integerRegex      = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// evmMaxAtomicUnits is the maximum number of digits of an EVM authorization value, as in the
+// TypeScript implementation
+const evmMaxAtomicUnits = 18
+
+// ValidationError is returned by the Validate methods for a field breaking the protocol's rules
+type ValidationError struct {
+	// Field is the JSON name of the field (ex: "authorization.nonce")
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// Validate checks the requirements are for a registered scheme and network, with an integer
+// amount, a URL resource and addresses of the network's family
+func (p *PaymentRequirements) Validate() error {
+	if _, err := LookupPayloadScheme(p.Scheme, p.Network); err != nil {
+		return err
+	}
+	if err := validateInteger("maxAmountRequired", p.MaxAmountRequired); err != nil {
+		return err
+	}
+	if resource, err := url.Parse(p.Resource); err != nil || resource.Scheme == "" || resource.Host == "" {
+		return &ValidationError{Field: "resource", Reason: fmt.Sprintf("%q is not a URL", p.Resource)}
+	}
+	if err := validateNetworkAddress("payTo", p.PayTo, p.Network); err != nil {
+		return err
+	}
+	if err := validateNetworkAddress("asset", p.Asset, p.Network); err != nil {
+		return err
+	}
+	if p.MaxTimeoutSeconds < 0 {
+		return &ValidationError{Field: "maxTimeoutSeconds", Reason: "must not be negative"}
+	}
+
+	return nil
+}
+
+// Validate checks the payload is of the supported x402 version, of a registered scheme and
+// network, and carries a valid payload of its scheme
+func (p *PaymentPayload) Validate() error {
+	if p.X402Version != X402Version {
+		return &UnsupportedVersionError{Version: p.X402Version}
+	}
+	payloadScheme, err := LookupPayloadScheme(p.Scheme, p.Network)
+	if err != nil {
+		return err
+	}
+
+	var payload any
+	switch {
+	case p.SchemePayload != nil:
+		payload = p.SchemePayload
+	case p.Scheme == SchemeExact && p.Payload != nil:
+		return p.Payload.Validate()
+	case p.Scheme == SchemeUpto && p.UptoPayload != nil:
+		payload = p.UptoPayload
+	default:
+		return &ValidationError{Field: "payload", Reason: "is required"}
+	}
+
+	if payloadScheme.Validate != nil {
+		if err := payloadScheme.Validate(payload); err != nil {
+			return &ValidationError{Field: "payload", Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// Validate checks the signature is 65 hex encoded bytes and the authorization is valid
+func (p *ExactEvmPayload) Validate() error {
+	if !evmSignatureRegex.MatchString(p.Signature) {
+		return &ValidationError{Field: "signature", Reason: "must be 65 hex encoded bytes"}
+	}
+	if p.Authorization == nil {
+		return &ValidationError{Field: "authorization", Reason: "is required"}
+	}
+	return p.Authorization.Validate()
+}
+
+// Validate checks the authorization has EVM addresses, integer value and validity window, and
+// a 32 bytes nonce
+func (a *ExactEvmPayloadAuthorization) Validate() error {
+	if !evmAddressRegex.MatchString(a.From) {
+		return &ValidationError{Field: "authorization.from", Reason: fmt.Sprintf("%q is not an EVM address", a.From)}
+	}
+	if !evmAddressRegex.MatchString(a.To) {
+		return &ValidationError{Field: "authorization.to", Reason: fmt.Sprintf("%q is not an EVM address", a.To)}
+	}
+	if err := validateInteger("authorization.value", a.Value); err != nil {
+		return err
+	}
+	if len(a.Value) > evmMaxAtomicUnits {
+		return &ValidationError{Field: "authorization.value", Reason: fmt.Sprintf("must have at most %d digits", evmMaxAtomicUnits)}
+	}
+	if err := validateInteger("authorization.validAfter", a.ValidAfter); err != nil {
+		return err
+	}
+	if err := validateInteger("authorization.validBefore", a.ValidBefore); err != nil {
+		return err
+	}
+	if !hex32BytesRegex.MatchString(a.Nonce) {
+		return &ValidationError{Field: "authorization.nonce", Reason: "must be 32 hex encoded bytes"}
+	}
+
+	return nil
+}
+
+// Validate checks the payer, if any, is an address. Reasons are not checked, as facilitators may
+// report reasons unknown to this package, see ErrorReason.
+func (r *VerifyResponse) Validate() error {
+	return validatePayer(r.Payer)
+}
+
+// Validate checks the payer, if any, is an address. A successful settlement must have a
+// transaction on a registered network.
+func (r *SettleResponse) Validate() error {
+	if err := validatePayer(r.Payer); err != nil {
+		return err
+	}
+	if !r.Success {
+		return nil
+	}
+
+	if r.Transaction == "" {
+		return &ValidationError{Field: "transaction", Reason: "is required"}
+	}
+	if _, err := NetworkFamily(r.Network); err != nil {
+		return &ValidationError{Field: "network", Reason: err.Error()}
+	}
+	return nil
+}
+
+// validateInteger checks value is a non-negative decimal integer
+func validateInteger(field, value string) error {
+	if !integerRegex.MatchString(value) {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("%q is not an integer", value)}
+	}
+	return nil
+}
+
+// validateNetworkAddress checks address is an EVM address on EVM networks, and an EVM or SVM
+// address on others
+func validateNetworkAddress(field, address, network string) error {
+	family, err := NetworkFamily(network)
+	if err != nil {
+		return &ValidationError{Field: "network", Reason: err.Error()}
+	}
+	if family == NetworkFamilyEVM && !evmAddressRegex.MatchString(address) {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("%q is not an EVM address", address)}
+	}
+	if !isAddress(address) {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("%q is not an address", address)}
+	}
+	return nil
+}
+
+func validatePayer(payer *string) error {
+	if payer != nil && *payer != "" && !isAddress(*payer) {
+		return &ValidationError{Field: "payer", Reason: fmt.Sprintf("%q is not an address", *payer)}
+	}
+	return nil
+}
+
+// isAddress reports whether address is an EVM or SVM address
+func isAddress(address string) bool {
+	return evmAddressRegex.MatchString(address) || svmAddressRegex.MatchString(address)
+}
@@ -0,0 +1,191 @@
+package types_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	_ "github.com/coinbase/x402/go/pkg/svm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func validRequirements() *types.PaymentRequirements {
+	return &types.PaymentRequirements{
+		Scheme:            types.SchemeExact,
+		Network:           "base-sepolia",
+		MaxAmountRequired: "1000000",
+		Resource:          "https://example.com/resource",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+	}
+}
+
+func validPayload() *types.PaymentPayload {
+	return &types.PaymentPayload{
+		X402Version: 1,
+		Scheme:      types.SchemeExact,
+		Network:     "base-sepolia",
+		Payload: &types.ExactEvmPayload{
+			Signature: "0x2d6a7588d6acca505cbf0d9a4a227e0c52c6c34008c8e8986a1283259764173608a2ce6496642e377d6da8dbbf5836e9bd15092f9ecab05ded3d6293af148b571c",
+			Authorization: &types.ExactEvmPayloadAuthorization{
+				From:        "0x857b06519E91e3A54538791bDbb0E22373e36b66",
+				To:          "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				Value:       "1000000",
+				ValidAfter:  "1745323800",
+				ValidBefore: "1745323985",
+				Nonce:       "0xf3746613c2d920b5fdabc0856f2aeb2d4f88ee6037b8cc5d04a71a4462f13480",
+			},
+		},
+	}
+}
+
+func TestPaymentRequirements_Validate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		modify        func(r *types.PaymentRequirements)
+		expectedError string
+	}{
+		{
+			name:   "valid",
+			modify: func(r *types.PaymentRequirements) {},
+		},
+		{
+			name:          "unsupported scheme",
+			modify:        func(r *types.PaymentRequirements) { r.Scheme = "stream" },
+			expectedError: `unsupported scheme "stream" on network "base-sepolia"`,
+		},
+		{
+			name:          "decimal amount",
+			modify:        func(r *types.PaymentRequirements) { r.MaxAmountRequired = "1.5" },
+			expectedError: `invalid maxAmountRequired: "1.5" is not an integer`,
+		},
+		{
+			name:          "relative resource",
+			modify:        func(r *types.PaymentRequirements) { r.Resource = "/resource" },
+			expectedError: `invalid resource: "/resource" is not a URL`,
+		},
+		{
+			name:          "short payTo",
+			modify:        func(r *types.PaymentRequirements) { r.PayTo = "0x123" },
+			expectedError: `invalid payTo: "0x123" is not an EVM address`,
+		},
+		{
+			name: "solana addresses",
+			modify: func(r *types.PaymentRequirements) {
+				r.Network = "solana-devnet"
+				r.PayTo = "2wKupLR9q6wXYppw8Gr2NvWxKBUqm4PPJKkQfoxHDBg4"
+				r.Asset = "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requirements := validRequirements()
+			tc.modify(requirements)
+
+			err := requirements.Validate()
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestPaymentPayload_Validate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		modify        func(p *types.PaymentPayload)
+		expectedError string
+	}{
+		{
+			name:   "valid",
+			modify: func(p *types.PaymentPayload) {},
+		},
+		{
+			name:          "unsupported version",
+			modify:        func(p *types.PaymentPayload) { p.X402Version = 2 },
+			expectedError: "unsupported x402Version: 2",
+		},
+		{
+			name:          "missing payload",
+			modify:        func(p *types.PaymentPayload) { p.Payload = nil },
+			expectedError: "invalid payload: is required",
+		},
+		{
+			name:          "non hex signature",
+			modify:        func(p *types.PaymentPayload) { p.Payload.Signature = "signature" },
+			expectedError: "invalid signature: must be 65 hex encoded bytes",
+		},
+		{
+			name:          "short signature",
+			modify:        func(p *types.PaymentPayload) { p.Payload.Signature = "0x2d6a7588" },
+			expectedError: "invalid signature: must be 65 hex encoded bytes",
+		},
+		{
+			name:          "invalid from",
+			modify:        func(p *types.PaymentPayload) { p.Payload.Authorization.From = "0xvalidFrom" },
+			expectedError: `invalid authorization.from: "0xvalidFrom" is not an EVM address`,
+		},
+		{
+			name:          "negative value",
+			modify:        func(p *types.PaymentPayload) { p.Payload.Authorization.Value = "-1" },
+			expectedError: `invalid authorization.value: "-1" is not an integer`,
+		},
+		{
+			name:          "value too large",
+			modify:        func(p *types.PaymentPayload) { p.Payload.Authorization.Value = "1000000000000000000" },
+			expectedError: "invalid authorization.value: must have at most 18 digits",
+		},
+		{
+			name:          "invalid validBefore",
+			modify:        func(p *types.PaymentPayload) { p.Payload.Authorization.ValidBefore = "tomorrow" },
+			expectedError: `invalid authorization.validBefore: "tomorrow" is not an integer`,
+		},
+		{
+			name:          "short nonce",
+			modify:        func(p *types.PaymentPayload) { p.Payload.Authorization.Nonce = "0x01" },
+			expectedError: "invalid authorization.nonce: must be 32 hex encoded bytes",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload := validPayload()
+			tc.modify(payload)
+
+			err := payload.Validate()
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestResponses_Validate(t *testing.T) {
+	payer := "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+	solanaPayer := "2wKupLR9q6wXYppw8Gr2NvWxKBUqm4PPJKkQfoxHDBg4"
+	invalidPayer := "0xvalidPayer"
+	unknownReason := "Invalid payment"
+
+	assert.NoError(t, (&types.VerifyResponse{IsValid: true, Payer: &payer}).Validate())
+	assert.NoError(t, (&types.VerifyResponse{InvalidReason: types.ReasonPtr(types.ErrorReasonInsufficientFunds), Payer: &solanaPayer}).Validate())
+	assert.NoError(t, (&types.VerifyResponse{InvalidReason: &unknownReason}).Validate())
+	assert.EqualError(t, (&types.VerifyResponse{IsValid: true, Payer: &invalidPayer}).Validate(), `invalid payer: "0xvalidPayer" is not an address`)
+
+	assert.NoError(t, (&types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: "base-sepolia", Payer: &payer}).Validate())
+	assert.NoError(t, (&types.SettleResponse{ErrorReason: types.ReasonPtr(types.ErrorReasonInvalidTransactionState)}).Validate())
+	assert.NoError(t, (&types.SettleResponse{ErrorReason: &unknownReason}).Validate())
+	assert.EqualError(t, (&types.SettleResponse{Success: true, Network: "base-sepolia"}).Validate(), "invalid transaction: is required")
+
+	err := (&types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: "unknown"}).Validate()
+	var validationErr *types.ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "network", validationErr.Field)
+}
@@ -0,0 +1,132 @@
+package x402client
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/coinbase/x402/go/pkg/evm"
+	"github.com/coinbase/x402/go/pkg/signer"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// PaymentRequirementsSelector selects which of the accepted payment requirements to pay
+type PaymentRequirementsSelector func(accepts []*types.PaymentRequirements) (*types.PaymentRequirements, error)
+
+// SelectPaymentRequirements is the default PaymentRequirementsSelector.
+// It selects the first exact requirement on a supported EVM network, preferring base.
+func SelectPaymentRequirements(accepts []*types.PaymentRequirements) (*types.PaymentRequirements, error) {
+	var selected *types.PaymentRequirements
+	for _, requirements := range accepts {
+		if requirements == nil || requirements.Scheme != "exact" {
+			continue
+		}
+		if _, err := evm.ChainID(requirements.Network); err != nil {
+			continue
+		}
+
+		if requirements.Network == "base" {
+			return requirements, nil
+		}
+		if selected == nil {
+			selected = requirements
+		}
+	}
+
+	if selected == nil {
+		return nil, fmt.Errorf("no supported payment requirements")
+	}
+
+	return selected, nil
+}
+
+// CreatePaymentPayload creates a payment payload for the requirements, authorizing an ERC-3009
+// transferWithAuthorization of maxAmountRequired to payTo signed by the signer
+func CreatePaymentPayload(ctx context.Context, s signer.Signer, x402Version int, requirements *types.PaymentRequirements) (*types.PaymentPayload, error) {
+	domain, err := evm.DomainForRequirements(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var nonce [32]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("failed to create nonce: %w", err)
+	}
+
+	authorization := &types.ExactEvmPayloadAuthorization{
+		From:        s.Address().Hex(),
+		To:          requirements.PayTo,
+		Value:       requirements.MaxAmountRequired,
+		ValidAfter:  big.NewInt(now.Add(-60 * time.Second).Unix()).String(), // 60 seconds before
+		ValidBefore: big.NewInt(now.Add(time.Duration(requirements.MaxTimeoutSeconds) * time.Second).Unix()).String(),
+		Nonce:       hexutil.Encode(nonce[:]),
+	}
+
+	auth, err := evm.ParseAuthorization(authorization)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment requirements: %w", err)
+	}
+
+	signature, err := s.SignTypedData(ctx, auth.TypedData(domain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign authorization: %w", err)
+	}
+
+	return &types.PaymentPayload{
+		X402Version: x402Version,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Payload: &types.ExactEvmPayload{
+			Signature:     hexutil.Encode(signature),
+			Authorization: authorization,
+		},
+	}, nil
+}
+
+// CreateUptoPaymentPayload creates a payment payload for upto requirements, an EIP-2612 permit
+// allowing the spender of the requirements to transfer up to maxAmountRequired, signed by the
+// signer. The nonce must be the current permit nonce of the signer on the asset.
+func CreateUptoPaymentPayload(ctx context.Context, s signer.Signer, x402Version int, requirements *types.PaymentRequirements, nonce *big.Int) (*types.PaymentPayload, error) {
+	domain, err := evm.DomainForRequirements(requirements)
+	if err != nil {
+		return nil, err
+	}
+	spender, err := evm.UptoSpender(requirements)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment requirements: %w", err)
+	}
+
+	permit := &types.UptoEvmPayloadPermit{
+		Owner:    s.Address().Hex(),
+		Spender:  spender.Hex(),
+		Value:    requirements.MaxAmountRequired,
+		Nonce:    nonce.String(),
+		Deadline: big.NewInt(time.Now().Add(time.Duration(requirements.MaxTimeoutSeconds) * time.Second).Unix()).String(),
+	}
+
+	parsed, err := evm.ParsePermit(permit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment requirements: %w", err)
+	}
+
+	signature, err := s.SignTypedData(ctx, parsed.TypedData(domain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign permit: %w", err)
+	}
+
+	return &types.PaymentPayload{
+		X402Version: x402Version,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		UptoPayload: &types.UptoEvmPayload{
+			Signature: hexutil.Encode(signature),
+			Permit:    permit,
+		},
+	}, nil
+}
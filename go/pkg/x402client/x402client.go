@@ -0,0 +1,171 @@
+package x402client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/coinbase/x402/go/pkg/signer"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// paymentRequiredResponse is the body of a 402 Payment Required response
+type paymentRequiredResponse struct {
+	X402Version int                          `json:"x402Version"`
+	Error       string                       `json:"error"`
+	Accepts     []*types.PaymentRequirements `json:"accepts"`
+}
+
+// Transport is an http.RoundTripper that pays for resources responding with 402 Payment Required.
+// The request is retried once with an X-PAYMENT header for the selected payment requirements.
+type Transport struct {
+	// Base is the underlying RoundTripper. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Signer signs the payment authorizations
+	Signer signer.Signer
+	// Selector selects the payment requirements to pay. If nil, SelectPaymentRequirements is used.
+	Selector PaymentRequirementsSelector
+	// MaxAmount is the maximum amount, in atomic units, the transport pays for a single request.
+	// If nil, any amount is paid.
+	MaxAmount *big.Int
+	// PermitNonce returns the EIP-2612 permit nonce of the owner on the token, to pay upto
+	// payment requirements. If nil, only exact payment requirements can be paid.
+	PermitNonce func(ctx context.Context, token, owner common.Address) (*big.Int, error)
+}
+
+// NewClient creates an http.Client that pays for 402 responses with the signer
+func NewClient(s signer.Signer) *http.Client {
+	return &http.Client{
+		Transport: &Transport{Signer: s},
+	}
+}
+
+// RoundTrip executes the request, paying and retrying it if the server responds with 402 Payment Required
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Buffer the body so the request can be replayed with the payment header
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusPaymentRequired {
+		return resp, err
+	}
+
+	paymentRequired, err := decodePaymentRequired(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := t.Selector
+	if selector == nil {
+		selector = SelectPaymentRequirements
+	}
+	requirements, err := selector(paymentRequired.Accepts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select payment requirements: %w", err)
+	}
+
+	if t.MaxAmount != nil {
+		amount, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid maxAmountRequired: %q", requirements.MaxAmountRequired)
+		}
+		if amount.Cmp(t.MaxAmount) > 0 {
+			return nil, fmt.Errorf("payment amount %s exceeds maximum %s", amount, t.MaxAmount)
+		}
+	}
+
+	paymentPayload, err := t.createPaymentPayload(req.Context(), paymentRequired.X402Version, requirements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+	paymentHeader, err := paymentPayload.EncodeToBase64String()
+	if err != nil {
+		return nil, err
+	}
+
+	paidReq := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to replay request body: %w", err)
+		}
+		paidReq.Body = body
+	}
+	paidReq.Header.Set("X-PAYMENT", paymentHeader)
+
+	return t.base().RoundTrip(paidReq)
+}
+
+// createPaymentPayload creates the payment payload of the requirements' scheme
+func (t *Transport) createPaymentPayload(ctx context.Context, x402Version int, requirements *types.PaymentRequirements) (*types.PaymentPayload, error) {
+	if requirements.Scheme != types.SchemeUpto {
+		return CreatePaymentPayload(ctx, t.Signer, x402Version, requirements)
+	}
+
+	if t.PermitNonce == nil {
+		return nil, fmt.Errorf("paying %s payment requirements requires PermitNonce", types.SchemeUpto)
+	}
+	if !common.IsHexAddress(requirements.Asset) {
+		return nil, fmt.Errorf("invalid asset address: %q", requirements.Asset)
+	}
+	nonce, err := t.PermitNonce(ctx, common.HexToAddress(requirements.Asset), t.Signer.Address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read permit nonce: %w", err)
+	}
+
+	return CreateUptoPaymentPayload(ctx, t.Signer, x402Version, requirements, nonce)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// decodePaymentRequired reads and closes the body of a 402 response
+func decodePaymentRequired(resp *http.Response) (*paymentRequiredResponse, error) {
+	defer resp.Body.Close()
+
+	var paymentRequired paymentRequiredResponse
+	if err := json.NewDecoder(resp.Body).Decode(&paymentRequired); err != nil {
+		return nil, fmt.Errorf("failed to decode payment required response: %w", err)
+	}
+	if len(paymentRequired.Accepts) == 0 {
+		return nil, fmt.Errorf("payment required response has no accepted payment requirements")
+	}
+	if paymentRequired.X402Version == 0 {
+		paymentRequired.X402Version = 1
+	}
+
+	return &paymentRequired, nil
+}
+
+// PaymentResponse decodes the X-PAYMENT-RESPONSE header of a paid response.
+// It returns nil if the response has no payment response header.
+func PaymentResponse(resp *http.Response) (*types.SettleResponse, error) {
+	header := resp.Header.Get("X-PAYMENT-RESPONSE")
+	if header == "" {
+		return nil, nil
+	}
+
+	return types.DecodeSettleResponseFromBase64(header)
+}
@@ -0,0 +1,229 @@
+package x402client_test
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/x402/go/pkg/facilitator"
+	x402gin "github.com/coinbase/x402/go/pkg/gin"
+	"github.com/coinbase/x402/go/pkg/signer"
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/coinbase/x402/go/pkg/x402client"
+)
+
+const (
+	testUSDC    = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	testPayTo   = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+	testSpender = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+)
+
+// setupTest starts a local facilitator and a Gin resource server charging $0.01 for POST /paid,
+// and up to $0.01 for GET /metered depending on the used query parameter.
+func setupTest(t *testing.T) (*signer.PrivateKeySigner, *facilitator.MemoryChain, *httptest.Server) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s := signer.NewPrivateKeySigner(key)
+
+	chain := facilitator.NewMemoryChain()
+	chain.SetBalance(common.HexToAddress(testUSDC), s.Address(), big.NewInt(1_000_000))
+	chain.SetSpender(common.HexToAddress(testSpender))
+
+	facilitatorServer := httptest.NewServer(facilitator.NewHandler(
+		facilitator.NewFacilitator(map[string]facilitator.ChainReader{"base-sepolia": chain}),
+	))
+	t.Cleanup(facilitatorServer.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST(
+		"/paid",
+		x402gin.PaymentMiddleware(
+			big.NewFloat(0.01),
+			testPayTo,
+			x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+			x402gin.WithResource("http://example.com/paid"),
+		),
+		func(c *gin.Context) {
+			body, _ := io.ReadAll(c.Request.Body)
+			c.String(http.StatusOK, "paid: %s", body)
+		},
+	)
+	router.GET(
+		"/metered",
+		x402gin.PaymentMiddleware(
+			big.NewFloat(0.01),
+			testPayTo,
+			x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+			x402gin.WithUpto(testSpender),
+		),
+		func(c *gin.Context) {
+			used, _ := new(big.Int).SetString(c.Query("used"), 10)
+			if err := x402gin.ReportUsage(c, used); err != nil {
+				c.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			c.String(http.StatusOK, "metered")
+		},
+	)
+	router.GET("/free", func(c *gin.Context) {
+		c.String(http.StatusOK, "free")
+	})
+
+	resourceServer := httptest.NewServer(router)
+	t.Cleanup(resourceServer.Close)
+
+	return s, chain, resourceServer
+}
+
+func TestTransport_PaysPaymentRequired(t *testing.T) {
+	payer, chain, server := setupTest(t)
+	client := x402client.NewClient(payer)
+
+	resp, err := client.Post(server.URL+"/paid", "text/plain", strings.NewReader("hello"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "paid: hello", string(body))
+
+	settleResponse, err := x402client.PaymentResponse(resp)
+	require.NoError(t, err)
+	require.NotNil(t, settleResponse)
+	assert.True(t, settleResponse.Success)
+	assert.Equal(t, "base-sepolia", settleResponse.Network)
+
+	balance, _ := chain.BalanceOf(context.Background(), common.HexToAddress(testUSDC), common.HexToAddress(testPayTo))
+	assert.Equal(t, big.NewInt(10_000), balance)
+}
+
+func TestTransport_FreeResource(t *testing.T) {
+	payer, _, server := setupTest(t)
+	client := x402client.NewClient(payer)
+
+	resp, err := client.Get(server.URL + "/free")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	settleResponse, err := x402client.PaymentResponse(resp)
+	assert.NoError(t, err)
+	assert.Nil(t, settleResponse)
+}
+
+func TestTransport_MaxAmountExceeded(t *testing.T) {
+	payer, _, server := setupTest(t)
+	client := &http.Client{
+		Transport: &x402client.Transport{
+			Signer:    payer,
+			MaxAmount: big.NewInt(9_999),
+		},
+	}
+
+	_, err := client.Post(server.URL+"/paid", "text/plain", strings.NewReader("hello"))
+	assert.ErrorContains(t, err, "exceeds maximum")
+}
+
+func TestTransport_PaysUptoUsage(t *testing.T) {
+	payer, chain, server := setupTest(t)
+	client := &http.Client{
+		Transport: &x402client.Transport{
+			Signer: payer,
+			Selector: func(accepts []*types.PaymentRequirements) (*types.PaymentRequirements, error) {
+				return accepts[0], nil
+			},
+			PermitNonce: chain.PermitNonce,
+		},
+	}
+	token := common.HexToAddress(testUSDC)
+
+	resp, err := client.Get(server.URL + "/metered?used=2500")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	settleResponse, err := x402client.PaymentResponse(resp)
+	require.NoError(t, err)
+	require.NotNil(t, settleResponse)
+	assert.True(t, settleResponse.Success)
+
+	balance, _ := chain.BalanceOf(context.Background(), token, common.HexToAddress(testPayTo))
+	assert.Equal(t, big.NewInt(2_500), balance)
+
+	// Nothing is charged without usage
+	resp, err = client.Get(server.URL + "/metered?used=0")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("X-PAYMENT-RESPONSE"))
+	balance, _ = chain.BalanceOf(context.Background(), token, common.HexToAddress(testPayTo))
+	assert.Equal(t, big.NewInt(2_500), balance)
+
+	// Usage above the maximum is rejected
+	resp, err = client.Get(server.URL + "/metered?used=10001")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestSelectPaymentRequirements(t *testing.T) {
+	testCases := []struct {
+		name            string
+		accepts         []*types.PaymentRequirements
+		expectedNetwork string
+		expectError     bool
+	}{
+		{
+			name: "prefers base",
+			accepts: []*types.PaymentRequirements{
+				{Scheme: "exact", Network: "base-sepolia"},
+				{Scheme: "exact", Network: "base"},
+			},
+			expectedNetwork: "base",
+		},
+		{
+			name: "skips unsupported schemes and networks",
+			accepts: []*types.PaymentRequirements{
+				{Scheme: "upto", Network: "base"},
+				{Scheme: "exact", Network: "unknown"},
+				{Scheme: "exact", Network: "avalanche-fuji"},
+			},
+			expectedNetwork: "avalanche-fuji",
+		},
+		{
+			name: "no supported requirements",
+			accepts: []*types.PaymentRequirements{
+				{Scheme: "upto", Network: "base"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := x402client.SelectPaymentRequirements(tc.accepts)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedNetwork, selected.Network)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Package x402http provides the x402 payment middleware for net/http handlers.
+// It is compatible with any router accepting func(http.Handler) http.Handler middlewares, such as chi,
+// and is configured with the options of the middleware package.
+package x402http
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+
+	"github.com/coinbase/x402/go/pkg/middleware"
+)
+
+// ReportUsage reports the amount, in atomic units of the asset, used by the request of an upto
+// payment, see WithUpto. It is charged instead of the price once the handler returns.
+func ReportUsage(r *http.Request, amount *big.Int) error {
+	return middleware.ReportUsage(r.Context(), amount)
+}
+
+// Middleware is the net/http middleware for the resource server using the x402payment protocol
+type Middleware struct {
+	engine *middleware.Engine
+}
+
+// NewMiddleware creates the net/http middleware charging amount to address.
+// Amount: the decimal denominated amount to charge (ex: 0.01 for 1 cent)
+func NewMiddleware(amount *big.Float, address string, opts ...middleware.Options) *Middleware {
+	return &Middleware{
+		engine: middleware.NewEngine(middleware.USDAmount(amount), address, middleware.NewOptions(opts...)),
+	}
+}
+
+// NewMiddlewareContext is NewMiddleware returning the error of the supported payment kinds check
+// instead of panicking, see middleware.NewEngineContext
+func NewMiddlewareContext(ctx context.Context, amount *big.Float, address string, opts ...middleware.Options) (*Middleware, error) {
+	engine, err := middleware.NewEngineContext(ctx, middleware.USDAmount(amount), address, middleware.NewOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+	return &Middleware{engine: engine}, nil
+}
+
+// Middleware gates next behind the payment, it can be given to chi's Use
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlePayment(w, r, next, m.engine)
+	})
+}
+
+// PaymentMiddleware returns the Middleware method of NewMiddleware(amount, address, opts...)
+func PaymentMiddleware(amount *big.Float, address string, opts ...middleware.Options) func(http.Handler) http.Handler {
+	return NewMiddleware(amount, address, opts...).Middleware
+}
+
+// handlePayment gates the request behind the engine's payment
+func handlePayment(w http.ResponseWriter, r *http.Request, next http.Handler, engine *middleware.Engine) {
+	payment, response := engine.Verify(r)
+	if response != nil {
+		middleware.WriteResponse(w, response)
+		return
+	}
+
+	r = r.WithContext(middleware.ContextWithPayment(r.Context(), payment))
+
+	switch engine.SettlementMode() {
+	case middleware.SettleBeforeHandler:
+		settleResponseHeader, response := engine.Settle(r.Context(), payment)
+		if response != nil {
+			middleware.WriteResponse(w, response)
+			return
+		}
+		if settleResponseHeader != "" {
+			w.Header().Set(middleware.PaymentResponseHeader, settleResponseHeader)
+		}
+		next.ServeHTTP(w, r)
+		return
+	case middleware.SettleAsync:
+		settleResponseHeader, response := engine.HoldSettlement(r.Context(), payment)
+		if response != nil {
+			middleware.WriteResponse(w, response)
+			return
+		}
+		if settleResponseHeader != "" {
+			w.Header().Set(middleware.PaymentResponseHeader, settleResponseHeader)
+		}
+		next.ServeHTTP(w, r)
+		engine.SettleAsync(r.Context(), payment)
+		return
+	}
+
+	// Buffer the handler's response until the payment is settled
+	writer := &responseWriter{
+		ResponseWriter: w,
+		buffer:         middleware.NewResponseBuffer(),
+	}
+	next.ServeHTTP(writer, r)
+
+	// Settle payment
+	settleResponseHeader, response := engine.Settle(r.Context(), payment)
+	if response != nil {
+		middleware.WriteResponse(w, response)
+		return
+	}
+
+	// Write the original response with the settlement header
+	if settleResponseHeader != "" {
+		w.Header().Set(middleware.PaymentResponseHeader, settleResponseHeader)
+	}
+	w.WriteHeader(writer.buffer.StatusCode)
+	w.Write(writer.buffer.Body.Bytes())
+}
+
+// responseWriter is a custom response writer that captures the response
+type responseWriter struct {
+	http.ResponseWriter
+	buffer *middleware.ResponseBuffer
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.buffer.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.buffer.Write(b)
+}
@@ -0,0 +1,77 @@
+package x402http_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/middleware"
+	"github.com/coinbase/x402/go/pkg/middleware/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/coinbase/x402/go/pkg/x402http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentMiddleware(t *testing.T) {
+	middlewaretest.Run(t, func(t *testing.T, amount *big.Float, payTo string, opts ...middleware.Options) middlewaretest.Server {
+		mux := http.NewServeMux()
+		mux.Handle("GET /protected", x402http.PaymentMiddleware(amount, payTo, opts...)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("success"))
+			}),
+		))
+
+		return middlewaretest.ServeHandler(mux)
+	})
+}
+
+// fakeFacilitator verifies and settles every payment without contacting a remote facilitator
+type fakeFacilitator struct {
+	settled int
+}
+
+func (f *fakeFacilitator) VerifyContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	payer := middlewaretest.Payer
+	return &types.VerifyResponse{IsValid: true, Payer: &payer}, nil
+}
+
+func (f *fakeFacilitator) SettleContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	f.settled++
+	return &types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: requirements.Network}, nil
+}
+
+func (f *fakeFacilitator) SupportedContext(ctx context.Context) (*types.SupportedPaymentKindsResponse, error) {
+	return &types.SupportedPaymentKindsResponse{}, nil
+}
+
+func TestPaymentMiddleware_WithFacilitator(t *testing.T) {
+	facilitator := &fakeFacilitator{}
+	handler := x402http.NewMiddleware(big.NewFloat(1), middlewaretest.PayTo,
+		middleware.WithFacilitator(facilitator),
+	).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("success"))
+	}))
+
+	paymentPayloadJson, err := json.Marshal(middlewaretest.NewPaymentPayload())
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "success", w.Body.String())
+	assert.Equal(t, 1, facilitator.settled)
+
+	settleResponse, err := types.DecodeSettleResponseFromBase64(w.Header().Get("X-PAYMENT-RESPONSE"))
+	require.NoError(t, err)
+	assert.Equal(t, "0xtesthash", settleResponse.Transaction)
+}